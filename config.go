@@ -0,0 +1,26 @@
+package main
+
+import (
+	"errors"
+	"io"
+
+	"github.com/hoop33/roster/config"
+	"gopkg.in/yaml.v2"
+)
+
+var errConfigUsage = errors.New("usage: roster config print")
+
+// runConfig prints the settings resolved from the defaults, config file,
+// environment, and flags, with secrets redacted
+func runConfig(cfg *config.Config, args []string, w io.Writer) error {
+	if len(args) != 1 || args[0] != "print" {
+		return errConfigUsage
+	}
+
+	b, err := yaml.Marshal(cfg.Redact())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
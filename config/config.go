@@ -0,0 +1,388 @@
+// Package config resolves the server's settings from defaults, a YAML or
+// TOML file, environment variables, and command-line flags, each
+// overriding the ones before.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hoop33/roster/players"
+	"gopkg.in/yaml.v2"
+)
+
+// Config holds the settings for the server and its commands
+type Config struct {
+	Store      string   `yaml:"store"`
+	SQLiteFile string   `yaml:"sqlite_file"`
+	HTTP       HTTP     `yaml:"http"`
+	GRPC       GRPC     `yaml:"grpc"`
+	Database   Database `yaml:"database"`
+	Log        Log      `yaml:"log"`
+	Purge      Purge    `yaml:"purge"`
+	Features   Features `yaml:"features"`
+}
+
+// HTTP holds the settings for the HTTP transport
+type HTTP struct {
+	Addr         string `yaml:"addr"`
+	CacheControl string `yaml:"cache_control"`
+}
+
+// GRPC holds the settings for the gRPC transport
+type GRPC struct {
+	Addr string `yaml:"addr"`
+}
+
+// Database holds the settings for connecting to PostgreSQL. Blank
+// connection settings are left to the driver's defaults.
+type Database struct {
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	Name            string        `yaml:"name"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	SSLMode         string        `yaml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+}
+
+// Log holds the logging settings
+type Log struct {
+	// Level is info to log everything, or error to log only failures
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// Purge holds the settings for purging deleted players
+type Purge struct {
+	// After is how long to keep deleted players; 0 keeps them forever
+	After    time.Duration `yaml:"after"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Features toggles optional behavior
+type Features struct {
+	GRPCLegacyErrors bool `yaml:"grpc_legacy_errors"`
+}
+
+// Redacted is shown in place of secrets
+const Redacted = "REDACTED"
+
+// Default returns the settings used when nothing else is configured
+func Default() *Config {
+	return &Config{
+		Store:      "postgres",
+		SQLiteFile: "roster.db",
+		HTTP: HTTP{
+			Addr:         ":9090",
+			CacheControl: players.DefaultCacheControl,
+		},
+		GRPC: GRPC{
+			Addr: ":9091",
+		},
+		Database: Database{
+			Name:         "roster",
+			SSLMode:      "disable",
+			MaxIdleConns: 2,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "logfmt",
+		},
+		Purge: Purge{
+			After:    30 * 24 * time.Hour,
+			Interval: time.Hour,
+		},
+	}
+}
+
+// setting is a value that can be set by an environment variable or flag
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	field func(*Config) interface{}
+}
+
+var settings = []setting{
+	{"store", "ROSTER_STORE", "player store to use (postgres, sqlite, or memory)", func(c *Config) interface{} { return &c.Store }},
+	{"sqlite-file", "ROSTER_SQLITE_FILE", "database file to use with the sqlite store", func(c *Config) interface{} { return &c.SQLiteFile }},
+	{"http-addr", "ROSTER_HTTP_ADDR", "address to serve HTTP on", func(c *Config) interface{} { return &c.HTTP.Addr }},
+	{"cache-control", "ROSTER_CACHE_CONTROL", "Cache-Control header to send with players over HTTP", func(c *Config) interface{} { return &c.HTTP.CacheControl }},
+	{"grpc-addr", "ROSTER_GRPC_ADDR", "address to serve gRPC on", func(c *Config) interface{} { return &c.GRPC.Addr }},
+	{"db-host", "ROSTER_DB_HOST", "PostgreSQL host", func(c *Config) interface{} { return &c.Database.Host }},
+	{"db-port", "ROSTER_DB_PORT", "PostgreSQL port", func(c *Config) interface{} { return &c.Database.Port }},
+	{"db-name", "ROSTER_DB_NAME", "PostgreSQL database name", func(c *Config) interface{} { return &c.Database.Name }},
+	{"db-user", "ROSTER_USER", "PostgreSQL user", func(c *Config) interface{} { return &c.Database.User }},
+	{"db-password", "ROSTER_PASSWORD", "PostgreSQL password", func(c *Config) interface{} { return &c.Database.Password }},
+	{"db-sslmode", "ROSTER_DB_SSLMODE", "PostgreSQL SSL mode (disable, require, verify-ca, or verify-full)", func(c *Config) interface{} { return &c.Database.SSLMode }},
+	{"db-max-open-conns", "ROSTER_DB_MAX_OPEN_CONNS", "most open database connections; 0 is unlimited", func(c *Config) interface{} { return &c.Database.MaxOpenConns }},
+	{"db-max-idle-conns", "ROSTER_DB_MAX_IDLE_CONNS", "most idle database connections to keep", func(c *Config) interface{} { return &c.Database.MaxIdleConns }},
+	{"db-conn-max-lifetime", "ROSTER_DB_CONN_MAX_LIFETIME", "how long to reuse a database connection; 0 reuses it forever", func(c *Config) interface{} { return &c.Database.ConnMaxLifetime }},
+	{"log-level", "ROSTER_LOG_LEVEL", "what to log (info, or error for failures only)", func(c *Config) interface{} { return &c.Log.Level }},
+	{"log-format", "ROSTER_LOG_FORMAT", "log format (logfmt or json)", func(c *Config) interface{} { return &c.Log.Format }},
+	{"purge-after", "ROSTER_PURGE_AFTER", "how long to keep deleted players before purging them; 0 keeps them forever", func(c *Config) interface{} { return &c.Purge.After }},
+	{"purge-interval", "ROSTER_PURGE_INTERVAL", "how often to purge deleted players", func(c *Config) interface{} { return &c.Purge.Interval }},
+	{"grpc-legacy-errors", "ROSTER_GRPC_LEGACY_ERRORS", "report gRPC failures in the deprecated err fields instead of the status", func(c *Config) interface{} { return &c.Features.GRPCLegacyErrors }},
+}
+
+// Load resolves the settings, defining their flags on fs and parsing args
+// with it. The file named by the -config flag, or $ROSTER_CONFIG, is read
+// if either is set.
+func Load(fs *flag.FlagSet, args []string, getenv func(string) string) (*Config, error) {
+	// Flags are parsed into their own config, so they can be applied last
+	flags := Default()
+	file := fs.String("config", "", "YAML or TOML file to read settings from")
+	for _, s := range settings {
+		fs.Var(fieldValue{s.field(flags)}, s.flag, s.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	c := Default()
+	if *file == "" {
+		*file = getenv("ROSTER_CONFIG")
+	}
+	if *file != "" {
+		if err := c.readFile(*file); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if v := getenv(s.env); v != "" {
+			if err := set(s.field(c), v); err != nil {
+				return nil, fmt.Errorf("%s: %v", s.env, err)
+			}
+		}
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && err == nil {
+				err = set(s.field(c), f.Value.String())
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readFile reads settings from a YAML file, or a TOML one if its name
+// ends in .toml
+func (c *Config) readFile(file string) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(filepath.Ext(file), ".toml") {
+		err = c.readTOML(b)
+	} else {
+		err = yaml.UnmarshalStrict(b, c)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	return nil
+}
+
+// readTOML reads settings from TOML, with the same keys as YAML and
+// durations as strings, e.g., "48h"
+func (c *Config) readTOML(b []byte) error {
+	var values map[string]toml.Primitive
+	md, err := toml.Decode(string(b), &values)
+	if err != nil {
+		return err
+	}
+	return decodeTOML(md, values, reflect.ValueOf(c).Elem(), "")
+}
+
+// decodeTOML decodes a table into the fields of a struct named by their
+// yaml tags, rejecting keys that don't name one
+func decodeTOML(md toml.MetaData, values map[string]toml.Primitive, v reflect.Value, prefix string) error {
+	fields := map[string]reflect.Value{}
+	for i := 0; i < v.NumField(); i++ {
+		fields[v.Type().Field(i).Tag.Get("yaml")] = v.Field(i)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("unknown setting %s%s", prefix, key)
+		}
+
+		if field.Kind() == reflect.Struct {
+			var table map[string]toml.Primitive
+			if err := md.PrimitiveDecode(values[key], &table); err != nil {
+				return fmt.Errorf("%s%s: %v", prefix, key, err)
+			}
+			if err := decodeTOML(md, table, field, prefix+key+"."); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var err error
+		if p, ok := field.Addr().Interface().(*time.Duration); ok {
+			var s string
+			if err = md.PrimitiveDecode(values[key], &s); err == nil {
+				err = set(p, s)
+			}
+		} else {
+			err = md.PrimitiveDecode(values[key], field.Addr().Interface())
+		}
+		if err != nil {
+			return fmt.Errorf("%s%s: %v", prefix, key, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the settings that have a fixed set of values, and the
+// numbers and durations that can't be negative
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(name, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		problems = append(problems, fmt.Sprintf("%s %q must be one of %s", name, value, strings.Join(allowed, ", ")))
+	}
+	check("store", c.Store, "postgres", "sqlite", "memory")
+	check("database.sslmode", c.Database.SSLMode, "disable", "require", "verify-ca", "verify-full")
+	check("log.level", c.Log.Level, "info", "error")
+	check("log.format", c.Log.Format, "logfmt", "json")
+
+	negative := func(name string, value int64) {
+		if value < 0 {
+			problems = append(problems, fmt.Sprintf("%s must not be negative", name))
+		}
+	}
+	negative("database.port", int64(c.Database.Port))
+	negative("database.max_open_conns", int64(c.Database.MaxOpenConns))
+	negative("database.max_idle_conns", int64(c.Database.MaxIdleConns))
+	negative("database.conn_max_lifetime", int64(c.Database.ConnMaxLifetime))
+	negative("purge.after", int64(c.Purge.After))
+	if c.Purge.After > 0 && c.Purge.Interval <= 0 {
+		problems = append(problems, "purge.interval must be positive when purge.after is set")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid config: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Redact returns a copy of the settings with the secrets replaced, for
+// showing to people
+func (c *Config) Redact() *Config {
+	r := *c
+	if r.Database.Password != "" {
+		r.Database.Password = Redacted
+	}
+	return &r
+}
+
+// DSN returns the PostgreSQL connection string, leaving out blank settings
+func (d Database) DSN() string {
+	var parts []string
+	add := func(key, value string) {
+		if value != "" {
+			parts = append(parts, key+"="+quoteDSN(value))
+		}
+	}
+	add("host", d.Host)
+	if d.Port > 0 {
+		add("port", strconv.Itoa(d.Port))
+	}
+	add("dbname", d.Name)
+	add("user", d.User)
+	add("password", d.Password)
+	add("sslmode", d.SSLMode)
+	return strings.Join(parts, " ")
+}
+
+// quoteDSN quotes a connection string value that has spaces, quotes, or
+// backslashes
+func quoteDSN(value string) string {
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `'`, `\'`, -1)
+	return "'" + value + "'"
+}
+
+// set parses a setting's value into the field it points to
+func set(field interface{}, value string) error {
+	switch p := field.(type) {
+	case *string:
+		*p = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*p = d
+	}
+	return nil
+}
+
+// fieldValue is a flag.Value that sets a field of a config
+type fieldValue struct {
+	field interface{}
+}
+
+func (v fieldValue) String() string {
+	if v.field == nil {
+		return ""
+	}
+	return fmt.Sprint(reflect.ValueOf(v.field).Elem().Interface())
+}
+
+func (v fieldValue) Set(value string) error {
+	return set(v.field, value)
+}
+
+func (v fieldValue) IsBoolFlag() bool {
+	_, ok := v.field.(*bool)
+	return ok
+}
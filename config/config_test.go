@@ -0,0 +1,211 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func load(args []string, env map[string]string) (*Config, error) {
+	fs := flag.NewFlagSet("roster", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return Load(fs, args, func(key string) string {
+		return env[key]
+	})
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "roster")
+	assert.Nil(t, err)
+	_, err = f.WriteString(content)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+	return f.Name()
+}
+
+func writeTOMLFile(t *testing.T, content string) string {
+	file := writeConfigFile(t, content)
+	assert.Nil(t, os.Rename(file, file+".toml"))
+	return file + ".toml"
+}
+
+func TestLoadShouldReturnDefaults(t *testing.T) {
+	c, err := load(nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, Default(), c)
+	assert.Equal(t, ":9090", c.HTTP.Addr)
+	assert.Equal(t, ":9091", c.GRPC.Addr)
+	assert.Equal(t, "dbname=roster sslmode=disable", c.Database.DSN())
+}
+
+func TestLoadShouldOverrideFileWithEnvironmentAndFlags(t *testing.T) {
+	file := writeConfigFile(t, `
+store: sqlite
+http:
+  addr: ":8080"
+grpc:
+  addr: ":8081"
+database:
+  host: db.example.com
+  max_open_conns: 10
+log:
+  format: json
+purge:
+  after: 48h
+features:
+  grpc_legacy_errors: true
+`)
+	defer os.Remove(file)
+
+	c, err := load([]string{"-store", "memory", "-db-port=6432", "-grpc-legacy-errors=false", "import", "-"}, map[string]string{
+		"ROSTER_CONFIG":    file,
+		"ROSTER_STORE":     "postgres",
+		"ROSTER_HTTP_ADDR": ":7070",
+		"ROSTER_USER":      "tcoughlin",
+		"ROSTER_DB_PORT":   "5433",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "memory", c.Store)
+	assert.Equal(t, ":7070", c.HTTP.Addr)
+	assert.Equal(t, ":8081", c.GRPC.Addr)
+	assert.Equal(t, "db.example.com", c.Database.Host)
+	assert.Equal(t, 6432, c.Database.Port)
+	assert.Equal(t, "tcoughlin", c.Database.User)
+	assert.Equal(t, 10, c.Database.MaxOpenConns)
+	assert.Equal(t, 2, c.Database.MaxIdleConns)
+	assert.Equal(t, "json", c.Log.Format)
+	assert.Equal(t, "info", c.Log.Level)
+	assert.Equal(t, 48*time.Hour, c.Purge.After)
+	assert.Equal(t, time.Hour, c.Purge.Interval)
+	assert.False(t, c.Features.GRPCLegacyErrors)
+}
+
+func TestLoadShouldPreferConfigFlagToEnvironment(t *testing.T) {
+	file := writeConfigFile(t, "store: memory\n")
+	defer os.Remove(file)
+
+	c, err := load([]string{"-config", file}, map[string]string{"ROSTER_CONFIG": "missing.yaml"})
+	assert.Nil(t, err)
+	assert.Equal(t, "memory", c.Store)
+}
+
+func TestLoadShouldRejectInvalidSettings(t *testing.T) {
+	file := writeConfigFile(t, "database:\n  hostname: db.example.com\n")
+	defer os.Remove(file)
+
+	_, err := load([]string{"-config", file}, nil)
+	assert.NotNil(t, err)
+
+	_, err = load([]string{"-config", "missing.yaml"}, nil)
+	assert.NotNil(t, err)
+
+	_, err = load(nil, map[string]string{"ROSTER_DB_PORT": "x"})
+	assert.Equal(t, `ROSTER_DB_PORT: invalid number "x"`, err.Error())
+
+	_, err = load([]string{"-purge-after", "1 day"}, nil)
+	assert.NotNil(t, err)
+
+	_, err = load([]string{"-store", "mysql", "-log-level", "debug"}, nil)
+	assert.Equal(t, `invalid config: store "mysql" must be one of postgres, sqlite, memory; log.level "debug" must be one of info, error`, err.Error())
+
+	_, err = load([]string{"-db-port", "-1", "-db-max-open-conns", "-1", "-db-max-idle-conns", "-1", "-db-conn-max-lifetime", "-1m", "-purge-after", "-1h"}, nil)
+	assert.Equal(t, "invalid config: database.port must not be negative; database.max_open_conns must not be negative; database.max_idle_conns must not be negative; database.conn_max_lifetime must not be negative; purge.after must not be negative", err.Error())
+
+	for _, interval := range []string{"0s", "-1h"} {
+		_, err = load([]string{"-purge-interval", interval}, nil)
+		assert.Equal(t, "invalid config: purge.interval must be positive when purge.after is set", err.Error(), interval)
+	}
+
+	c, err := load([]string{"-purge-after", "0s", "-purge-interval", "0s"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), c.Purge.Interval)
+}
+
+func TestLoadShouldReadTOMLFiles(t *testing.T) {
+	file := writeTOMLFile(t, `
+# roster settings
+store = "sqlite"
+sqlite_file = 'C:\roster.db'
+
+[database]
+host = "db.example.com"
+max_open_conns = 10
+conn_max_lifetime = "5m"
+
+[purge]
+after = "48h"
+
+[features]
+grpc_legacy_errors = true
+`)
+	defer os.Remove(file)
+
+	c, err := load([]string{"-config", file}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "sqlite", c.Store)
+	assert.Equal(t, `C:\roster.db`, c.SQLiteFile)
+	assert.Equal(t, "db.example.com", c.Database.Host)
+	assert.Equal(t, 10, c.Database.MaxOpenConns)
+	assert.Equal(t, 5*time.Minute, c.Database.ConnMaxLifetime)
+	assert.Equal(t, 48*time.Hour, c.Purge.After)
+	assert.Equal(t, time.Hour, c.Purge.Interval)
+	assert.True(t, c.Features.GRPCLegacyErrors)
+}
+
+func TestLoadShouldRejectInvalidTOMLFiles(t *testing.T) {
+	for content, expected := range map[string]string{
+		"[database]\nhostname = \"db.example.com\"": "unknown setting database.hostname",
+		"[database]\nport = \"5432\"":               "database.port: toml: cannot load TOML value of type string into a Go integer",
+		"[purge]\nafter = \"2 days\"":               `purge.after: invalid duration "2 days"`,
+		"[purge]\nafter = 48":                       "purge.after: toml: cannot load TOML value of type int64 into a Go string",
+		"store = \"a\"\nstore = \"b\"":              "Near line 2 (last key parsed 'store'): Key 'store' has already been defined.",
+	} {
+		file := writeTOMLFile(t, content)
+		_, err := load([]string{"-config", file}, nil)
+		assert.EqualError(t, err, file+": "+expected, content)
+		os.Remove(file)
+	}
+}
+
+func TestRedactShouldHideSecrets(t *testing.T) {
+	c := Default()
+	c.Database.User = "tcoughlin"
+	c.Database.Password = "jaguars"
+
+	r := c.Redact()
+	assert.Equal(t, Redacted, r.Database.Password)
+	assert.Equal(t, "tcoughlin", r.Database.User)
+	assert.Equal(t, "jaguars", c.Database.Password)
+
+	assert.Equal(t, "", Default().Redact().Database.Password)
+}
+
+func TestConfigShouldMarshalToTheFileFormat(t *testing.T) {
+	c := Default()
+	c.Database.Password = "jaguars"
+	b, err := yaml.Marshal(c)
+	assert.Nil(t, err)
+
+	file := writeConfigFile(t, string(b))
+	defer os.Remove(file)
+	loaded, err := load([]string{"-config", file}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, c, loaded)
+}
+
+func TestDSNShouldQuoteValues(t *testing.T) {
+	d := Database{
+		Host:     "localhost",
+		Port:     5432,
+		Name:     "roster",
+		User:     "tcoughlin",
+		Password: `it's a \secret`,
+		SSLMode:  "verify-full",
+	}
+	assert.Equal(t, `host=localhost port=5432 dbname=roster user=tcoughlin password='it\'s a \\secret' sslmode=verify-full`, d.DSN())
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"io"
+
+	"github.com/go-kit/kit/log"
+)
+
+// NewLogger returns a logger that writes to w in the configured format,
+// timestamping each record
+func (l Log) NewLogger(w io.Writer) log.Logger {
+	var logger log.Logger
+	if l.Format == "json" {
+		logger = log.NewJSONLogger(log.NewSyncWriter(w))
+	} else {
+		logger = log.NewLogfmtLogger(log.NewSyncWriter(w))
+	}
+	if l.Level == "error" {
+		logger = failureLogger{logger}
+	}
+	return log.With(logger, "ts", log.DefaultTimestampUTC())
+}
+
+// failureLogger drops the records that don't report an error in an err
+// value
+type failureLogger struct {
+	next log.Logger
+}
+
+func (l failureLogger) Log(keyvals ...interface{}) error {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] == "err" && keyvals[i+1] != nil {
+			return l.next.Log(keyvals...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLoggerShouldLogEverythingAtInfo(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Log{Level: "info", Format: "logfmt"}.NewLogger(&buf)
+	logger.Log("msg", "created logger")
+	assert.True(t, strings.HasPrefix(buf.String(), "ts="))
+	assert.True(t, strings.HasSuffix(buf.String(), " msg=\"created logger\"\n"))
+}
+
+func TestNewLoggerShouldLogOnlyFailuresAtError(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.With(Log{Level: "error", Format: "logfmt"}.NewLogger(&buf), "tag", "players")
+	var err error
+	logger.Log("msg", "getting a player", "err", err)
+	logger.Log("msg", "created logger")
+	assert.Equal(t, "", buf.String())
+
+	logger.Log("msg", "getting a player", "err", errors.New("not found"))
+	assert.True(t, strings.HasSuffix(buf.String(), ` tag=players msg="getting a player" err="not found"`+"\n"))
+}
+
+func TestNewLoggerShouldLogJSON(t *testing.T) {
+	var buf bytes.Buffer
+	Log{Level: "info", Format: "json"}.NewLogger(&buf).Log("msg", "created logger")
+
+	var record map[string]string
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "created logger", record["msg"])
+	assert.NotEqual(t, "", record["ts"])
+}
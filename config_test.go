@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hoop33/roster/config"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRunConfigShouldRejectBadUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"show"}, {"print", "yaml"}} {
+		err := runConfig(config.Default(), args, ioutil.Discard)
+		assert.Equal(t, errConfigUsage, err, strings.Join(args, " "))
+	}
+}
+
+func TestRunConfigShouldPrintRedactedSettings(t *testing.T) {
+	cfg := config.Default()
+	cfg.HTTP.Addr = ":8080"
+	cfg.Database.User = "tcoughlin"
+	cfg.Database.Password = "jaguars"
+
+	var out bytes.Buffer
+	assert.Nil(t, runConfig(cfg, []string{"print"}, &out))
+	assert.False(t, strings.Contains(out.String(), "jaguars"))
+	assert.True(t, strings.Contains(out.String(), "password: "+config.Redacted+"\n"), out.String())
+
+	var printed config.Config
+	assert.Nil(t, yaml.UnmarshalStrict(out.Bytes(), &printed))
+	assert.Equal(t, cfg.Redact(), &printed)
+	assert.Equal(t, "jaguars", cfg.Database.Password)
+}
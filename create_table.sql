@@ -1,11 +0,0 @@
-CREATE TABLE IF NOT EXISTS players (
-  id SERIAL PRIMARY KEY,
-  name TEXT,
-  number TEXT,
-  position TEXT,
-  height TEXT,
-  weight TEXT,
-  age TEXT,
-  experience INTEGER,
-  college TEXT
-)
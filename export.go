@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/players"
+)
+
+var errExportUsage = errors.New("usage: roster export [-format csv|jsonl|tsv] [-o file] [filters]")
+
+// runExport writes the players matching the filter flags to w, or to the
+// file named by -o, one at a time as they're read from the store
+func runExport(ps players.Service, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	format := fs.String("format", models.ExportCSV, "format to export in (csv, jsonl, or tsv)")
+	out := fs.String("o", "", "file to write to instead of standard output")
+	filter := filterFlags(fs)
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errExportUsage
+	}
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	pw, err := models.NewPlayerWriter(w, *format)
+	if err != nil {
+		return err
+	}
+	if err := ps.ExportPlayers(context.Background(), filter(), pw.Write); err != nil {
+		return err
+	}
+	return pw.Flush()
+}
+
+// filterFlags defines the flags that filter and sort players, and returns
+// a function that builds the filter from them once they're parsed
+func filterFlags(fs *flag.FlagSet) func() models.PlayerFilter {
+	positions := fs.String("position", "", "comma-separated positions to include")
+	college := fs.String("college", "", "college to include players from")
+	name := fs.String("name", "", "name prefix to include players with")
+	minExp := fs.Int("min-experience", -1, "least experience to include")
+	maxExp := fs.Int("max-experience", -1, "most experience to include")
+	includeDeleted := fs.Bool("include-deleted", false, "include deleted players")
+	sort := fs.String("sort", "", "comma-separated fields to sort by, each prefixed with - for descending")
+
+	return func() models.PlayerFilter {
+		filter := models.PlayerFilter{
+			College:        *college,
+			NamePrefix:     *name,
+			IncludeDeleted: *includeDeleted,
+			Sort:           models.ParseSort(*sort),
+		}
+		for _, p := range strings.Split(*positions, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				filter.Positions = append(filter.Positions, p)
+			}
+		}
+		if *minExp >= 0 {
+			filter.MinExperience = minExp
+		}
+		if *maxExp >= 0 {
+			filter.MaxExperience = maxExp
+		}
+		return filter
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/players"
+	"github.com/stretchr/testify/assert"
+)
+
+func newExportTestService(t *testing.T) players.Service {
+	ps := newTestService()
+	for _, p := range []models.Player{
+		{Name: "Blake Bortles", Number: 5, Position: "QB", College: "UCF"},
+		{Name: "Jalen Ramsey", Number: 20, Position: "CB", College: "Florida State"},
+		{Name: "Cody Kessler", Number: 6, Position: "QB", College: "USC"},
+	} {
+		_, _, err := ps.SavePlayer(context.Background(), &p)
+		assert.Nil(t, err)
+	}
+	return ps
+}
+
+func TestRunExportShouldRejectBadUsage(t *testing.T) {
+	for _, args := range [][]string{{"extra"}, {"-colour", "red"}, {"-min-experience", "x"}} {
+		err := runExport(newTestService(), args, ioutil.Discard)
+		assert.Equal(t, errExportUsage, err, strings.Join(args, " "))
+	}
+
+	err := runExport(newTestService(), []string{"-format", "xlsx"}, ioutil.Discard)
+	assert.EqualError(t, err, `unknown export format "xlsx"`)
+}
+
+func TestRunExportShouldWriteEachFormat(t *testing.T) {
+	ps := newExportTestService(t)
+
+	for _, tc := range []struct {
+		args  []string
+		lines []string
+	}{
+		{nil, []string{
+			"id,name,number,position,height,weight,birth_date,experience,college",
+			"1,Blake Bortles,5,QB,,,,0,UCF",
+			"3,Cody Kessler,6,QB,,,,0,USC",
+			"2,Jalen Ramsey,20,CB,,,,0,Florida State",
+		}},
+		{[]string{"-format", "tsv", "-position", "QB", "-sort", "-number"}, []string{
+			"id\tname\tnumber\tposition\theight\tweight\tbirth_date\texperience\tcollege",
+			"3\tCody Kessler\t6\tQB\t\t\t\t0\tUSC",
+			"1\tBlake Bortles\t5\tQB\t\t\t\t0\tUCF",
+		}},
+		{[]string{"-format", "csv", "-college", "Florida State"}, []string{
+			"id,name,number,position,height,weight,birth_date,experience,college",
+			"2,Jalen Ramsey,20,CB,,,,0,Florida State",
+		}},
+		{[]string{"-name", "Nobody"}, []string{
+			"id,name,number,position,height,weight,birth_date,experience,college",
+		}},
+	} {
+		var out bytes.Buffer
+		assert.Nil(t, runExport(ps, tc.args, &out), strings.Join(tc.args, " "))
+		assert.Equal(t, tc.lines, strings.Split(strings.TrimSpace(out.String()), "\n"), strings.Join(tc.args, " "))
+	}
+
+	var out bytes.Buffer
+	assert.Nil(t, runExport(ps, []string{"-format", "jsonl", "-position", "CB"}, &out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 1, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], `{"id":2,"name":"Jalen Ramsey",`))
+}
+
+func TestRunExportShouldWriteToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roster")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "players.csv")
+
+	var out bytes.Buffer
+	assert.Nil(t, runExport(newExportTestService(t), []string{"-o", file, "-position", "CB"}, &out))
+	assert.Equal(t, "", out.String())
+	b, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "id,name,number,position,height,weight,birth_date,experience,college\n2,Jalen Ramsey,20,CB,,,,0,Florida State\n", string(b))
+
+	err = runExport(newTestService(), []string{"-o", filepath.Join(dir, "missing", "players.csv")}, ioutil.Discard)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/players"
+)
+
+var errImportUsage = errors.New("usage: roster import <file.csv|->")
+
+// runImport imports the players in a CSV file, or standard input for
+// "-", and prints what happened to each row. Changes are attributed to
+// the $USER running the import.
+func runImport(ps players.Service, args []string, stdin io.Reader, w io.Writer) error {
+	if len(args) != 1 {
+		return errImportUsage
+	}
+
+	r := stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	rows, err := models.ParseImport(r, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if user := os.Getenv("USER"); user != "" {
+		ctx = models.WithActor(ctx, user)
+	}
+	results, err := ps.ImportPlayers(ctx, rows)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROW\tSTATUS\tID\tNAME\tERRORS")
+	for _, r := range results {
+		id := ""
+		if r.ID > 0 {
+			id = fmt.Sprint(r.ID)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", r.Row, r.Status, id, r.Name, strings.Join(r.Errors, "; "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	s := models.SummarizeImport(results)
+	fmt.Fprintf(w, "created %d, updated %d, unchanged %d, rejected %d\n", s.Created, s.Updated, s.Unchanged, s.Rejected)
+	if s.Rejected > 0 {
+		return fmt.Errorf("rejected %d row(s)", s.Rejected)
+	}
+	return nil
+}
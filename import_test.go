@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/players"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestService() players.Service {
+	return players.NewValidatingService(players.NewService(models.NewMemoryRepository()))
+}
+
+func TestRunImportShouldRejectBadUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"a.csv", "b.csv"}} {
+		err := runImport(newTestService(), args, strings.NewReader(""), ioutil.Discard)
+		assert.Equal(t, errImportUsage, err, strings.Join(args, " "))
+	}
+}
+
+func TestRunImportShouldReportEachRow(t *testing.T) {
+	ps := newTestService()
+	_, _, err := ps.SavePlayer(context.Background(), &models.Player{Name: "Blake Bortles", Number: 5, Position: "QB", College: "UCF"})
+	assert.Nil(t, err)
+
+	var out bytes.Buffer
+	err = runImport(ps, []string{"-"}, strings.NewReader("name,number,position\nBlake Bortles,9,QB\nLeonard Fournette,27,RB\nJalen Ramsey,two,CB\n"), &out)
+	assert.Equal(t, "rejected 1 row(s)", err.Error())
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 5, len(lines))
+	assert.Equal(t, []string{"ROW", "STATUS", "ID", "NAME", "ERRORS"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"1", "updated", "1", "Blake", "Bortles"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"2", "created", "2", "Leonard", "Fournette"}, strings.Fields(lines[2]))
+	assert.True(t, strings.HasPrefix(strings.Join(strings.Fields(lines[3]), " "), `3 rejected Jalen Ramsey number: invalid number "two"`))
+	assert.Equal(t, "created 1, updated 1, unchanged 0, rejected 1", lines[4])
+
+	player, err := ps.GetPlayer(context.Background(), 1, false)
+	assert.Nil(t, err)
+	assert.Equal(t, models.Number(9), player.Number)
+	assert.Equal(t, "UCF", player.College)
+}
+
+func TestRunImportShouldReadFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "roster")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "players.csv")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("name,position\nBlake Bortles,QB\n"), 0644))
+
+	var out bytes.Buffer
+	assert.Nil(t, runImport(newTestService(), []string{file}, nil, &out))
+	assert.True(t, strings.HasSuffix(out.String(), "created 1, updated 0, unchanged 0, rejected 0\n"))
+
+	err = runImport(newTestService(), []string{filepath.Join(dir, "missing.csv")}, nil, ioutil.Discard)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -1,8 +1,9 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
@@ -10,27 +11,115 @@ import (
 	"syscall"
 
 	"github.com/go-kit/kit/log"
+	"github.com/hoop33/roster/config"
+	"github.com/hoop33/roster/migrations"
+	"github.com/hoop33/roster/models"
 	"github.com/hoop33/roster/pb"
 	"github.com/hoop33/roster/players"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	"google.golang.org/grpc"
 )
 
 func main() {
-	logger := createLogger()
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	logger := createLogger(cfg.Log)
 	startLogger := log.With(logger, "tag", "start")
 	startLogger.Log("msg", "created logger")
 
-	db, err := createDatabase()
-	if err != nil {
-		startLogger.Log("msg", "failed to connect to database", "err", err)
+	if flag.Arg(0) == "config" {
+		if err := runConfig(cfg, flag.Args()[1:], os.Stdout); err != nil {
+			startLogger.Log("msg", "config command failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "players" {
+		if err := runPlayers(flag.Args()[1:], os.Stdin, os.Stdout); err != nil {
+			startLogger.Log("msg", "players command failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var db *sqlx.DB
+	var repo models.PlayerRepository
+	switch cfg.Store {
+	case "postgres":
+		var err error
+		db, err = createDatabase(cfg.Database)
+		if err != nil {
+			startLogger.Log("msg", "failed to connect to database", "err", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		startLogger.Log("msg", "connected to database")
+		repo = models.NewPostgresRepository(db)
+	case "sqlite":
+		var err error
+		db, err = createSQLiteDatabase(cfg.SQLiteFile)
+		if err != nil {
+			startLogger.Log("msg", "failed to open database", "file", cfg.SQLiteFile, "err", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		startLogger.Log("msg", "opened database", "file", cfg.SQLiteFile)
+		repo = models.NewSQLiteRepository(db)
+	case "memory":
+		repo = models.NewMemoryRepository()
+		startLogger.Log("msg", "created in-memory store")
+	default:
+		startLogger.Log("msg", "unknown player store", "store", cfg.Store)
 		os.Exit(1)
 	}
-	defer db.Close()
-	startLogger.Log("msg", "connected to database")
 
-	ps := createPlayersService(db, logger)
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(db, flag.Args()[1:], os.Stdout); err != nil {
+			startLogger.Log("msg", "migration failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if db != nil {
+		pending, err := migrations.NewMigrator(db).Pending()
+		if err != nil {
+			startLogger.Log("msg", "failed to check database schema", "err", err)
+			os.Exit(1)
+		}
+		if len(pending) > 0 {
+			startLogger.Log("msg", "database schema is behind; run 'roster migrate up'", "pending", len(pending))
+			os.Exit(1)
+		}
+		startLogger.Log("msg", "database schema is current")
+	}
+
+	if flag.Arg(0) == "export" {
+		ps := players.NewValidatingService(players.NewService(repo))
+		if err := runExport(ps, flag.Args()[1:], os.Stdout); err != nil {
+			startLogger.Log("msg", "export failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "import" {
+		ps := players.NewValidatingService(players.NewService(repo))
+		if err := runImport(ps, flag.Args()[1:], os.Stdin, os.Stdout); err != nil {
+			startLogger.Log("msg", "import failed", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	ps := createPlayersService(repo, logger)
 	startLogger.Log("msg", "created players service")
 
 	ep := players.NewEndpoints(ps)
@@ -38,20 +127,34 @@ func main() {
 
 	errs := make(chan error)
 
+	if cfg.Purge.After > 0 {
+		purger := players.NewPurger(repo, cfg.Purge.After, log.With(logger, "tag", "purge"))
+		go func() {
+			if err := purger.Run(context.Background(), cfg.Purge.Interval); err != nil {
+				errs <- err
+			}
+		}()
+		startLogger.Log("msg", "started purger", "retention", cfg.Purge.After, "interval", cfg.Purge.Interval)
+	}
+
 	go func() {
-		httpTransport := players.NewHTTPTransport(ep, logger)
+		httpTransport := players.NewHTTPTransport(ep, logger, players.CacheControl(cfg.HTTP.CacheControl))
 		startLogger.Log("msg", "created http transport")
 
-		httpAddr := ":9090"
+		httpAddr := cfg.HTTP.Addr
 		startLogger.Log("transport", "http", "address", httpAddr, "msg", "listening")
 		errs <- http.ListenAndServe(httpAddr, httpTransport)
 	}()
 
 	go func() {
-		grpcTransport := players.NewGRPCTransport(ep, logger)
+		var grpcOptions []players.GRPCOption
+		if cfg.Features.GRPCLegacyErrors {
+			grpcOptions = append(grpcOptions, players.LegacyErrors())
+		}
+		grpcTransport := players.NewGRPCTransport(ep, logger, grpcOptions...)
 		startLogger.Log("msg", "created grpc transport")
 
-		grpcAddr := ":9091"
+		grpcAddr := cfg.GRPC.Addr
 		listener, err := net.Listen("tcp", grpcAddr)
 		if err != nil {
 			errs <- err
@@ -74,30 +177,42 @@ func main() {
 	logger.Log("terminated", <-errs)
 }
 
-func createLogger() log.Logger {
-	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stdout))
-	return log.With(logger, "ts", log.DefaultTimestampUTC())
+// createLogger logs to standard output for the server, and to standard
+// error for commands, whose output may be redirected
+func createLogger(cfg config.Log) log.Logger {
+	w := os.Stdout
+	if flag.NArg() > 0 {
+		w = os.Stderr
+	}
+	return cfg.NewLogger(w)
 }
 
-func createDatabase() (*sqlx.DB, error) {
-	db, err := sqlx.Connect("postgres", fmt.Sprintf("user=%s password=%s dbname=roster sslmode=disable",
-		os.Getenv("ROSTER_USER"),
-		os.Getenv("ROSTER_PASSWORD")))
+func createDatabase(cfg config.Database) (*sqlx.DB, error) {
+	db, err := sqlx.Connect("postgres", cfg.DSN())
 	if err != nil {
 		return nil, err
 	}
 
-	ddl, err := ioutil.ReadFile("create_table.sql")
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return db, nil
+}
+
+func createSQLiteDatabase(file string) (*sqlx.DB, error) {
+	db, err := sqlx.Connect("sqlite3", file)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = db.Exec(string(ddl))
-	return db, err
+	// SQLite allows a single writer, so share one connection
+	db.SetMaxOpenConns(1)
+	return db, nil
 }
 
-func createPlayersService(db *sqlx.DB, logger log.Logger) players.Service {
-	ps := players.NewService(db)
+func createPlayersService(repo models.PlayerRepository, logger log.Logger) players.Service {
+	ps := players.NewService(repo)
+	ps = players.NewValidatingService(ps)
 	ps = players.NewLoggingService(log.With(logger, "tag", "players"), ps)
 	return ps
 }
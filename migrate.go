@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/hoop33/roster/migrations"
+	"github.com/jmoiron/sqlx"
+)
+
+var errMigrateUsage = errors.New("usage: roster migrate up|down|status")
+var errMigrateNoDatabase = errors.New("migrations require the postgres or sqlite store")
+
+func runMigrate(db *sqlx.DB, args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errMigrateUsage
+	}
+
+	if db == nil {
+		return errMigrateNoDatabase
+	}
+
+	m := migrations.NewMigrator(db)
+	switch args[0] {
+	case "up":
+		n, err := m.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "applied %d migration(s)\n", n)
+		return nil
+	case "down":
+		migration, err := m.Down()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "rolled back %04d_%s\n", migration.Version, migration.Name)
+		return nil
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%04d_%s\t%s\n", s.Migration.Version, s.Migration.Name, applied)
+		}
+		return tw.Flush()
+	default:
+		return errMigrateUsage
+	}
+}
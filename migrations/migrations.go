@@ -0,0 +1,318 @@
+package migrations
+
+// Statements holds the SQL for a migration step in each supported dialect
+type Statements struct {
+	Postgres string
+	SQLite   string
+}
+
+// For returns the statements for the given database driver
+func (s Statements) For(driver string) string {
+	if driver == "sqlite3" {
+		return s.SQLite
+	}
+	return s.Postgres
+}
+
+// Migration is a numbered, reversible schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      Statements
+	Down    Statements
+}
+
+// All lists every migration in version order. Append new migrations to the
+// end; never edit or renumber one that has been released.
+var All = []Migration{
+	{
+		Version: 1,
+		Name:    "create_players",
+		Up: Statements{
+			Postgres: `CREATE TABLE IF NOT EXISTS players (
+				id SERIAL PRIMARY KEY,
+				name TEXT,
+				number TEXT,
+				position TEXT,
+				height TEXT,
+				weight TEXT,
+				age TEXT,
+				experience INTEGER,
+				college TEXT
+			)`,
+			SQLite: `CREATE TABLE IF NOT EXISTS players (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT,
+				number TEXT,
+				position TEXT,
+				height TEXT,
+				weight TEXT,
+				age TEXT,
+				experience INTEGER,
+				college TEXT
+			)`,
+		},
+		Down: Statements{
+			Postgres: `DROP TABLE players`,
+			SQLite:   `DROP TABLE players`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "type_player_attributes",
+		Up: Statements{
+			// Ages only approximate birth dates, so converted rows are
+			// dated the given number of years before the migration ran
+			Postgres: `ALTER TABLE players ADD COLUMN birth_date DATE;
+				UPDATE players SET birth_date = (CURRENT_DATE - age::integer * INTERVAL '1 year')::date
+					WHERE age ~ '^[0-9]+$';
+				ALTER TABLE players DROP COLUMN age;
+				ALTER TABLE players ALTER COLUMN number TYPE INTEGER
+					USING CASE WHEN number ~ '^[0-9]+$' THEN number::integer ELSE 0 END;
+				ALTER TABLE players ALTER COLUMN number SET NOT NULL;
+				ALTER TABLE players ALTER COLUMN number SET DEFAULT 0;
+				ALTER TABLE players ALTER COLUMN height TYPE INTEGER
+					USING CASE WHEN height ~ '^[0-9]+-[0-9]+$'
+						THEN split_part(height, '-', 1)::integer * 12 + split_part(height, '-', 2)::integer
+						ELSE 0 END;
+				ALTER TABLE players ALTER COLUMN height SET NOT NULL;
+				ALTER TABLE players ALTER COLUMN height SET DEFAULT 0;
+				ALTER TABLE players ALTER COLUMN weight TYPE INTEGER
+					USING CASE WHEN weight ~ '^[0-9]+$' THEN weight::integer ELSE 0 END;
+				ALTER TABLE players ALTER COLUMN weight SET NOT NULL;
+				ALTER TABLE players ALTER COLUMN weight SET DEFAULT 0`,
+			SQLite: `CREATE TABLE players_new (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT,
+					number INTEGER NOT NULL DEFAULT 0,
+					position TEXT,
+					height INTEGER NOT NULL DEFAULT 0,
+					weight INTEGER NOT NULL DEFAULT 0,
+					birth_date DATE,
+					experience INTEGER,
+					college TEXT
+				);
+				INSERT INTO players_new
+					(id, name, number, position, height, weight, birth_date, experience, college)
+					SELECT id, name,
+						CASE WHEN number GLOB '[0-9]*' THEN CAST(number AS INTEGER) ELSE 0 END,
+						position,
+						CASE WHEN height GLOB '[0-9]*-[0-9]*'
+							THEN CAST(substr(height, 1, instr(height, '-') - 1) AS INTEGER) * 12 +
+								CAST(substr(height, instr(height, '-') + 1) AS INTEGER)
+							ELSE 0 END,
+						CASE WHEN weight GLOB '[0-9]*' THEN CAST(weight AS INTEGER) ELSE 0 END,
+						CASE WHEN age GLOB '[0-9]*' THEN date('now', '-' || age || ' years') END,
+						experience, college
+					FROM players;
+				DROP TABLE players;
+				ALTER TABLE players_new RENAME TO players`,
+		},
+		Down: Statements{
+			Postgres: `ALTER TABLE players ADD COLUMN age TEXT;
+				UPDATE players SET age = date_part('year', age(birth_date))::text
+					WHERE birth_date IS NOT NULL;
+				ALTER TABLE players DROP COLUMN birth_date;
+				ALTER TABLE players ALTER COLUMN number DROP DEFAULT;
+				ALTER TABLE players ALTER COLUMN number DROP NOT NULL;
+				ALTER TABLE players ALTER COLUMN number TYPE TEXT USING number::text;
+				ALTER TABLE players ALTER COLUMN height DROP DEFAULT;
+				ALTER TABLE players ALTER COLUMN height DROP NOT NULL;
+				ALTER TABLE players ALTER COLUMN height TYPE TEXT
+					USING CASE WHEN height > 0 THEN (height / 12) || '-' || (height % 12) END;
+				ALTER TABLE players ALTER COLUMN weight DROP DEFAULT;
+				ALTER TABLE players ALTER COLUMN weight DROP NOT NULL;
+				ALTER TABLE players ALTER COLUMN weight TYPE TEXT
+					USING CASE WHEN weight > 0 THEN weight::text END`,
+			SQLite: `CREATE TABLE players_old (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT,
+					number TEXT,
+					position TEXT,
+					height TEXT,
+					weight TEXT,
+					age TEXT,
+					experience INTEGER,
+					college TEXT
+				);
+				INSERT INTO players_old
+					(id, name, number, position, height, weight, age, experience, college)
+					SELECT id, name,
+						CAST(number AS TEXT),
+						position,
+						CASE WHEN height > 0 THEN (height / 12) || '-' || (height % 12) END,
+						CASE WHEN weight > 0 THEN CAST(weight AS TEXT) END,
+						CASE WHEN birth_date IS NOT NULL
+							THEN CAST((julianday('now') - julianday(birth_date)) / 365.25 AS INTEGER) END,
+						experience, college
+					FROM players;
+				DROP TABLE players;
+				ALTER TABLE players_old RENAME TO players`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "index_player_names",
+		Up: Statements{
+			Postgres: `CREATE EXTENSION IF NOT EXISTS pg_trgm;
+				CREATE INDEX players_name_tsv ON players USING GIN (to_tsvector('simple', name));
+				CREATE INDEX players_name_trgm ON players USING GIN (name gin_trgm_ops)`,
+			SQLite: `CREATE INDEX players_name ON players (name)`,
+		},
+		Down: Statements{
+			Postgres: `DROP INDEX players_name_trgm;
+				DROP INDEX players_name_tsv`,
+			SQLite: `DROP INDEX players_name`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add_player_versions",
+		Up: Statements{
+			Postgres: `ALTER TABLE players ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+			SQLite:   `ALTER TABLE players ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+		},
+		Down: Statements{
+			Postgres: `ALTER TABLE players DROP COLUMN version`,
+			// SQLite can't drop columns, so rebuild the table without it
+			SQLite: `CREATE TABLE players_old (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT,
+					number INTEGER NOT NULL DEFAULT 0,
+					position TEXT,
+					height INTEGER NOT NULL DEFAULT 0,
+					weight INTEGER NOT NULL DEFAULT 0,
+					birth_date DATE,
+					experience INTEGER,
+					college TEXT
+				);
+				INSERT INTO players_old
+					(id, name, number, position, height, weight, birth_date, experience, college)
+					SELECT id, name, number, position, height, weight, birth_date, experience, college
+					FROM players;
+				DROP TABLE players;
+				ALTER TABLE players_old RENAME TO players;
+				CREATE INDEX players_name ON players (name)`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add_player_updated_at",
+		Up: Statements{
+			Postgres: `ALTER TABLE players ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()`,
+			// SQLite only allows constant defaults on added columns, so
+			// stamp the existing rows separately
+			SQLite: `ALTER TABLE players ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+				UPDATE players SET updated_at = CURRENT_TIMESTAMP`,
+		},
+		Down: Statements{
+			Postgres: `ALTER TABLE players DROP COLUMN updated_at`,
+			SQLite: `CREATE TABLE players_old (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT,
+					number INTEGER NOT NULL DEFAULT 0,
+					position TEXT,
+					height INTEGER NOT NULL DEFAULT 0,
+					weight INTEGER NOT NULL DEFAULT 0,
+					birth_date DATE,
+					experience INTEGER,
+					college TEXT,
+					version INTEGER NOT NULL DEFAULT 1
+				);
+				INSERT INTO players_old
+					(id, name, number, position, height, weight, birth_date, experience, college, version)
+					SELECT id, name, number, position, height, weight, birth_date, experience, college, version
+					FROM players;
+				DROP TABLE players;
+				ALTER TABLE players_old RENAME TO players;
+				CREATE INDEX players_name ON players (name)`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create_player_history",
+		Up: Statements{
+			// No foreign key to players, since history outlives deletes
+			Postgres: `CREATE TABLE player_history (
+					id SERIAL PRIMARY KEY,
+					player_id INTEGER NOT NULL,
+					version INTEGER NOT NULL,
+					operation TEXT NOT NULL,
+					actor TEXT NOT NULL,
+					changed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+					changes TEXT NOT NULL
+				);
+				CREATE INDEX player_history_player ON player_history (player_id, id)`,
+			SQLite: `CREATE TABLE player_history (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					player_id INTEGER NOT NULL,
+					version INTEGER NOT NULL,
+					operation TEXT NOT NULL,
+					actor TEXT NOT NULL,
+					changed_at TIMESTAMP NOT NULL,
+					changes TEXT NOT NULL
+				);
+				CREATE INDEX player_history_player ON player_history (player_id, id)`,
+		},
+		Down: Statements{
+			Postgres: `DROP TABLE player_history`,
+			SQLite:   `DROP TABLE player_history`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_player_deleted_at",
+		Up: Statements{
+			// The partial index keeps the purge job's scan small
+			Postgres: `ALTER TABLE players ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+				CREATE INDEX players_deleted_at ON players (deleted_at) WHERE deleted_at IS NOT NULL`,
+			SQLite: `ALTER TABLE players ADD COLUMN deleted_at TIMESTAMP;
+				CREATE INDEX players_deleted_at ON players (deleted_at) WHERE deleted_at IS NOT NULL`,
+		},
+		Down: Statements{
+			// Deleted players can't be hidden without the column, so
+			// purge them rather than bring them back
+			Postgres: `DELETE FROM players WHERE deleted_at IS NOT NULL;
+				DROP INDEX players_deleted_at;
+				ALTER TABLE players DROP COLUMN deleted_at`,
+			SQLite: `DELETE FROM players WHERE deleted_at IS NOT NULL;
+				DROP INDEX players_deleted_at;
+				CREATE TABLE players_old (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					name TEXT,
+					number INTEGER NOT NULL DEFAULT 0,
+					position TEXT,
+					height INTEGER NOT NULL DEFAULT 0,
+					weight INTEGER NOT NULL DEFAULT 0,
+					birth_date DATE,
+					experience INTEGER,
+					college TEXT,
+					version INTEGER NOT NULL DEFAULT 1,
+					updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00'
+				);
+				INSERT INTO players_old
+					(id, name, number, position, height, weight, birth_date, experience, college, version, updated_at)
+					SELECT id, name, number, position, height, weight, birth_date, experience, college, version, updated_at
+					FROM players;
+				DROP TABLE players;
+				ALTER TABLE players_old RENAME TO players;
+				CREATE INDEX players_name ON players (name)`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "trim_sqlite_birth_dates",
+		// Saved birth dates were once stored with a time in SQLite, which
+		// compares them as text against the plain dates of migrated rows.
+		// PostgreSQL has a real date type, and the old format needs no
+		// restoring, so the rest do nothing.
+		Up: Statements{
+			Postgres: `SELECT 1`,
+			SQLite:   `UPDATE players SET birth_date = substr(birth_date, 1, 10) WHERE length(birth_date) > 10`,
+		},
+		Down: Statements{
+			Postgres: `SELECT 1`,
+			SQLite:   `SELECT 1`,
+		},
+	},
+}
@@ -0,0 +1,166 @@
+package migrations
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNoMigrations is returned when there is no applied migration to roll back
+var ErrNoMigrations = errors.New("no migrations to roll back")
+
+// Status describes whether a migration has been applied
+type Status struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies and rolls back migrations against a database
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+type appliedMigration struct {
+	Version   int       `db:"version"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// NewMigrator returns a migrator for all known migrations
+func NewMigrator(db *sqlx.DB) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: All,
+	}
+}
+
+// Up applies all pending migrations and returns how many were applied
+func (m *Migrator) Up() (int, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, migration := range pending {
+		if err := m.apply(migration); err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+// Down rolls back the most recently applied migration and returns it
+func (m *Migrator) Down() (*Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; ok {
+			return &migration, m.revert(migration)
+		}
+	}
+	return nil, ErrNoMigrations
+}
+
+// Status returns the status of every known migration
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		at, ok := applied[migration.Version]
+		statuses[i] = Status{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: at,
+		}
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) apply(migration Migration) error {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(migration.Up.For(m.db.DriverName())); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec(m.db.Rebind(`INSERT INTO schema_migrations
+		(version, applied_at)
+		VALUES (?, ?)`),
+		migration.Version, time.Now().UTC()); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) revert(migration Migration) error {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(migration.Down.For(m.db.DriverName())); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec(m.db.Rebind(`DELETE FROM schema_migrations
+		WHERE version=?`),
+		migration.Version); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	if _, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return nil, err
+	}
+
+	var rows []appliedMigration
+	if err := m.db.Select(&rows, "SELECT version, applied_at FROM schema_migrations"); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
@@ -0,0 +1,185 @@
+package migrations
+
+import (
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpShouldApplyAllPendingMigrations(t *testing.T) {
+	db, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	n, err := m.Up()
+	assert.Nil(t, err)
+	assert.Equal(t, len(All), n)
+
+	pending, err := m.Pending()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(pending))
+
+	_, err = db.Exec("SELECT * FROM players")
+	assert.Nil(t, err)
+}
+
+func TestUpShouldDoNothingWhenCurrent(t *testing.T) {
+	db, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	_, err = m.Up()
+	assert.Nil(t, err)
+
+	n, err := m.Up()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestDownShouldRollBackLatestMigration(t *testing.T) {
+	db, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	_, err = m.Up()
+	assert.Nil(t, err)
+
+	migration, err := m.Down()
+	assert.Nil(t, err)
+	assert.Equal(t, All[len(All)-1].Version, migration.Version)
+
+	pending, err := m.Pending()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pending))
+}
+
+func TestDownShouldReturnErrorWhenNothingApplied(t *testing.T) {
+	db, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	_, err = NewMigrator(db).Down()
+	assert.Equal(t, ErrNoMigrations, err)
+}
+
+func TestStatusShouldReportAppliedAndPendingMigrations(t *testing.T) {
+	db, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	m := NewMigrator(db)
+	statuses, err := m.Status()
+	assert.Nil(t, err)
+	assert.Equal(t, len(All), len(statuses))
+	assert.False(t, statuses[0].Applied)
+
+	_, err = m.Up()
+	assert.Nil(t, err)
+
+	statuses, err = m.Status()
+	assert.Nil(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[0].AppliedAt.IsZero())
+}
+
+func TestTypePlayerAttributesShouldConvertLegacyRows(t *testing.T) {
+	db, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	m := &Migrator{
+		db:         db,
+		migrations: All[:1],
+	}
+	_, err = m.Up()
+	assert.Nil(t, err)
+
+	_, err = db.Exec(`INSERT INTO players
+		(name, number, position, height, weight, age, experience, college)
+		VALUES ('Blake Bortles', '5', 'QB', '6-5', '236', '26', 5, 'Central Florida'),
+		('Rookie', '', 'K', 'N/A', '', '', 0, 'N/A')`)
+	assert.Nil(t, err)
+
+	m.migrations = All[:2]
+	_, err = m.Up()
+	assert.Nil(t, err)
+
+	var row struct {
+		Number    int    `db:"number"`
+		Height    int    `db:"height"`
+		Weight    int    `db:"weight"`
+		BirthYear string `db:"birth_year"`
+	}
+	err = db.Get(&row, "SELECT number, height, weight, strftime('%Y', birth_date) AS birth_year FROM players WHERE id = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, row.Number)
+	assert.Equal(t, 77, row.Height)
+	assert.Equal(t, 236, row.Weight)
+	assert.Equal(t, strconv.Itoa(time.Now().Year()-26), row.BirthYear)
+
+	err = db.Get(&row, "SELECT number, height, weight, '' AS birth_year FROM players WHERE id = 2")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, row.Number)
+	assert.Equal(t, 0, row.Height)
+
+	_, err = m.Down()
+	assert.Nil(t, err)
+
+	var height string
+	err = db.Get(&height, "SELECT height FROM players WHERE id = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, "6-5", height)
+}
+
+func TestTrimSQLiteBirthDatesShouldDropTimes(t *testing.T) {
+	db, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	m := &Migrator{
+		db:         db,
+		migrations: All[:7],
+	}
+	_, err = m.Up()
+	assert.Nil(t, err)
+
+	_, err = db.Exec(`INSERT INTO players (name, birth_date) VALUES
+		('Blake Bortles', '1992-04-29 00:00:00+00:00'),
+		('Jalen Ramsey', '1994-10-24'),
+		('Jarrod Wilson', NULL)`)
+	assert.Nil(t, err)
+
+	m.migrations = All[:8]
+	_, err = m.Up()
+	assert.Nil(t, err)
+
+	var dates []sql.NullString
+	assert.Nil(t, db.Select(&dates, "SELECT CAST(birth_date AS TEXT) FROM players ORDER BY id"))
+	assert.Equal(t, []sql.NullString{{String: "1992-04-29", Valid: true}, {String: "1994-10-24", Valid: true}, {}}, dates)
+}
+
+func TestStatementsForShouldSelectDialect(t *testing.T) {
+	s := Statements{
+		Postgres: "postgres",
+		SQLite:   "sqlite",
+	}
+	assert.Equal(t, "postgres", s.For("postgres"))
+	assert.Equal(t, "sqlite", s.For("sqlite3"))
+}
+
+func createDB() (*sqlx.DB, error) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
@@ -0,0 +1,257 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateFormat is the layout used to encode birth dates
+const DateFormat = "2006-01-02"
+
+// Number is a jersey number
+type Number int
+
+// Height is a height in inches
+type Height int
+
+// Weight is a weight in pounds
+type Weight int
+
+// Date is a calendar date; the zero value means unknown
+type Date struct {
+	time.Time
+}
+
+// ParseNumber parses a jersey number such as "5"; blank means 0
+func ParseNumber(s string) (Number, error) {
+	n, err := parseInt(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return Number(n), nil
+}
+
+// String returns the jersey number as a string
+func (n Number) String() string {
+	return strconv.Itoa(int(n))
+}
+
+// MarshalJSON encodes the jersey number as a string, as earlier versions did
+func (n Number) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
+// UnmarshalJSON accepts the jersey number as either a string or a number
+func (n *Number) UnmarshalJSON(data []byte) error {
+	s, err := unquote(data)
+	if err != nil {
+		return err
+	}
+	*n, err = ParseNumber(s)
+	return err
+}
+
+// ParseHeight parses a height in feet-inches form such as "6-4", or in
+// inches such as "76"; blank means 0
+func ParseHeight(s string) (Height, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) == 1 {
+		inches, err := parseInt(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid height %q", s)
+		}
+		return Height(inches), nil
+	}
+
+	feet, err := strconv.Atoi(parts[0])
+	if err != nil || feet < 0 {
+		return 0, fmt.Errorf("invalid height %q", s)
+	}
+	inches, err := strconv.Atoi(parts[1])
+	if err != nil || inches < 0 || inches > 11 {
+		return 0, fmt.Errorf("invalid height %q", s)
+	}
+	return Height(feet*12 + inches), nil
+}
+
+// Feet returns the whole feet in the height
+func (h Height) Feet() int {
+	return int(h) / 12
+}
+
+// Inches returns the inches left over after the whole feet
+func (h Height) Inches() int {
+	return int(h) % 12
+}
+
+// String returns the height in feet-inches form, or blank if unknown
+func (h Height) String() string {
+	if h <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", h.Feet(), h.Inches())
+}
+
+// MarshalJSON encodes the height in feet-inches form
+func (h Height) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.String())
+}
+
+// UnmarshalJSON accepts the height in feet-inches form or as inches
+func (h *Height) UnmarshalJSON(data []byte) error {
+	s, err := unquote(data)
+	if err != nil {
+		return err
+	}
+	*h, err = ParseHeight(s)
+	return err
+}
+
+// ParseWeight parses a weight in pounds such as "236"; blank means 0
+func ParseWeight(s string) (Weight, error) {
+	w, err := parseInt(strings.TrimSuffix(strings.TrimSpace(s), "lb"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid weight %q", s)
+	}
+	return Weight(w), nil
+}
+
+// String returns the weight in pounds as a string, or blank if unknown
+func (w Weight) String() string {
+	if w <= 0 {
+		return ""
+	}
+	return strconv.Itoa(int(w))
+}
+
+// MarshalJSON encodes the weight as a string, as earlier versions did
+func (w Weight) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.String())
+}
+
+// UnmarshalJSON accepts the weight as either a string or a number
+func (w *Weight) UnmarshalJSON(data []byte) error {
+	s, err := unquote(data)
+	if err != nil {
+		return err
+	}
+	*w, err = ParseWeight(s)
+	return err
+}
+
+// ParseDate parses a date such as "1992-04-29"; blank means unknown
+func ParseDate(s string) (Date, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Date{}, nil
+	}
+	t, err := time.Parse(DateFormat, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q", s)
+	}
+	return Date{t}, nil
+}
+
+// NewDate returns the date for the given year, month, and day
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// String returns the date in DateFormat, or blank if unknown
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(DateFormat)
+}
+
+// YearsUntil returns the number of whole years from the date to t
+func (d Date) YearsUntil(t time.Time) int {
+	if d.IsZero() {
+		return 0
+	}
+	years := t.Year() - d.Year()
+	if t.Month() < d.Month() || (t.Month() == d.Month() && t.Day() < d.Day()) {
+		years--
+	}
+	return years
+}
+
+// MarshalJSON encodes the date in DateFormat
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes a date in DateFormat
+func (d *Date) UnmarshalJSON(data []byte) error {
+	s, err := unquote(data)
+	if err != nil {
+		return err
+	}
+	*d, err = ParseDate(s)
+	return err
+}
+
+// Scan implements sql.Scanner
+func (d *Date) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Date{}
+		return nil
+	case time.Time:
+		*d = NewDate(v.Year(), v.Month(), v.Day())
+		return nil
+	case []byte:
+		return d.scanString(string(v))
+	case string:
+		return d.scanString(v)
+	}
+	return fmt.Errorf("cannot scan %T into Date", src)
+}
+
+// Value implements driver.Valuer. Dates are stored in DateFormat, as
+// SQLite keeps them as text and compares them as strings.
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.Format(DateFormat), nil
+}
+
+func (d *Date) scanString(s string) error {
+	if len(s) > len(DateFormat) {
+		s = s[:len(DateFormat)]
+	}
+	date, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = date
+	return nil
+}
+
+func parseInt(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func unquote(data []byte) (string, error) {
+	if bytes.Equal(data, []byte("null")) {
+		return "", nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		err := json.Unmarshal(data, &s)
+		return s, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNumberShouldParseDigits(t *testing.T) {
+	n, err := ParseNumber(" 20 ")
+	assert.Nil(t, err)
+	assert.Equal(t, Number(20), n)
+
+	n, err = ParseNumber("")
+	assert.Nil(t, err)
+	assert.Equal(t, Number(0), n)
+
+	_, err = ParseNumber("R")
+	assert.NotNil(t, err)
+}
+
+func TestParseHeightShouldParseFeetAndInches(t *testing.T) {
+	h, err := ParseHeight("6-4")
+	assert.Nil(t, err)
+	assert.Equal(t, Height(76), h)
+	assert.Equal(t, 6, h.Feet())
+	assert.Equal(t, 4, h.Inches())
+	assert.Equal(t, "6-4", h.String())
+}
+
+func TestParseHeightShouldParseInches(t *testing.T) {
+	h, err := ParseHeight("71")
+	assert.Nil(t, err)
+	assert.Equal(t, "5-11", h.String())
+}
+
+func TestParseHeightShouldReturnErrorWhenInvalid(t *testing.T) {
+	for _, s := range []string{"tall", "6-12", "6-", "-4", "N/A"} {
+		_, err := ParseHeight(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestParseWeightShouldAllowPoundSuffix(t *testing.T) {
+	w, err := ParseWeight("236lb")
+	assert.Nil(t, err)
+	assert.Equal(t, Weight(236), w)
+	assert.Equal(t, "236", w.String())
+	assert.Equal(t, "", Weight(0).String())
+}
+
+func TestParseDateShouldParseISODates(t *testing.T) {
+	d, err := ParseDate("1992-04-29")
+	assert.Nil(t, err)
+	assert.Equal(t, NewDate(1992, time.April, 29), d)
+	assert.Equal(t, "1992-04-29", d.String())
+
+	d, err = ParseDate("")
+	assert.Nil(t, err)
+	assert.True(t, d.IsZero())
+
+	_, err = ParseDate("04/29/1992")
+	assert.NotNil(t, err)
+}
+
+func TestDateYearsUntilShouldCountWholeYears(t *testing.T) {
+	d := NewDate(1992, time.April, 29)
+	assert.Equal(t, 25, d.YearsUntil(time.Date(2018, time.April, 28, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 26, d.YearsUntil(time.Date(2018, time.April, 29, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 0, Date{}.YearsUntil(time.Now()))
+}
+
+func TestDateScanShouldAcceptTimesAndStrings(t *testing.T) {
+	var d Date
+	assert.Nil(t, d.Scan(time.Date(1992, time.April, 29, 13, 0, 0, 0, time.UTC)))
+	assert.Equal(t, NewDate(1992, time.April, 29), d)
+
+	assert.Nil(t, d.Scan([]byte("1995-10-24")))
+	assert.Equal(t, NewDate(1995, time.October, 24), d)
+
+	assert.Nil(t, d.Scan(nil))
+	assert.True(t, d.IsZero())
+
+	v, err := d.Value()
+	assert.Nil(t, err)
+	assert.Nil(t, v)
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// The formats players can be exported in
+const (
+	ExportCSV   = "csv"
+	ExportJSONL = "jsonl"
+	ExportTSV   = "tsv"
+)
+
+// ErrExportFormat is returned when players are exported in an unknown format
+var ErrExportFormat = errors.New("unknown export format")
+
+// exportColumns are the columns of CSV and TSV exports, which ParseImport
+// reads back
+var exportColumns = []string{"id", "name", "number", "position", "height", "weight", "birth_date", "experience", "college"}
+
+// PlayerWriter writes players one at a time in an export format. Flush
+// must be called after the last player.
+type PlayerWriter interface {
+	Write(p *Player) error
+	Flush() error
+}
+
+// NewPlayerWriter returns a PlayerWriter for a format
+func NewPlayerWriter(w io.Writer, format string) (PlayerWriter, error) {
+	switch format {
+	case ExportCSV:
+		return &delimitedWriter{w: csv.NewWriter(w)}, nil
+	case ExportTSV:
+		cw := csv.NewWriter(w)
+		cw.Comma = '\t'
+		return &delimitedWriter{w: cw}, nil
+	case ExportJSONL:
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("%v %q", ErrExportFormat, format)
+}
+
+// delimitedWriter writes a header row followed by a row per player. The
+// header is written with the first player, or on Flush if there are none.
+type delimitedWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (d *delimitedWriter) writeHeader() error {
+	if d.header {
+		return nil
+	}
+	d.header = true
+	return d.w.Write(exportColumns)
+}
+
+func (d *delimitedWriter) Write(p *Player) error {
+	if err := d.writeHeader(); err != nil {
+		return err
+	}
+	return d.w.Write([]string{
+		strconv.Itoa(p.ID),
+		p.Name,
+		p.Number.String(),
+		p.Position,
+		p.Height.String(),
+		p.Weight.String(),
+		p.BirthDate.String(),
+		strconv.Itoa(p.Experience),
+		p.College,
+	})
+}
+
+func (d *delimitedWriter) Flush() error {
+	if err := d.writeHeader(); err != nil {
+		return err
+	}
+	d.w.Flush()
+	return d.w.Error()
+}
+
+// jsonlWriter writes each player as a line of JSON
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(p *Player) error {
+	return j.enc.Encode(p)
+}
+
+func (j *jsonlWriter) Flush() error {
+	return nil
+}
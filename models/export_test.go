@@ -0,0 +1,108 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPlayerWriterShouldRejectUnknownFormat(t *testing.T) {
+	_, err := NewPlayerWriter(&bytes.Buffer{}, "xlsx")
+	assert.EqualError(t, err, `unknown export format "xlsx"`)
+}
+
+func TestPlayerWriterShouldWriteCSVThatImports(t *testing.T) {
+	var buf bytes.Buffer
+	pw, err := NewPlayerWriter(&buf, ExportCSV)
+	assert.Nil(t, err)
+	assert.Nil(t, pw.Write(&Player{
+		ID:         1,
+		Name:       "Blake Bortles",
+		Number:     5,
+		Position:   "QB",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  NewDate(1992, time.April, 29),
+		Experience: 5,
+		College:    "Central Florida",
+	}))
+	assert.Nil(t, pw.Write(&Player{ID: 2, Name: "Smith, Telvin", Number: 50}))
+	assert.Nil(t, pw.Flush())
+	assert.Equal(t, `id,name,number,position,height,weight,birth_date,experience,college
+1,Blake Bortles,5,QB,6-5,236,1992-04-29,5,Central Florida
+2,"Smith, Telvin",50,,,,,0,
+`, buf.String())
+
+	rows, err := ParseImport(&buf, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rows))
+	assert.Nil(t, rows[0].Errors)
+	assert.Equal(t, 1, rows[0].Player.ID)
+	assert.Equal(t, NewDate(1992, time.April, 29), rows[0].Player.BirthDate)
+	assert.Equal(t, "Smith, Telvin", rows[1].Player.Name)
+}
+
+func TestPlayerWriterShouldWriteJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	pw, err := NewPlayerWriter(&buf, ExportJSONL)
+	assert.Nil(t, err)
+	assert.Nil(t, pw.Write(&Player{ID: 1, Name: "Blake Bortles"}))
+	assert.Nil(t, pw.Write(&Player{ID: 2, Name: "Jalen Ramsey"}))
+	assert.Nil(t, pw.Flush())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.True(t, strings.HasPrefix(lines[1], `{"id":2,"name":"Jalen Ramsey",`))
+}
+
+func TestRepositoriesShouldExportFilteredPlayers(t *testing.T) {
+	db, err := createSQLiteDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	for name, repo := range map[string]PlayerRepository{
+		"memory": NewMemoryRepository(),
+		"sqlite": NewSQLiteRepository(db),
+	} {
+		ctx := context.Background()
+		for _, p := range []*Player{
+			{Name: "Blake Bortles", Number: 5, Position: "QB"},
+			{Name: "Jalen Ramsey", Number: 20, Position: "CB"},
+			{Name: "Cody Kessler", Number: 6, Position: "QB"},
+		} {
+			_, _, err := repo.SavePlayer(ctx, p)
+			assert.Nil(t, err, name)
+		}
+		assert.Nil(t, repo.DeletePlayer(ctx, 3, 0), name)
+
+		var names []string
+		collect := func(p *Player) error {
+			names = append(names, p.Name)
+			return nil
+		}
+		assert.Nil(t, repo.ExportPlayers(ctx, PlayerFilter{Positions: []string{"QB"}}, collect), name)
+		assert.Equal(t, []string{"Blake Bortles"}, names, name)
+
+		names = nil
+		filter := PlayerFilter{IncludeDeleted: true, Sort: ParseSort("-number")}
+		assert.Nil(t, repo.ExportPlayers(ctx, filter, collect), name)
+		assert.Equal(t, []string{"Jalen Ramsey", "Cody Kessler", "Blake Bortles"}, names, name)
+
+		stop := errors.New("stop")
+		names = nil
+		err := repo.ExportPlayers(ctx, PlayerFilter{}, func(p *Player) error {
+			names = append(names, p.Name)
+			return stop
+		})
+		assert.Equal(t, stop, err, name)
+		assert.Equal(t, 1, len(names), name)
+
+		err = repo.ExportPlayers(ctx, PlayerFilter{Sort: ParseSort("salary")}, collect)
+		assert.Equal(t, ErrInvalidSort, err, name)
+	}
+}
@@ -0,0 +1,270 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidSort is returned when a list is sorted by an unknown field
+var ErrInvalidSort = errors.New("invalid sort field")
+
+// PlayerFilter restricts and orders a list of players. The zero value
+// matches every player that isn't deleted, ordered by number.
+type PlayerFilter struct {
+	Positions      []string
+	College        string
+	NamePrefix     string
+	MinExperience  *int
+	MaxExperience  *int
+	IncludeDeleted bool
+	Sort           []SortField
+}
+
+// SortField orders a list by a player field
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// DefaultSort orders players by jersey number
+var DefaultSort = []SortField{{Field: "number"}}
+
+// sortColumns maps the fields a list can be sorted by to their columns.
+// Missing birth dates sort first, as they do in memory.
+var sortColumns = map[string]string{
+	"name":       "name",
+	"number":     "number",
+	"position":   "position",
+	"height":     "height",
+	"weight":     "weight",
+	"birth_date": "COALESCE(birth_date, '0001-01-01')",
+	"experience": "experience",
+	"college":    "college",
+	"id":         "id",
+}
+
+// IsSortField reports whether a list can be sorted by the field
+func IsSortField(field string) bool {
+	_, ok := sortColumns[field]
+	return ok
+}
+
+// ParseSort parses a comma-separated list of fields, each prefixed with
+// "-" for descending order, e.g., "-experience,name"
+func ParseSort(s string) []SortField {
+	var fields []SortField
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		sf := SortField{Field: strings.TrimPrefix(f, "+")}
+		if strings.HasPrefix(f, "-") {
+			sf = SortField{Field: f[1:], Descending: true}
+		}
+		fields = append(fields, sf)
+	}
+	return fields
+}
+
+// FormatSort is the inverse of ParseSort
+func FormatSort(fields []SortField) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f SortField) String() string {
+	if f.Descending {
+		return "-" + f.Field
+	}
+	return f.Field
+}
+
+// order returns the sort fields, ending with id so the order is total
+func (f PlayerFilter) order() ([]SortField, error) {
+	fields := f.Sort
+	if len(fields) == 0 {
+		fields = DefaultSort
+	}
+
+	order := make([]SortField, 0, len(fields)+1)
+	for _, sf := range fields {
+		if !IsSortField(sf.Field) {
+			return nil, ErrInvalidSort
+		}
+		order = append(order, sf)
+		if sf.Field == "id" {
+			return order, nil
+		}
+	}
+	return append(order, SortField{Field: "id"}), nil
+}
+
+func (f PlayerFilter) matches(p Player) bool {
+	if !f.IncludeDeleted && p.DeletedAt != nil {
+		return false
+	}
+	if len(f.Positions) > 0 && !contains(f.Positions, p.Position) {
+		return false
+	}
+	if f.College != "" && p.College != f.College {
+		return false
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(p.Name), strings.ToLower(f.NamePrefix)) {
+		return false
+	}
+	if f.MinExperience != nil && p.Experience < *f.MinExperience {
+		return false
+	}
+	if f.MaxExperience != nil && p.Experience > *f.MaxExperience {
+		return false
+	}
+	return true
+}
+
+// where returns the conditions and arguments that restrict a query to
+// the players the filter matches
+func (f PlayerFilter) where() ([]string, []interface{}) {
+	var where []string
+	var args []interface{}
+	if !f.IncludeDeleted {
+		where = append(where, "deleted_at IS NULL")
+	}
+	if len(f.Positions) > 0 {
+		where = append(where, "position IN ("+placeholders(len(f.Positions))+")")
+		for _, p := range f.Positions {
+			args = append(args, p)
+		}
+	}
+	if f.College != "" {
+		where = append(where, "college = ?")
+		args = append(args, f.College)
+	}
+	if f.NamePrefix != "" {
+		where = append(where, `LOWER(name) LIKE ? ESCAPE '\'`)
+		args = append(args, likePrefix(strings.ToLower(f.NamePrefix)))
+	}
+	if f.MinExperience != nil {
+		where = append(where, "experience >= ?")
+		args = append(args, *f.MinExperience)
+	}
+	if f.MaxExperience != nil {
+		where = append(where, "experience <= ?")
+		args = append(args, *f.MaxExperience)
+	}
+	return where, args
+}
+
+// comparePlayers compares two players by a sort field, returning a
+// negative number, zero, or a positive number
+func comparePlayers(a, b Player, field string) int {
+	switch field {
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "number":
+		return int(a.Number) - int(b.Number)
+	case "position":
+		return strings.Compare(a.Position, b.Position)
+	case "height":
+		return int(a.Height) - int(b.Height)
+	case "weight":
+		return int(a.Weight) - int(b.Weight)
+	case "birth_date":
+		switch {
+		case a.BirthDate.Before(b.BirthDate.Time):
+			return -1
+		case a.BirthDate.After(b.BirthDate.Time):
+			return 1
+		}
+		return 0
+	case "experience":
+		return a.Experience - b.Experience
+	case "college":
+		return strings.Compare(a.College, b.College)
+	}
+	return a.ID - b.ID
+}
+
+// less reports whether a sorts before b
+func less(a, b Player, order []SortField) bool {
+	for _, sf := range order {
+		c := comparePlayers(a, b, sf.Field)
+		if c == 0 {
+			continue
+		}
+		if sf.Descending {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+// sortKey returns the value of a sort field, as a query argument
+func sortKey(p Player, field string) interface{} {
+	switch field {
+	case "name":
+		return p.Name
+	case "number":
+		return p.Number
+	case "position":
+		return p.Position
+	case "height":
+		return p.Height
+	case "weight":
+		return p.Weight
+	case "birth_date":
+		// Missing dates are the zero time, matching the COALESCE above
+		return p.BirthDate.Format(DateFormat)
+	case "experience":
+		return p.Experience
+	case "college":
+		return p.College
+	}
+	return p.ID
+}
+
+// setSortKey copies the value of a sort field from one player to another
+func setSortKey(dst *Player, src Player, field string) {
+	switch field {
+	case "name":
+		dst.Name = src.Name
+	case "number":
+		dst.Number = src.Number
+	case "position":
+		dst.Position = src.Position
+	case "height":
+		dst.Height = src.Height
+	case "weight":
+		dst.Weight = src.Weight
+	case "birth_date":
+		dst.BirthDate = src.BirthDate
+	case "experience":
+		dst.Experience = src.Experience
+	case "college":
+		dst.College = src.College
+	default:
+		dst.ID = src.ID
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func likePrefix(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s) + "%"
+}
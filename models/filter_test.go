@@ -0,0 +1,78 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSortShouldParseDirections(t *testing.T) {
+	assert.Equal(t, []SortField{
+		{Field: "experience", Descending: true},
+		{Field: "name"},
+		{Field: "college"},
+	}, ParseSort("-experience, name,+college,"))
+}
+
+func TestParseSortShouldReturnNilWhenEmpty(t *testing.T) {
+	assert.Nil(t, ParseSort(""))
+}
+
+func TestFormatSortShouldReverseParseSort(t *testing.T) {
+	assert.Equal(t, "-experience,name", FormatSort(ParseSort("-experience,name")))
+}
+
+func TestOrderShouldDefaultToNumberThenID(t *testing.T) {
+	order, err := PlayerFilter{}.order()
+	assert.Nil(t, err)
+	assert.Equal(t, numberOrder, order)
+}
+
+func TestOrderShouldNotAppendIDTwice(t *testing.T) {
+	order, err := PlayerFilter{Sort: []SortField{{Field: "id", Descending: true}, {Field: "name"}}}.order()
+	assert.Nil(t, err)
+	assert.Equal(t, []SortField{{Field: "id", Descending: true}}, order)
+}
+
+func TestOrderShouldReturnErrorWhenFieldUnknown(t *testing.T) {
+	_, err := PlayerFilter{Sort: []SortField{{Field: "salary"}}}.order()
+	assert.Equal(t, ErrInvalidSort, err)
+}
+
+func TestFilterShouldMatchAllConditions(t *testing.T) {
+	one, three := 1, 3
+	f := PlayerFilter{
+		Positions:     []string{"QB", "RB"},
+		College:       "LSU",
+		NamePrefix:    "leo",
+		MinExperience: &one,
+		MaxExperience: &three,
+	}
+	lf := Player{Name: "Leonard Fournette", Position: "RB", College: "LSU", Experience: 2}
+	assert.True(t, f.matches(lf))
+
+	for _, p := range []Player{
+		{Name: "Leonard Fournette", Position: "CB", College: "LSU", Experience: 2},
+		{Name: "Leonard Fournette", Position: "RB", College: "Florida", Experience: 2},
+		{Name: "Blake Bortles", Position: "RB", College: "LSU", Experience: 2},
+		{Name: "Leonard Fournette", Position: "RB", College: "LSU", Experience: 0},
+		{Name: "Leonard Fournette", Position: "RB", College: "LSU", Experience: 4},
+	} {
+		assert.False(t, f.matches(p), p.String())
+	}
+}
+
+func TestFilterWhereShouldEscapeNamePrefix(t *testing.T) {
+	where, args := PlayerFilter{Positions: []string{"QB", "RB"}, NamePrefix: "A_b%"}.where()
+	assert.Equal(t, []string{"deleted_at IS NULL", "position IN (?, ?)", `LOWER(name) LIKE ? ESCAPE '\'`}, where)
+	assert.Equal(t, []interface{}{"QB", "RB", `a\_b\%%`}, args)
+}
+
+func TestLessShouldHonorDirection(t *testing.T) {
+	order := []SortField{{Field: "experience", Descending: true}, {Field: "name"}, {Field: "id"}}
+	veteran := Player{ID: 1, Name: "Zed", Experience: 8}
+	rookie := Player{ID: 2, Name: "Abe", Experience: 0}
+	assert.True(t, less(veteran, rookie, order))
+	assert.False(t, less(rookie, veteran, order))
+	assert.True(t, less(Player{ID: 3, Name: "Abe"}, Player{ID: 4, Name: "Bo"}, order))
+}
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The operations recorded in a player's history
+const (
+	OperationCreate  = "create"
+	OperationUpdate  = "update"
+	OperationDelete  = "delete"
+	OperationRestore = "restore"
+	OperationPurge   = "purge"
+)
+
+// Anonymous is the actor recorded for changes by unidentified callers
+const Anonymous = "anonymous"
+
+type actorKey struct{}
+
+// WithActor returns a context that attributes changes to an actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns who changes made with a context are attributed to
+func ActorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return Anonymous
+}
+
+// FieldChange is a change to one field of a player. Before and After
+// hold the field's JSON values, and are null when the player didn't
+// exist (or was deleted) before or doesn't after.
+type FieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// Changes lists the fields a change touched, stored as JSON
+type Changes []FieldChange
+
+// Scan implements the sql.Scanner interface
+func (c *Changes) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	}
+	return fmt.Errorf("cannot scan %T into Changes", src)
+}
+
+// Value implements the driver.Valuer interface
+func (c Changes) Value() (driver.Value, error) {
+	if c == nil {
+		c = Changes{}
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// HistoryEntry records one change to a player. Entries are only ever
+// appended, so a player's entries replay its whole life.
+type HistoryEntry struct {
+	ID        int       `db:"id" json:"id"`
+	PlayerID  int       `db:"player_id" json:"player_id"`
+	Version   int       `db:"version" json:"version"`
+	Operation string    `db:"operation" json:"operation"`
+	Actor     string    `db:"actor" json:"actor"`
+	ChangedAt time.Time `db:"changed_at" json:"changed_at"`
+	Changes   Changes   `db:"changes" json:"changes"`
+}
+
+// historyFields are the player fields tracked in history, in column order
+var historyFields = []string{"name", "number", "position", "height", "weight", "birth_date", "experience", "college"}
+
+// newHistoryEntry records the change from one state of a player to
+// another; before is nil for a create, and after is nil for a purge.
+// Deleted players have no field values, so deletes and restores list
+// every field.
+func newHistoryEntry(operation, actor string, before, after *Player) (*HistoryEntry, error) {
+	changes, err := diffPlayers(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &HistoryEntry{
+		Operation: operation,
+		Actor:     actor,
+		Changes:   changes,
+	}
+	if after != nil {
+		entry.PlayerID = after.ID
+		entry.Version = after.Version
+		entry.ChangedAt = after.UpdatedAt
+	} else {
+		entry.PlayerID = before.ID
+		entry.Version = before.Version
+		entry.ChangedAt = timestamp()
+	}
+	return entry, nil
+}
+
+// diffPlayers lists the fields that differ between two states of a player
+func diffPlayers(before, after *Player) (Changes, error) {
+	b, err := playerValues(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := playerValues(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := Changes{}
+	for _, field := range historyFields {
+		if !bytes.Equal(b[field], a[field]) {
+			changes = append(changes, FieldChange{
+				Field:  field,
+				Before: orNull(b[field]),
+				After:  orNull(a[field]),
+			})
+		}
+	}
+	return changes, nil
+}
+
+func orNull(v json.RawMessage) json.RawMessage {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	return v
+}
+
+// playerValues returns the JSON value of each of a player's fields, or
+// none if the player is missing or deleted
+func playerValues(p *Player) (map[string]json.RawMessage, error) {
+	if p == nil || p.DeletedAt != nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	err = json.Unmarshal(data, &values)
+	return values, err
+}
+
+func recordHistory(db sqlx.Ext, entry *HistoryEntry) error {
+	_, err := db.Exec(db.Rebind(`INSERT INTO player_history
+		(player_id, version, operation, actor, changed_at, changes)
+		VALUES (?, ?, ?, ?, ?, ?)`),
+		entry.PlayerID, entry.Version, entry.Operation, entry.Actor, entry.ChangedAt, entry.Changes)
+	return err
+}
+
+// GetPlayerHistory returns every change to a player, oldest first
+func GetPlayerHistory(db sqlx.Ext, id int) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	err := sqlx.Select(db, &entries, db.Rebind(`SELECT * FROM player_history
+		WHERE player_id = ?
+		ORDER BY id ASC`),
+		id)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return entries, nil
+}
+
+// lockPlayer gets a player, locking its row until the transaction ends
+// where the database supports it
+func lockPlayer(tx *sqlx.Tx, id int) (*Player, error) {
+	query := "SELECT * FROM players WHERE id = ?"
+	if supportsRowLocks(tx) {
+		query += " FOR UPDATE"
+	}
+	player := Player{}
+	if err := tx.Get(&player, tx.Rebind(query), id); err != nil {
+		return nil, err
+	}
+	return &player, nil
+}
+
+func supportsRowLocks(db sqlx.Ext) bool {
+	return db.DriverName() == "postgres"
+}
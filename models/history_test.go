@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffPlayersShouldListChangedFields(t *testing.T) {
+	before := &Player{ID: 1, Name: "Jalen Ramsey", Number: 20, Position: "CB", Version: 1}
+	after := *before
+	after.Number = 5
+	after.College = "Florida State"
+	after.Version = 2
+
+	changes, err := diffPlayers(before, &after)
+	assert.Nil(t, err)
+	assert.Equal(t, Changes{
+		{Field: "number", Before: json.RawMessage(`"20"`), After: json.RawMessage(`"5"`)},
+		{Field: "college", Before: json.RawMessage(`""`), After: json.RawMessage(`"Florida State"`)},
+	}, changes)
+
+	changes, err = diffPlayers(nil, before)
+	assert.Nil(t, err)
+	assert.Equal(t, len(historyFields), len(changes))
+	assert.Equal(t, json.RawMessage("null"), changes[0].Before)
+	assert.Equal(t, json.RawMessage(`"Jalen Ramsey"`), changes[0].After)
+}
+
+func TestChangesShouldRoundTripThroughDatabase(t *testing.T) {
+	changes := Changes{{Field: "name", After: json.RawMessage(`"Jalen Ramsey"`)}}
+	value, err := changes.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"field":"name","before":null,"after":"Jalen Ramsey"}]`, value)
+
+	var scanned Changes
+	assert.Nil(t, scanned.Scan([]byte(value.(string))))
+	assert.Equal(t, "name", scanned[0].Field)
+	assert.Equal(t, json.RawMessage(`"Jalen Ramsey"`), scanned[0].After)
+
+	value, err = Changes(nil).Value()
+	assert.Nil(t, err)
+	assert.Equal(t, "[]", value)
+}
+
+func TestActorFromShouldDefaultToAnonymous(t *testing.T) {
+	assert.Equal(t, Anonymous, ActorFrom(context.Background()))
+	assert.Equal(t, "jaguars", ActorFrom(WithActor(context.Background(), "jaguars")))
+}
+
+func TestRepositoriesShouldRecordHistory(t *testing.T) {
+	db, err := createSQLiteDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	for name, repo := range map[string]PlayerRepository{
+		"memory": NewMemoryRepository(),
+		"sqlite": NewSQLiteRepository(db),
+	} {
+		ctx := WithActor(context.Background(), "jaguars")
+		_, _, err := repo.SavePlayer(ctx, &Player{Name: "Jalen Ramsey", Number: 20, Position: "CB", BirthDate: NewDate(1994, time.October, 24)})
+		assert.Nil(t, err, name)
+		_, _, err = repo.SavePlayer(ctx, &Player{ID: 1, Name: "Jalen Ramsey", Number: 5, Position: "CB", BirthDate: NewDate(1994, time.October, 24)})
+		assert.Nil(t, err, name)
+		college := "Florida State"
+		_, err = repo.PatchPlayer(WithActor(context.Background(), "rams"), 1, &PlayerPatch{College: &college})
+		assert.Nil(t, err, name)
+		assert.Nil(t, repo.DeletePlayer(ctx, 1, 0), name)
+
+		entries, err := repo.GetPlayerHistory(ctx, 1)
+		assert.Nil(t, err, name)
+		assert.Equal(t, 4, len(entries), name)
+
+		assert.Equal(t, OperationCreate, entries[0].Operation, name)
+		assert.Equal(t, 1, entries[0].Version, name)
+		assert.Equal(t, "jaguars", entries[0].Actor, name)
+
+		assert.Equal(t, OperationUpdate, entries[1].Operation, name)
+		assert.Equal(t, 2, entries[1].Version, name)
+		assert.Equal(t, 1, len(entries[1].Changes), name)
+		assert.Equal(t, "number", entries[1].Changes[0].Field, name)
+
+		assert.Equal(t, "rams", entries[2].Actor, name)
+		assert.Equal(t, "college", entries[2].Changes[0].Field, name)
+
+		assert.Equal(t, OperationDelete, entries[3].Operation, name)
+		assert.Equal(t, 4, entries[3].Version, name)
+		assert.Equal(t, json.RawMessage(`"Florida State"`), entries[3].Changes[len(entries[3].Changes)-1].Before, name)
+		assert.Equal(t, json.RawMessage("null"), entries[3].Changes[0].After, name)
+
+		_, err = repo.GetPlayerHistory(ctx, 2)
+		assert.Equal(t, sql.ErrNoRows, err, name)
+	}
+}
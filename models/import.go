@@ -0,0 +1,307 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The statuses of an imported row
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	ImportRejected  = "rejected"
+)
+
+// ErrImportHeader is returned when an import's header row is missing or
+// names unknown columns
+var ErrImportHeader = errors.New("invalid import header")
+
+// importColumns are the columns an import may have. Files written by
+// jags have name, number, position, height, weight, age, experience, and
+// college; birth_date may be given instead of age. Exports also have id,
+// which must be a number if given but isn't saved, since rows are matched
+// by name.
+var importColumns = map[string]bool{
+	"id":         true,
+	"name":       true,
+	"number":     true,
+	"position":   true,
+	"height":     true,
+	"weight":     true,
+	"age":        true,
+	"birth_date": true,
+	"experience": true,
+	"college":    true,
+}
+
+// ImportRow is a player read from an import. Rows with errors are
+// rejected rather than saved. Fields names the player fields the import's
+// columns set, so an update leaves the others alone; nil sets them all.
+// An age column sets the "age" field, which only fills in an unknown
+// birth date, since the date is estimated from it.
+type ImportRow struct {
+	Row    int
+	Player Player
+	Fields []string
+	Errors []string
+}
+
+// ImportResult reports what an import did with a row. Players are
+// matched to existing ones by name, ignoring case.
+type ImportResult struct {
+	Row    int      `json:"row"`
+	Status string   `json:"status"`
+	ID     int      `json:"id,omitempty"`
+	Name   string   `json:"name"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ImportSummary counts the rows of an import by status
+type ImportSummary struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Rejected  int `json:"rejected"`
+}
+
+// SummarizeImport counts the results of an import by status
+func SummarizeImport(results []ImportResult) ImportSummary {
+	var s ImportSummary
+	for _, r := range results {
+		switch r.Status {
+		case ImportCreated:
+			s.Created++
+		case ImportUpdated:
+			s.Updated++
+		case ImportUnchanged:
+			s.Unchanged++
+		case ImportRejected:
+			s.Rejected++
+		}
+	}
+	return s
+}
+
+// ParseImport reads players from CSV with a header row. Rows that can't
+// be parsed, and rows repeating an earlier row's name, are returned with
+// errors. Ages are converted to birth dates that many years before now.
+func ParseImport(r io.Reader, now time.Time) ([]ImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, ErrImportHeader
+	}
+	hasName := false
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+		if !importColumns[header[i]] {
+			return nil, fmt.Errorf("%v: unknown column %q", ErrImportHeader, column)
+		}
+		hasName = hasName || header[i] == "name"
+	}
+	if !hasName {
+		return nil, fmt.Errorf("%v: name column is required", ErrImportHeader)
+	}
+	fields := importFields(header)
+
+	var rows []ImportRow
+	seen := make(map[string]int)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		row := ImportRow{Row: len(rows) + 1, Fields: fields}
+		if pe, ok := err.(*csv.ParseError); ok {
+			row.Errors = append(row.Errors, pe.Err.Error())
+		} else if err != nil {
+			return nil, err
+		} else {
+			row.Player, row.Errors = parseImportRecord(header, record, now)
+		}
+
+		key := strings.ToLower(row.Player.Name)
+		if first, ok := seen[key]; ok && key != "" {
+			row.Errors = append(row.Errors, fmt.Sprintf("name repeats row %d", first))
+		} else if key != "" {
+			seen[key] = row.Row
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// importFields returns the player fields an import's columns set. A
+// birth_date column wins over an age column.
+func importFields(header []string) []string {
+	columns := map[string]bool{}
+	for _, column := range header {
+		columns[column] = true
+	}
+
+	fields := []string{}
+	for _, field := range historyFields {
+		if columns[field] {
+			fields = append(fields, field)
+		} else if field == "birth_date" && columns["age"] {
+			fields = append(fields, "age")
+		}
+	}
+	return fields
+}
+
+func parseImportRecord(header, record []string, now time.Time) (Player, []string) {
+	var p Player
+	var errs []string
+	for i, column := range header {
+		value := strings.TrimSpace(record[i])
+		if value == "N/A" {
+			value = ""
+		}
+
+		var err error
+		switch column {
+		case "id":
+			if value != "" {
+				if p.ID, err = strconv.Atoi(value); err != nil {
+					err = fmt.Errorf("invalid id %q", value)
+				}
+			}
+		case "name":
+			p.Name = value
+		case "number":
+			p.Number, err = ParseNumber(value)
+		case "position":
+			p.Position = value
+		case "height":
+			p.Height, err = ParseHeight(value)
+		case "weight":
+			p.Weight, err = ParseWeight(value)
+		case "age":
+			if value == "" {
+				continue
+			}
+			var age int
+			if age, err = strconv.Atoi(value); err != nil || age < 0 {
+				err = fmt.Errorf("invalid age %q", value)
+				break
+			}
+			born := now.AddDate(-age, 0, 0)
+			p.BirthDate = NewDate(born.Year(), born.Month(), born.Day())
+		case "birth_date":
+			p.BirthDate, err = ParseDate(value)
+		case "experience":
+			// jags marks rookies with R
+			if value == "" || value == "R" {
+				continue
+			}
+			if p.Experience, err = strconv.Atoi(value); err != nil {
+				err = fmt.Errorf("invalid experience %q", value)
+			}
+		case "college":
+			p.College = value
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", column, err))
+		}
+	}
+	return p, errs
+}
+
+// planImport decides what importing a row does, given the players that
+// already have its name. It returns the row's result and, when the row
+// changes something, the player before (nil if new) and after.
+func planImport(row ImportRow, matches []Player) (ImportResult, *Player, *Player) {
+	result := ImportResult{
+		Row:    row.Row,
+		Name:   row.Player.Name,
+		Errors: row.Errors,
+	}
+	if len(row.Errors) > 0 {
+		result.Status = ImportRejected
+		return result, nil, nil
+	}
+
+	after := row.Player
+	after.ID = 0
+	after.Version = 0
+	switch len(matches) {
+	case 0:
+		if row.Fields != nil && !hasField(row.Fields, "position") {
+			result.Status = ImportRejected
+			result.Errors = []string{"position: is required for a new player"}
+			return result, nil, nil
+		}
+		result.Status = ImportCreated
+		return result, nil, &after
+	case 1:
+	default:
+		result.Status = ImportRejected
+		result.Errors = []string{fmt.Sprintf("name matches %d players", len(matches))}
+		return result, nil, nil
+	}
+
+	before := matches[0]
+	result.ID = before.ID
+	if row.Fields != nil {
+		after = updateImportFields(before, row)
+	}
+	after.ID = before.ID
+	if changes, err := diffPlayers(&before, &after); err == nil && len(changes) == 0 {
+		result.Status = ImportUnchanged
+		return result, nil, nil
+	}
+	result.Status = ImportUpdated
+	return result, &before, &after
+}
+
+// updateImportFields returns a player with the fields a row sets changed
+func updateImportFields(player Player, row ImportRow) Player {
+	player.Version = 0
+	player.UpdatedAt = time.Time{}
+	for _, field := range row.Fields {
+		if field == "age" {
+			if player.BirthDate.IsZero() {
+				player.BirthDate = row.Player.BirthDate
+			}
+			continue
+		}
+		if patch, err := NewPlayerPatch(row.Player, []string{field}); err == nil {
+			patch.Apply(&player)
+		}
+	}
+	return player
+}
+
+func hasField(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// findPlayersByName returns the players that aren't deleted with a name,
+// ignoring case, locking them until the transaction ends where the
+// database supports it. Two are enough to tell the name is ambiguous.
+func findPlayersByName(tx *sqlx.Tx, name string) ([]Player, error) {
+	query := "SELECT * FROM players WHERE LOWER(name) = LOWER(?) AND deleted_at IS NULL ORDER BY id ASC LIMIT 2"
+	if supportsRowLocks(tx) {
+		query += " FOR UPDATE"
+	}
+	var players []Player
+	err := tx.Select(&players, tx.Rebind(query), name)
+	return players, err
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImportShouldReadJagsLayout(t *testing.T) {
+	now := time.Date(2018, time.September, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := ParseImport(strings.NewReader(`Name,Number,Position,Height,Weight,Age,Experience,College
+Blake Bortles,5,QB,6-5,236,26,5,Central Florida
+Leonard Fournette,27,RB,6-0,240,23,R,LSU
+Josh Lambo,4,K,6-0,215,N/A,4,N/A
+`), now)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(rows))
+
+	assert.Equal(t, 1, rows[0].Row)
+	assert.Nil(t, rows[0].Errors)
+	assert.Equal(t, "Blake Bortles", rows[0].Player.Name)
+	assert.Equal(t, Number(5), rows[0].Player.Number)
+	assert.Equal(t, Height(77), rows[0].Player.Height)
+	assert.Equal(t, Weight(236), rows[0].Player.Weight)
+	assert.Equal(t, NewDate(1992, time.September, 1), rows[0].Player.BirthDate)
+	assert.Equal(t, 5, rows[0].Player.Experience)
+	assert.Equal(t, "Central Florida", rows[0].Player.College)
+
+	assert.Equal(t, 0, rows[1].Player.Experience)
+	assert.Equal(t, Date{}, rows[2].Player.BirthDate)
+	assert.Equal(t, "", rows[2].Player.College)
+}
+
+func TestParseImportShouldReturnRowErrors(t *testing.T) {
+	rows, err := ParseImport(strings.NewReader(`name,number,age,experience,birth_date
+Blake Bortles,five,-1,x,1992-04-29
+blake bortles,5,,,
+Jalen Ramsey,20
+`), time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, 3, len(rows[0].Errors))
+	assert.Equal(t, `age: invalid age "-1"`, rows[0].Errors[1])
+	assert.Equal(t, `experience: invalid experience "x"`, rows[0].Errors[2])
+	assert.Equal(t, []string{"name repeats row 1"}, rows[1].Errors)
+	assert.Equal(t, 1, len(rows[2].Errors))
+}
+
+func TestParseImportShouldReturnErrorWhenHeaderIsInvalid(t *testing.T) {
+	for _, input := range []string{"", "number,position\n", "name,nickname\n"} {
+		_, err := ParseImport(strings.NewReader(input), time.Now())
+		assert.NotNil(t, err, input)
+		assert.True(t, strings.HasPrefix(err.Error(), ErrImportHeader.Error()), input)
+	}
+}
+
+func TestPlanImportShouldDecideStatus(t *testing.T) {
+	existing := Player{ID: 3, Version: 2, Name: "Blake Bortles", Number: 5}
+
+	result, before, after := planImport(ImportRow{Row: 1, Player: Player{Name: "Jalen Ramsey"}}, nil)
+	assert.Equal(t, ImportCreated, result.Status)
+	assert.Nil(t, before)
+	assert.Equal(t, "Jalen Ramsey", after.Name)
+
+	result, before, after = planImport(ImportRow{Row: 2, Player: Player{Name: "Blake Bortles", Number: 5}}, []Player{existing})
+	assert.Equal(t, ImportUnchanged, result.Status)
+	assert.Equal(t, 3, result.ID)
+	assert.Nil(t, before)
+	assert.Nil(t, after)
+
+	result, before, after = planImport(ImportRow{Row: 3, Player: Player{Name: "Blake Bortles", Number: 6}}, []Player{existing})
+	assert.Equal(t, ImportUpdated, result.Status)
+	assert.Equal(t, Number(5), before.Number)
+	assert.Equal(t, 3, after.ID)
+
+	result, _, after = planImport(ImportRow{Row: 4, Player: Player{Name: "Blake Bortles"}}, []Player{existing, existing})
+	assert.Equal(t, ImportRejected, result.Status)
+	assert.Equal(t, []string{"name matches 2 players"}, result.Errors)
+	assert.Nil(t, after)
+
+	result, _, after = planImport(ImportRow{Row: 5, Errors: []string{"bad"}}, nil)
+	assert.Equal(t, ImportRejected, result.Status)
+	assert.Nil(t, after)
+}
+
+func TestRepositoriesShouldImportPlayers(t *testing.T) {
+	db, err := createSQLiteDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	for name, repo := range map[string]PlayerRepository{
+		"memory": NewMemoryRepository(),
+		"sqlite": NewSQLiteRepository(db),
+	} {
+		ctx := WithActor(context.Background(), "jaguars")
+		for _, p := range []*Player{{Name: "Blake Bortles", Number: 5}, {Name: "Marqise Lee", Number: 11}} {
+			_, _, err := repo.SavePlayer(ctx, p)
+			assert.Nil(t, err, name)
+		}
+
+		results, err := repo.ImportPlayers(ctx, []ImportRow{
+			{Row: 1, Player: Player{Name: "Blake Bortles", Number: 5}},
+			{Row: 2, Player: Player{Name: "marqise lee", Number: 12}},
+			{Row: 3, Player: Player{Name: "Jalen Ramsey", Number: 20}},
+			{Row: 4, Errors: []string{"number: invalid number \"x\""}},
+		})
+		assert.Nil(t, err, name)
+		assert.Equal(t, ImportSummary{Created: 1, Updated: 1, Unchanged: 1, Rejected: 1}, SummarizeImport(results), name)
+		assert.Equal(t, 3, results[2].ID, name)
+
+		updated, err := repo.GetPlayer(ctx, 2, false)
+		assert.Nil(t, err, name)
+		assert.Equal(t, Number(12), updated.Number, name)
+		assert.Equal(t, "marqise lee", updated.Name, name)
+		assert.Equal(t, 2, updated.Version, name)
+
+		entries, err := repo.GetPlayerHistory(ctx, 2)
+		assert.Nil(t, err, name)
+		assert.Equal(t, OperationUpdate, entries[len(entries)-1].Operation, name)
+		entries, err = repo.GetPlayerHistory(ctx, 3)
+		assert.Nil(t, err, name)
+		assert.Equal(t, OperationCreate, entries[0].Operation, name)
+		assert.Equal(t, "jaguars", entries[0].Actor, name)
+	}
+}
+
+func TestParseImportShouldListFieldsFromHeader(t *testing.T) {
+	now := time.Date(2018, time.September, 1, 0, 0, 0, 0, time.UTC)
+	for header, fields := range map[string][]string{
+		"name,position":            {"name", "position"},
+		"id,college,name,age":      {"name", "age", "college"},
+		"name,age,birth_date":      {"name", "birth_date"},
+		"Name,Number,Position,Age": {"name", "number", "position", "age"},
+	} {
+		rows, err := ParseImport(strings.NewReader(header+"\n"+strings.Repeat(",", strings.Count(header, ","))+"\n"), now)
+		assert.Nil(t, err, header)
+		assert.Equal(t, fields, rows[0].Fields, header)
+	}
+}
+
+func TestRepositoriesShouldImportOnlyTheGivenColumns(t *testing.T) {
+	db, err := createSQLiteDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	now := time.Date(2018, time.September, 1, 0, 0, 0, 0, time.UTC)
+	for name, repo := range map[string]PlayerRepository{
+		"memory": NewMemoryRepository(),
+		"sqlite": NewSQLiteRepository(db),
+	} {
+		ctx := context.Background()
+		existing := Player{
+			Name:       "Jalen Ramsey",
+			Number:     20,
+			Position:   "CB",
+			Height:     73,
+			Weight:     208,
+			BirthDate:  NewDate(1994, time.October, 24),
+			Experience: 2,
+			College:    "Florida State",
+		}
+		saved, _, err := repo.SavePlayer(ctx, &existing)
+		assert.Nil(t, err, name)
+		id := saved.ID
+
+		rows, err := ParseImport(strings.NewReader("name,position\nJalen Ramsey,S\nMyles Jack,LB\n"), now)
+		assert.Nil(t, err, name)
+		results, err := repo.ImportPlayers(ctx, rows)
+		assert.Nil(t, err, name)
+		assert.Equal(t, ImportUpdated, results[0].Status, name)
+		assert.Equal(t, ImportCreated, results[1].Status, name)
+
+		rows, err = ParseImport(strings.NewReader("name,college\nA.J. Bouye,UCF\n"), now)
+		assert.Nil(t, err, name)
+		results, err = repo.ImportPlayers(ctx, rows)
+		assert.Nil(t, err, name)
+		assert.Equal(t, ImportRejected, results[0].Status, name)
+		assert.Equal(t, []string{"position: is required for a new player"}, results[0].Errors, name)
+
+		player, err := repo.GetPlayer(ctx, id, false)
+		assert.Nil(t, err, name)
+		assert.Equal(t, "S", player.Position, name)
+		assert.Equal(t, existing.Number, player.Number, name)
+		assert.Equal(t, existing.Height, player.Height, name)
+		assert.Equal(t, existing.Weight, player.Weight, name)
+		assert.Equal(t, existing.BirthDate, player.BirthDate, name)
+		assert.Equal(t, existing.Experience, player.Experience, name)
+		assert.Equal(t, existing.College, player.College, name)
+
+		for i := 0; i < 2; i++ {
+			rows, err = ParseImport(strings.NewReader("name,age\nJalen Ramsey,15\n"), now.AddDate(0, 0, i))
+			assert.Nil(t, err, name)
+			results, err = repo.ImportPlayers(ctx, rows)
+			assert.Nil(t, err, name)
+			assert.Equal(t, ImportUnchanged, results[0].Status, name)
+		}
+		player, err = repo.GetPlayer(ctx, id, false)
+		assert.Nil(t, err, name)
+		assert.Equal(t, NewDate(1994, time.October, 24), player.BirthDate, name)
+
+		rows, err = ParseImport(strings.NewReader("name,age\nMyles Jack,22\n"), now)
+		assert.Nil(t, err, name)
+		results, err = repo.ImportPlayers(ctx, rows)
+		assert.Nil(t, err, name)
+		assert.Equal(t, ImportUpdated, results[0].Status, name)
+		player, err = repo.GetPlayer(ctx, results[0].ID, false)
+		assert.Nil(t, err, name)
+		assert.Equal(t, NewDate(1996, time.September, 1), player.BirthDate, name)
+		assert.Equal(t, "LB", player.Position, name)
+	}
+}
@@ -0,0 +1,307 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryRepository struct {
+	mu      sync.RWMutex
+	players map[int]Player
+	nextID  int
+	history []HistoryEntry
+}
+
+// NewMemoryRepository returns a player repository that keeps players in memory
+func NewMemoryRepository() PlayerRepository {
+	return &memoryRepository{
+		players: make(map[int]Player),
+		nextID:  1,
+	}
+}
+
+func (r *memoryRepository) ListPlayers(_ context.Context, filter PlayerFilter, page PageRequest) ([]Player, string, error) {
+	order, err := filter.order()
+	if err != nil {
+		return nil, "", err
+	}
+
+	c, err := decodeCursor(page.Token, order)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var players []Player
+	for _, p := range r.players {
+		if filter.matches(p) && c.after(p, order) {
+			players = append(players, p)
+		}
+	}
+	if len(players) == 0 {
+		return nil, "", sql.ErrNoRows
+	}
+
+	sort.Slice(players, func(i, j int) bool {
+		return less(players[i], players[j], order)
+	})
+
+	players, next := nextPageToken(players, page.Limit(), order)
+	return players, next, nil
+}
+
+// ExportPlayers copies the matching players before calling fn, so a slow
+// reader doesn't hold up changes
+func (r *memoryRepository) ExportPlayers(_ context.Context, filter PlayerFilter, fn func(*Player) error) error {
+	order, err := filter.order()
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	var players []Player
+	for _, p := range r.players {
+		if filter.matches(p) {
+			players = append(players, p)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(players, func(i, j int) bool {
+		return less(players[i], players[j], order)
+	})
+
+	for i := range players {
+		if err := fn(&players[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) SearchPlayers(_ context.Context, query string, limit int) ([]SearchResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	players := make([]Player, 0, len(r.players))
+	for _, p := range r.players {
+		if p.DeletedAt == nil {
+			players = append(players, p)
+		}
+	}
+	return searchPlayers(players, searchTerms(query), limit), nil
+}
+
+func (r *memoryRepository) GetPlayer(_ context.Context, id int, includeDeleted bool) (*Player, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	player, ok := r.players[id]
+	if !ok || (!includeDeleted && player.DeletedAt != nil) {
+		return nil, sql.ErrNoRows
+	}
+	return &player, nil
+}
+
+func (r *memoryRepository) SavePlayer(ctx context.Context, player *Player) (*Player, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if player.ID <= 0 {
+		saved := *player
+		saved.ID = r.nextID
+		saved.Version = 1
+		saved.UpdatedAt = timestamp()
+		saved.DeletedAt = nil
+		if err := r.record(OperationCreate, ActorFrom(ctx), nil, &saved); err != nil {
+			return player, false, err
+		}
+		r.nextID++
+		r.players[saved.ID] = saved
+		*player = saved
+		return player, true, nil
+	}
+
+	current, ok := r.players[player.ID]
+	if !ok || current.DeletedAt != nil {
+		return player, false, sql.ErrNoRows
+	}
+	if player.Version > 0 && player.Version != current.Version {
+		return player, false, ErrVersionMismatch
+	}
+	saved := *player
+	saved.Version = current.Version + 1
+	saved.UpdatedAt = timestamp()
+	saved.DeletedAt = nil
+	if err := r.record(OperationUpdate, ActorFrom(ctx), &current, &saved); err != nil {
+		return player, false, err
+	}
+	r.players[saved.ID] = saved
+	*player = saved
+	return player, false, nil
+}
+
+func (r *memoryRepository) PatchPlayer(ctx context.Context, id int, patch *PlayerPatch) (*Player, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	player, ok := r.players[id]
+	if !ok || player.DeletedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+	if patch.Version > 0 && patch.Version != player.Version {
+		return nil, ErrVersionMismatch
+	}
+	if len(patch.changes()) > 0 {
+		before := player
+		patch.Apply(&player)
+		player.Version++
+		player.UpdatedAt = timestamp()
+		if err := r.record(OperationUpdate, ActorFrom(ctx), &before, &player); err != nil {
+			return nil, err
+		}
+		r.players[id] = player
+	}
+	return &player, nil
+}
+
+func (r *memoryRepository) DeletePlayer(ctx context.Context, id, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	player, ok := r.players[id]
+	if !ok || player.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+	if version > 0 && version != player.Version {
+		return ErrVersionMismatch
+	}
+	now := timestamp()
+	deleted := player
+	deleted.Version++
+	deleted.UpdatedAt = now
+	deleted.DeletedAt = &now
+	if err := r.record(OperationDelete, ActorFrom(ctx), &player, &deleted); err != nil {
+		return err
+	}
+	r.players[id] = deleted
+	return nil
+}
+
+func (r *memoryRepository) RestorePlayer(ctx context.Context, id, version int) (*Player, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	player, ok := r.players[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if player.DeletedAt == nil {
+		return nil, ErrNotDeleted
+	}
+	if version > 0 && version != player.Version {
+		return nil, ErrVersionMismatch
+	}
+	restored := player
+	restored.Version++
+	restored.UpdatedAt = timestamp()
+	restored.DeletedAt = nil
+	if err := r.record(OperationRestore, ActorFrom(ctx), &player, &restored); err != nil {
+		return nil, err
+	}
+	r.players[id] = restored
+	return &restored, nil
+}
+
+func (r *memoryRepository) PurgePlayers(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []int
+	for id, p := range r.players {
+		if p.DeletedAt != nil && p.DeletedAt.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		player := r.players[id]
+		if err := r.record(OperationPurge, ActorFrom(ctx), &player, nil); err != nil {
+			return 0, err
+		}
+		delete(r.players, id)
+	}
+	return len(ids), nil
+}
+
+func (r *memoryRepository) ImportPlayers(ctx context.Context, rows []ImportRow) ([]ImportResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := make([]ImportResult, len(rows))
+	for i, row := range rows {
+		var matches []Player
+		for _, p := range r.players {
+			if p.DeletedAt == nil && strings.EqualFold(p.Name, row.Player.Name) {
+				matches = append(matches, p)
+			}
+		}
+
+		result, before, after := planImport(row, matches)
+		if after != nil {
+			operation := OperationUpdate
+			if before == nil {
+				operation = OperationCreate
+				after.ID = r.nextID
+				after.Version = 1
+			} else {
+				after.Version = before.Version + 1
+			}
+			after.UpdatedAt = timestamp()
+			if err := r.record(operation, ActorFrom(ctx), before, after); err != nil {
+				return nil, err
+			}
+			if before == nil {
+				r.nextID++
+			}
+			r.players[after.ID] = *after
+			result.ID = after.ID
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+func (r *memoryRepository) GetPlayerHistory(_ context.Context, id int) ([]HistoryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []HistoryEntry
+	for _, e := range r.history {
+		if e.PlayerID == id {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return entries, nil
+}
+
+// record appends a change to the history; callers must hold the lock
+func (r *memoryRepository) record(operation, actor string, before, after *Player) error {
+	entry, err := newHistoryEntry(operation, actor, before, after)
+	if err != nil {
+		return err
+	}
+	entry.ID = len(r.history) + 1
+	r.history = append(r.history, *entry)
+	return nil
+}
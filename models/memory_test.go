@@ -0,0 +1,223 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRepositoryListPlayersShouldReturnNoRowsWhenEmpty(t *testing.T) {
+	players, _, err := NewMemoryRepository().ListPlayers(context.Background(), PlayerFilter{}, PageRequest{})
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.Equal(t, 0, len(players))
+}
+
+func TestMemoryRepositoryListPlayersShouldOrderByNumber(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	for _, p := range []Player{
+		{Name: "Jalen Ramsey", Number: 20, Position: "CB"},
+		{Name: "Blake Bortles", Number: 5, Position: "QB"},
+		{Name: "Leonard Fournette", Number: 27, Position: "RB"},
+	} {
+		p := p
+		_, _, err := repo.SavePlayer(ctx, &p)
+		assert.Nil(t, err)
+	}
+
+	players, _, err := repo.ListPlayers(ctx, PlayerFilter{}, PageRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(players))
+	assert.Equal(t, Number(5), players[0].Number)
+	assert.Equal(t, Number(20), players[1].Number)
+	assert.Equal(t, Number(27), players[2].Number)
+}
+
+func TestMemoryRepositoryListPlayersShouldPage(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	for _, p := range []Player{
+		{Name: "Jalen Ramsey", Number: 20},
+		{Name: "Blake Bortles", Number: 5},
+		{Name: "Leonard Fournette", Number: 27},
+	} {
+		p := p
+		_, _, err := repo.SavePlayer(ctx, &p)
+		assert.Nil(t, err)
+	}
+
+	players, next, err := repo.ListPlayers(ctx, PlayerFilter{}, PageRequest{Size: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(players))
+	assert.Equal(t, "Blake Bortles", players[0].Name)
+	assert.NotEqual(t, "", next)
+
+	players, next, err = repo.ListPlayers(ctx, PlayerFilter{}, PageRequest{Size: 2, Token: next})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(players))
+	assert.Equal(t, "Leonard Fournette", players[0].Name)
+	assert.Equal(t, "", next)
+}
+
+func TestMemoryRepositoryListPlayersShouldFilterAndSort(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	for _, p := range []Player{
+		{Name: "Jalen Ramsey", Number: 20, Position: "CB", College: "Florida State", Experience: 2},
+		{Name: "A.J. Bouye", Number: 21, Position: "CB", College: "UCF", Experience: 5},
+		{Name: "Blake Bortles", Number: 5, Position: "QB", College: "UCF", Experience: 5},
+		{Name: "Leonard Fournette", Number: 27, Position: "RB", College: "LSU", Experience: 1},
+	} {
+		p := p
+		_, _, err := repo.SavePlayer(ctx, &p)
+		assert.Nil(t, err)
+	}
+
+	two := 2
+	players, next, err := repo.ListPlayers(ctx, PlayerFilter{
+		MinExperience: &two,
+		Sort:          ParseSort("-experience,name"),
+	}, PageRequest{Size: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(players))
+	assert.Equal(t, "A.J. Bouye", players[0].Name)
+	assert.Equal(t, "Blake Bortles", players[1].Name)
+
+	players, _, err = repo.ListPlayers(ctx, PlayerFilter{
+		MinExperience: &two,
+		Sort:          ParseSort("-experience,name"),
+	}, PageRequest{Size: 2, Token: next})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(players))
+	assert.Equal(t, "Jalen Ramsey", players[0].Name)
+
+	players, _, err = repo.ListPlayers(ctx, PlayerFilter{College: "UCF", Positions: []string{"CB", "RB"}}, PageRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(players))
+	assert.Equal(t, "A.J. Bouye", players[0].Name)
+
+	_, _, err = repo.ListPlayers(ctx, PlayerFilter{Sort: ParseSort("salary")}, PageRequest{})
+	assert.Equal(t, ErrInvalidSort, err)
+}
+
+func TestMemoryRepositoryListPlayersShouldReturnErrorWhenTokenInvalid(t *testing.T) {
+	_, _, err := NewMemoryRepository().ListPlayers(context.Background(), PlayerFilter{}, PageRequest{Token: "!"})
+	assert.Equal(t, ErrInvalidPageToken, err)
+}
+
+func TestMemoryRepositoryListPlayersShouldFilterByPosition(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	_, _, err := repo.SavePlayer(ctx, &Player{Name: "Blake Bortles", Position: "QB"})
+	assert.Nil(t, err)
+	_, _, err = repo.SavePlayer(ctx, &Player{Name: "Jalen Ramsey", Position: "CB"})
+	assert.Nil(t, err)
+
+	players, _, err := repo.ListPlayers(ctx, PlayerFilter{Positions: []string{"QB"}}, PageRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(players))
+	assert.Equal(t, "Blake Bortles", players[0].Name)
+
+	_, _, err = repo.ListPlayers(ctx, PlayerFilter{Positions: []string{"K"}}, PageRequest{})
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func TestMemoryRepositorySavePlayerShouldCreateWhenNoIDAndUpdateWhenHasID(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	p := &Player{Name: "Blake Bortles"}
+	_, created, err := repo.SavePlayer(ctx, p)
+	assert.Nil(t, err)
+	assert.True(t, created)
+	assert.Equal(t, 1, p.ID)
+
+	p.Name = "Cody Kessler"
+	_, created, err = repo.SavePlayer(ctx, p)
+	assert.Nil(t, err)
+	assert.False(t, created)
+
+	player, err := repo.GetPlayer(ctx, 1, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Cody Kessler", player.Name)
+}
+
+func TestMemoryRepositoryShouldReturnNoRowsWhenPlayerNotFound(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	player, err := repo.GetPlayer(ctx, 1, false)
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.Nil(t, player)
+
+	_, created, err := repo.SavePlayer(ctx, &Player{ID: 1})
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.False(t, created)
+
+	assert.Equal(t, sql.ErrNoRows, repo.DeletePlayer(ctx, 1, 0))
+}
+
+func TestMemoryRepositoryDeletePlayerShouldRemovePlayer(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	_, _, err := repo.SavePlayer(ctx, &Player{Name: "Blake Bortles"})
+	assert.Nil(t, err)
+
+	assert.Nil(t, repo.DeletePlayer(ctx, 1, 0))
+	_, err = repo.GetPlayer(ctx, 1, false)
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func TestMemoryRepositoryShouldCheckVersions(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	player, _, err := repo.SavePlayer(ctx, &Player{Name: "Blake Bortles"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, player.Version)
+
+	created := player.UpdatedAt
+	assert.False(t, created.IsZero())
+
+	player, _, err = repo.SavePlayer(ctx, &Player{ID: 1, Name: "Cody Kessler", Version: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, player.Version)
+	assert.False(t, player.UpdatedAt.Before(created))
+
+	_, _, err = repo.SavePlayer(ctx, &Player{ID: 1, Name: "Blake Bortles", Version: 1})
+	assert.Equal(t, ErrVersionMismatch, err)
+
+	name := "Blake Bortles"
+	_, err = repo.PatchPlayer(ctx, 1, &PlayerPatch{Name: &name, Version: 1})
+	assert.Equal(t, ErrVersionMismatch, err)
+
+	player, err = repo.PatchPlayer(ctx, 1, &PlayerPatch{Name: &name, Version: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, player.Version)
+
+	assert.Equal(t, ErrVersionMismatch, repo.DeletePlayer(ctx, 1, 2))
+	assert.Nil(t, repo.DeletePlayer(ctx, 1, 3))
+}
+
+func TestMemoryRepositoryShouldBeSafeForConcurrentUse(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := repo.SavePlayer(ctx, &Player{Name: "Player"})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	players, _, err := repo.ListPlayers(ctx, PlayerFilter{}, PageRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 50, len(players))
+}
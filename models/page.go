@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// DefaultPageSize is the number of players in a page when no size is requested
+const DefaultPageSize = 100
+
+// MaxPageSize is the largest number of players returned in a page
+const MaxPageSize = 1000
+
+// ErrInvalidPageToken is returned when a page token cannot be decoded
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// PageRequest asks for a page of a list. An empty token asks for the first page.
+type PageRequest struct {
+	Size  int
+	Token string
+}
+
+// Limit returns the page size, applying the default and maximum
+func (p PageRequest) Limit() int {
+	if p.Size <= 0 {
+		return DefaultPageSize
+	}
+	if p.Size > MaxPageSize {
+		return MaxPageSize
+	}
+	return p.Size
+}
+
+// cursor marks the last player on a page, keeping only the fields the
+// list is sorted by, along with the sort so a token can't be reused
+// against a differently ordered list
+type cursor struct {
+	Sort string `json:"s"`
+	Last Player `json:"p"`
+}
+
+func newCursor(p Player, order []SortField) cursor {
+	var last Player
+	for _, sf := range order {
+		setSortKey(&last, p, sf.Field)
+	}
+	return cursor{Sort: FormatSort(order), Last: last}
+}
+
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(token string, order []SortField) (*cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	if c.Sort != FormatSort(order) {
+		return nil, ErrInvalidPageToken
+	}
+	return &c, nil
+}
+
+// after reports whether the player comes after the cursor in list order
+func (c *cursor) after(p Player, order []SortField) bool {
+	if c == nil {
+		return true
+	}
+	return less(c.Last, p, order)
+}
+
+// where returns the keyset condition and arguments selecting the players
+// after the cursor, e.g., (number > ? OR (number = ? AND id > ?))
+func (c *cursor) where(order []SortField) (string, []interface{}) {
+	var terms []string
+	var args []interface{}
+	for i, sf := range order {
+		var conds []string
+		for _, prev := range order[:i] {
+			conds = append(conds, sortColumns[prev.Field]+" = ?")
+			args = append(args, sortKey(c.Last, prev.Field))
+		}
+		op := " > ?"
+		if sf.Descending {
+			op = " < ?"
+		}
+		conds = append(conds, sortColumns[sf.Field]+op)
+		args = append(args, sortKey(c.Last, sf.Field))
+
+		term := strings.Join(conds, " AND ")
+		if len(conds) > 1 {
+			term = "(" + term + ")"
+		}
+		terms = append(terms, term)
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args
+}
+
+// nextPageToken trims a list fetched with one extra player to the page
+// size, returning the token for the following page if there is one
+func nextPageToken(players []Player, limit int, order []SortField) ([]Player, string) {
+	if len(players) <= limit {
+		return players, ""
+	}
+
+	players = players[:limit]
+	return players, newCursor(players[limit-1], order).encode()
+}
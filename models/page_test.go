@@ -0,0 +1,66 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageRequestLimitShouldApplyDefaultAndMaximum(t *testing.T) {
+	assert.Equal(t, DefaultPageSize, PageRequest{}.Limit())
+	assert.Equal(t, DefaultPageSize, PageRequest{Size: -1}.Limit())
+	assert.Equal(t, 10, PageRequest{Size: 10}.Limit())
+	assert.Equal(t, MaxPageSize, PageRequest{Size: MaxPageSize + 1}.Limit())
+}
+
+var numberOrder = []SortField{{Field: "number"}, {Field: "id"}}
+
+func TestDecodeCursorShouldRoundTrip(t *testing.T) {
+	p := Player{ID: 7, Name: "Jalen Ramsey", Number: 20}
+	c, err := decodeCursor(newCursor(p, numberOrder).encode(), numberOrder)
+	assert.Nil(t, err)
+	assert.Equal(t, "number,id", c.Sort)
+	assert.Equal(t, Player{ID: 7, Number: 20}, c.Last)
+}
+
+func TestDecodeCursorShouldReturnNilWhenEmpty(t *testing.T) {
+	c, err := decodeCursor("", numberOrder)
+	assert.Nil(t, err)
+	assert.Nil(t, c)
+}
+
+func TestDecodeCursorShouldReturnErrorWhenInvalid(t *testing.T) {
+	_, err := decodeCursor("not a token!", numberOrder)
+	assert.Equal(t, ErrInvalidPageToken, err)
+
+	_, err = decodeCursor("bm90IGpzb24", numberOrder)
+	assert.Equal(t, ErrInvalidPageToken, err)
+}
+
+func TestDecodeCursorShouldReturnErrorWhenSortChanged(t *testing.T) {
+	token := newCursor(Player{ID: 7, Number: 20}, numberOrder).encode()
+	_, err := decodeCursor(token, []SortField{{Field: "name"}, {Field: "id"}})
+	assert.Equal(t, ErrInvalidPageToken, err)
+}
+
+func TestCursorWhereShouldSelectPlayersAfterCursor(t *testing.T) {
+	c := newCursor(Player{ID: 7, Name: "Jalen Ramsey", Experience: 3}, []SortField{{Field: "experience", Descending: true}, {Field: "name"}, {Field: "id"}})
+	where, args := c.where([]SortField{{Field: "experience", Descending: true}, {Field: "name"}, {Field: "id"}})
+	assert.Equal(t, "(experience < ? OR (experience = ? AND name > ?) OR (experience = ? AND name = ? AND id > ?))", where)
+	assert.Equal(t, []interface{}{3, 3, "Jalen Ramsey", 3, "Jalen Ramsey", 7}, args)
+}
+
+func TestNextPageTokenShouldBeEmptyWhenLastPage(t *testing.T) {
+	players, next := nextPageToken([]Player{{ID: 1}, {ID: 2}}, 2, numberOrder)
+	assert.Equal(t, 2, len(players))
+	assert.Equal(t, "", next)
+}
+
+func TestNextPageTokenShouldTrimAndPointAtLastPlayer(t *testing.T) {
+	players, next := nextPageToken([]Player{{ID: 1, Number: 5}, {ID: 2, Number: 20}, {ID: 3, Number: 27}}, 2, numberOrder)
+	assert.Equal(t, 2, len(players))
+
+	c, err := decodeCursor(next, numberOrder)
+	assert.Nil(t, err)
+	assert.Equal(t, Player{ID: 2, Number: 20}, c.Last)
+}
@@ -0,0 +1,268 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PlayerPatch is a partial update to a player. Only the fields that are
+// set are changed; a field set to its zero value is cleared.
+type PlayerPatch struct {
+	Name       *string
+	Number     *Number
+	Position   *string
+	Height     *Height
+	Weight     *Weight
+	BirthDate  *Date
+	Experience *int
+	College    *string
+	// Version, when nonzero, must match the stored version
+	Version int
+}
+
+// ParseMergePatch parses an RFC 7396 JSON merge patch, where each member
+// replaces a field and null clears it
+func ParseMergePatch(data []byte) (*PlayerPatch, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil || members == nil {
+		return nil, fmt.Errorf("invalid patch: must be a JSON object")
+	}
+
+	patch := &PlayerPatch{}
+	for field, value := range members {
+		if err := patch.set(field, value); err != nil {
+			return nil, err
+		}
+	}
+	return patch, nil
+}
+
+// ParseJSONPatch parses an RFC 6902 JSON patch. Players are flat, so
+// only the add, replace, and remove operations on top-level fields are
+// supported.
+func ParseJSONPatch(data []byte) (*PlayerPatch, error) {
+	var ops []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("invalid patch: must be a JSON array of operations")
+	}
+
+	patch := &PlayerPatch{}
+	for _, op := range ops {
+		field := strings.TrimPrefix(op.Path, "/")
+		if field == op.Path || strings.Contains(field, "/") {
+			return nil, fmt.Errorf("invalid patch: unsupported path %q", op.Path)
+		}
+
+		var err error
+		switch op.Op {
+		case "add", "replace":
+			if op.Value == nil {
+				return nil, fmt.Errorf("invalid patch: %s %q needs a value", op.Op, op.Path)
+			}
+			err = patch.set(field, op.Value)
+		case "remove":
+			err = patch.set(field, json.RawMessage("null"))
+		default:
+			err = fmt.Errorf("invalid patch: unsupported op %q", op.Op)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return patch, nil
+}
+
+// NewPlayerPatch returns a patch that changes the named fields to their
+// values in a player
+func NewPlayerPatch(player Player, fields []string) (*PlayerPatch, error) {
+	patch := &PlayerPatch{}
+	for _, field := range fields {
+		switch field {
+		case "name":
+			patch.Name = &player.Name
+		case "number":
+			patch.Number = &player.Number
+		case "position":
+			patch.Position = &player.Position
+		case "height":
+			patch.Height = &player.Height
+		case "weight":
+			patch.Weight = &player.Weight
+		case "birth_date":
+			patch.BirthDate = &player.BirthDate
+		case "experience":
+			patch.Experience = &player.Experience
+		case "college":
+			patch.College = &player.College
+		case "id", "age", "version", "updated_at", "deleted_at":
+			return nil, fmt.Errorf("invalid patch: %s is read-only", field)
+		default:
+			return nil, fmt.Errorf("invalid patch: unknown field %q", field)
+		}
+	}
+	return patch, nil
+}
+
+func (p *PlayerPatch) set(field string, value json.RawMessage) error {
+	var target interface{}
+	switch field {
+	case "name":
+		p.Name = new(string)
+		target = p.Name
+	case "number":
+		p.Number = new(Number)
+		target = p.Number
+	case "position":
+		p.Position = new(string)
+		target = p.Position
+	case "height":
+		p.Height = new(Height)
+		target = p.Height
+	case "weight":
+		p.Weight = new(Weight)
+		target = p.Weight
+	case "birth_date":
+		p.BirthDate = new(Date)
+		target = p.BirthDate
+	case "experience":
+		p.Experience = new(int)
+		target = p.Experience
+	case "college":
+		p.College = new(string)
+		target = p.College
+	case "id", "age", "version", "updated_at", "deleted_at":
+		return fmt.Errorf("invalid patch: %s is read-only", field)
+	default:
+		return fmt.Errorf("invalid patch: unknown field %q", field)
+	}
+
+	if string(value) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(value, target); err != nil {
+		return fmt.Errorf("invalid patch: %s: %v", field, err)
+	}
+	return nil
+}
+
+// Fields returns the names of the fields the patch changes
+func (p *PlayerPatch) Fields() []string {
+	var fields []string
+	for _, c := range p.changes() {
+		fields = append(fields, c.field)
+	}
+	return fields
+}
+
+// Apply changes the patched fields of a player
+func (p *PlayerPatch) Apply(player *Player) {
+	if p.Name != nil {
+		player.Name = *p.Name
+	}
+	if p.Number != nil {
+		player.Number = *p.Number
+	}
+	if p.Position != nil {
+		player.Position = *p.Position
+	}
+	if p.Height != nil {
+		player.Height = *p.Height
+	}
+	if p.Weight != nil {
+		player.Weight = *p.Weight
+	}
+	if p.BirthDate != nil {
+		player.BirthDate = *p.BirthDate
+	}
+	if p.Experience != nil {
+		player.Experience = *p.Experience
+	}
+	if p.College != nil {
+		player.College = *p.College
+	}
+}
+
+type change struct {
+	field string
+	value interface{}
+}
+
+// changes lists the patched fields in column order, with their new values
+func (p *PlayerPatch) changes() []change {
+	var changes []change
+	if p.Name != nil {
+		changes = append(changes, change{"name", *p.Name})
+	}
+	if p.Number != nil {
+		changes = append(changes, change{"number", *p.Number})
+	}
+	if p.Position != nil {
+		changes = append(changes, change{"position", *p.Position})
+	}
+	if p.Height != nil {
+		changes = append(changes, change{"height", *p.Height})
+	}
+	if p.Weight != nil {
+		changes = append(changes, change{"weight", *p.Weight})
+	}
+	if p.BirthDate != nil {
+		changes = append(changes, change{"birth_date", *p.BirthDate})
+	}
+	if p.Experience != nil {
+		changes = append(changes, change{"experience", *p.Experience})
+	}
+	if p.College != nil {
+		changes = append(changes, change{"college", *p.College})
+	}
+	return changes
+}
+
+// PatchPlayer changes only the patched columns of a player, so concurrent
+// patches to different fields don't overwrite each other
+func PatchPlayer(db sqlx.Ext, id int, patch *PlayerPatch) (*Player, error) {
+	changes := patch.changes()
+	if len(changes) == 0 {
+		player, err := GetPlayer(db, id, false)
+		if err == nil && patch.Version > 0 && player.Version != patch.Version {
+			return nil, ErrVersionMismatch
+		}
+		return player, err
+	}
+
+	sets := make([]string, len(changes), len(changes)+2)
+	args := make([]interface{}, len(changes), len(changes)+3)
+	for i, c := range changes {
+		sets[i] = c.field + "=?"
+		args[i] = c.value
+	}
+	sets = append(sets, "updated_at=?", "version=version+1")
+	args = append(args, timestamp(), id)
+
+	update := `UPDATE players
+		SET ` + strings.Join(sets, ", ") + `
+		WHERE id=? AND deleted_at IS NULL`
+	if patch.Version > 0 {
+		update += " AND version=?"
+		args = append(args, patch.Version)
+	}
+
+	result, err := db.Exec(db.Rebind(update), args...)
+	if err != nil {
+		return nil, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if count != 1 {
+		return nil, missingOrStale(db, id, patch.Version)
+	}
+	return GetPlayer(db, id, false)
+}
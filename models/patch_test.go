@@ -0,0 +1,136 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestParseMergePatchShouldSetAndClearFields(t *testing.T) {
+	patch, err := ParseMergePatch([]byte(`{"number":"33","height":"6-1","college":null}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"number", "height", "college"}, patch.Fields())
+
+	p := Player{Name: "Jalen Ramsey", Number: 20, College: "Florida State"}
+	patch.Apply(&p)
+	assert.Equal(t, "Jalen Ramsey", p.Name)
+	assert.Equal(t, Number(33), p.Number)
+	assert.Equal(t, Height(73), p.Height)
+	assert.Equal(t, "", p.College)
+}
+
+func TestParseMergePatchShouldRejectInvalidPatches(t *testing.T) {
+	for _, data := range []string{
+		`[]`,
+		`null`,
+		`{"salary":1}`,
+		`{"id":2}`,
+		`{"age":"24"}`,
+		`{"birth_date":"yesterday"}`,
+	} {
+		_, err := ParseMergePatch([]byte(data))
+		assert.NotNil(t, err, data)
+	}
+}
+
+func TestParseJSONPatchShouldApplyOperations(t *testing.T) {
+	patch, err := ParseJSONPatch([]byte(`[
+		{"op":"replace","path":"/birth_date","value":"1994-10-24"},
+		{"op":"add","path":"/experience","value":3},
+		{"op":"remove","path":"/college"}
+	]`))
+	assert.Nil(t, err)
+
+	p := Player{College: "Florida State"}
+	patch.Apply(&p)
+	assert.Equal(t, NewDate(1994, time.October, 24), p.BirthDate)
+	assert.Equal(t, 3, p.Experience)
+	assert.Equal(t, "", p.College)
+}
+
+func TestParseJSONPatchShouldRejectUnsupportedOperations(t *testing.T) {
+	for _, data := range []string{
+		`{}`,
+		`[{"op":"move","from":"/name","path":"/college"}]`,
+		`[{"op":"test","path":"/name","value":"Jalen Ramsey"}]`,
+		`[{"op":"replace","path":"name","value":"Jalen Ramsey"}]`,
+		`[{"op":"replace","path":"/name/first","value":"Jalen"}]`,
+		`[{"op":"replace","path":"/name"}]`,
+	} {
+		_, err := ParseJSONPatch([]byte(data))
+		assert.NotNil(t, err, data)
+	}
+}
+
+func TestNewPlayerPatchShouldCopyNamedFields(t *testing.T) {
+	patch, err := NewPlayerPatch(Player{Name: "Jalen Ramsey", Number: 20, College: "Florida State"}, []string{"number", "college"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"number", "college"}, patch.Fields())
+
+	p := Player{Name: "Cody Kessler"}
+	patch.Apply(&p)
+	assert.Equal(t, "Cody Kessler", p.Name)
+	assert.Equal(t, Number(20), p.Number)
+	assert.Equal(t, "Florida State", p.College)
+
+	_, err = NewPlayerPatch(Player{}, []string{"id"})
+	assert.NotNil(t, err)
+	_, err = NewPlayerPatch(Player{}, []string{"salary"})
+	assert.NotNil(t, err)
+}
+
+func TestPatchPlayerShouldUpdateOnlyPatchedColumns(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`^UPDATE players SET number=\$1, college=\$2, updated_at=\$3, version=version\+1 WHERE id=\$4 AND deleted_at IS NULL$`).
+		WithArgs(33, "", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "number"}).AddRow(1, "Jalen Ramsey", "33"))
+
+	patch, err := ParseMergePatch([]byte(`{"number":33,"college":null}`))
+	assert.Nil(t, err)
+
+	player, err := PatchPlayer(db, 1, patch)
+	assert.Nil(t, err)
+	assert.Equal(t, Number(33), player.Number)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPatchPlayerShouldReturnNoRowsWhenNotFound(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`^UPDATE players SET name=\$1, updated_at=\$2, version=version\+1 WHERE id=\$3 AND deleted_at IS NULL$`).
+		WithArgs("Jalen Ramsey", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	name := "Jalen Ramsey"
+	_, err = PatchPlayer(db, 1, &PlayerPatch{Name: &name})
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMemoryRepositoryPatchPlayerShouldKeepUnpatchedFields(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	_, _, err := repo.SavePlayer(ctx, &Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+
+	number := Number(33)
+	player, err := repo.PatchPlayer(ctx, 1, &PlayerPatch{Number: &number})
+	assert.Nil(t, err)
+	assert.Equal(t, "CB", player.Position)
+	assert.Equal(t, Number(33), player.Number)
+
+	_, err = repo.PatchPlayer(ctx, 2, &PlayerPatch{Number: &number})
+	assert.Equal(t, sql.ErrNoRows, err)
+}
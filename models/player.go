@@ -1,8 +1,14 @@
 package models
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -11,59 +17,169 @@ import (
 type Player struct {
 	ID         int    `db:"id" json:"id"`
 	Name       string `db:"name" json:"name"`
-	Number     string `db:"number" json:"number"`
+	Number     Number `db:"number" json:"number"`
 	Position   string `db:"position" json:"position"`
-	Height     string `db:"height" json:"height"`
-	Weight     string `db:"weight" json:"weight"`
-	Age        string `db:"age" json:"age"`
+	Height     Height `db:"height" json:"height"`
+	Weight     Weight `db:"weight" json:"weight"`
+	BirthDate  Date   `db:"birth_date" json:"birth_date"`
 	Experience int    `db:"experience" json:"experience"`
 	College    string `db:"college" json:"college"`
+	// Version counts the saves to a player. When saving, a nonzero
+	// version must match the stored one, so stale edits are rejected.
+	Version int `db:"version" json:"version"`
+	// UpdatedAt is when the player was last saved
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	// DeletedAt is when the player was deleted, or nil if it wasn't.
+	// Deleted players are hidden, but kept until they're purged.
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+}
+
+// ErrVersionMismatch is returned when a player is saved or deleted with
+// a version that no longer matches the stored one
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// ErrNotDeleted is returned when restoring a player that isn't deleted
+var ErrNotDeleted = errors.New("player isn't deleted")
+
+// Age returns the player's age in whole years, or 0 if the birth date is unknown
+func (p *Player) Age() int {
+	return p.BirthDate.YearsUntil(time.Now())
+}
+
+// MarshalJSON encodes a player, including the computed age for clients
+// written before birth dates were tracked
+func (p Player) MarshalJSON() ([]byte, error) {
+	type player Player
+	age := ""
+	if !p.BirthDate.IsZero() {
+		age = strconv.Itoa(p.Age())
+	}
+	return json.Marshal(struct {
+		player
+		Age string `json:"age"`
+	}{
+		player: player(p),
+		Age:    age,
+	})
 }
 
 // String returns a String version of a player
 func (p *Player) String() string {
-	return fmt.Sprintf("[%d] %s (%s) -- #%s, %s, %slb, %syo, %dexp -- %s",
+	return fmt.Sprintf("[%d] %s (%s) -- #%d, %s, %slb, %dyo, %dexp -- %s",
 		p.ID,
 		p.Name,
 		p.Position,
 		p.Number,
 		p.Height,
 		p.Weight,
-		p.Age,
+		p.Age(),
 		p.Experience,
 		p.College)
 }
 
-// ListPlayers lists all the players, optionally restricted to a position
-func ListPlayers(db *sqlx.DB, position string) ([]Player, error) {
-	var players []Player
-	var err error
-	if position == "" {
-		err = db.Select(&players, "SELECT * FROM players ORDER BY number ASC")
-	} else {
-		err = db.Select(&players, "SELECT * FROM players WHERE position = $1 ORDER BY number ASC", position)
+// ListPlayers lists a page of the players matching a filter, in the
+// filter's order, and returns the token for the next page
+func ListPlayers(db *sqlx.DB, filter PlayerFilter, page PageRequest) ([]Player, string, error) {
+	order, err := filter.order()
+	if err != nil {
+		return nil, "", err
 	}
+
+	c, err := decodeCursor(page.Token, order)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	where, args := filter.where()
+	if c != nil {
+		w, a := c.where(order)
+		where = append(where, w)
+		args = append(args, a...)
+	}
+
+	query := "SELECT * FROM players"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + orderBy(order) + " LIMIT ?"
+	limit := page.Limit()
+	args = append(args, limit+1)
+
+	var players []Player
+	if err := db.Select(&players, db.Rebind(query), args...); err != nil {
+		return nil, "", err
 	}
 	if len(players) == 0 {
-		return nil, sql.ErrNoRows
+		return nil, "", sql.ErrNoRows
 	}
-	return players, nil
+
+	players, next := nextPageToken(players, limit, order)
+	return players, next, nil
 }
 
-// GetPlayer gets a player by ID
-func GetPlayer(db *sqlx.DB, id int) (*Player, error) {
+// ExportPlayers calls fn with each player matching a filter, in order,
+// reading them from the database one at a time rather than all at once
+func ExportPlayers(ctx context.Context, db *sqlx.DB, filter PlayerFilter, fn func(*Player) error) error {
+	order, err := filter.order()
+	if err != nil {
+		return err
+	}
+
+	where, args := filter.where()
+	query := "SELECT * FROM players"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + orderBy(order)
+
+	rows, err := db.QueryxContext(ctx, db.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p Player
+		if err := rows.StructScan(&p); err != nil {
+			return err
+		}
+		if err := fn(&p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func orderBy(order []SortField) string {
+	terms := make([]string, len(order))
+	for i, sf := range order {
+		dir := " ASC"
+		if sf.Descending {
+			dir = " DESC"
+		}
+		terms[i] = sortColumns[sf.Field] + dir
+	}
+	return strings.Join(terms, ", ")
+}
+
+// GetPlayer gets a player by ID, hiding deleted players unless asked to include them
+func GetPlayer(db sqlx.Ext, id int, includeDeleted bool) (*Player, error) {
+	query := "SELECT * FROM players WHERE id = ?"
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 	player := Player{}
-	err := db.Get(&player, "SELECT * FROM players WHERE id = $1", id)
+	err := sqlx.Get(db, &player, db.Rebind(query), id)
 	if err != nil {
 		return nil, err
 	}
 	return &player, nil
 }
 
-// Save saves a player (insert or update)
-func (p *Player) Save(db *sqlx.DB) (*Player, bool, error) {
+// Save saves a player (insert or update). Only players that aren't
+// deleted can be saved.
+func (p *Player) Save(db sqlx.Ext) (*Player, bool, error) {
+	p.DeletedAt = nil
 	created := false
 	var err error
 	if p.ID <= 0 {
@@ -75,57 +191,199 @@ func (p *Player) Save(db *sqlx.DB) (*Player, bool, error) {
 	return p, created, err
 }
 
-// Delete deletes a player
-func (p *Player) Delete(db *sqlx.DB) error {
-	result, err := db.Exec(`DELETE FROM players
-		WHERE id=$1`,
-		p.ID)
+// Delete marks a player deleted, checking its version if it has one.
+// The player is hidden from then on, until it's restored or purged.
+func (p *Player) Delete(db sqlx.Ext) error {
+	now := timestamp()
+	query := `UPDATE players
+		SET deleted_at=?, updated_at=?, version=version+1
+		WHERE id=? AND deleted_at IS NULL`
+	args := []interface{}{now, now, p.ID}
+	if p.Version > 0 {
+		query += " AND version=?"
+		args = append(args, p.Version)
+	}
+
+	ok, err := p.exec(db, query, args)
 	if err != nil {
 		return err
 	}
+	if !ok {
+		return missingOrStale(db, p.ID, p.Version)
+	}
+
+	p.UpdatedAt = now
+	p.DeletedAt = &now
+	return nil
+}
+
+// Restore undeletes a player, checking its version if it has one
+func (p *Player) Restore(db sqlx.Ext) error {
+	now := timestamp()
+	query := `UPDATE players
+		SET deleted_at=NULL, updated_at=?, version=version+1
+		WHERE id=? AND deleted_at IS NOT NULL`
+	args := []interface{}{now, p.ID}
+	if p.Version > 0 {
+		query += " AND version=?"
+		args = append(args, p.Version)
+	}
 
-	count, err := result.RowsAffected()
+	ok, err := p.exec(db, query, args)
 	if err != nil {
 		return err
 	}
+	if !ok {
+		current, err := GetPlayer(db, p.ID, true)
+		if err != nil {
+			return err
+		}
+		if current.DeletedAt == nil {
+			return ErrNotDeleted
+		}
+		return ErrVersionMismatch
+	}
+
+	p.UpdatedAt = now
+	p.DeletedAt = nil
+	return nil
+}
+
+// PurgePlayers permanently removes the players deleted before a time,
+// returning them
+func PurgePlayers(db sqlx.Ext, before time.Time) ([]Player, error) {
+	query := "SELECT * FROM players WHERE deleted_at < ? ORDER BY id ASC"
+	if supportsRowLocks(db) {
+		query += " FOR UPDATE"
+	}
+
+	var players []Player
+	if err := sqlx.Select(db, &players, db.Rebind(query), before); err != nil {
+		return nil, err
+	}
+	if len(players) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]interface{}, len(players))
+	for i, p := range players {
+		ids[i] = p.ID
+	}
+	_, err := db.Exec(db.Rebind("DELETE FROM players WHERE id IN ("+placeholders(len(ids))+")"), ids...)
+	if err != nil {
+		return nil, err
+	}
+	return players, nil
+}
 
-	if count != 1 {
+// missingOrStale explains why a write matched no rows: either the player
+// doesn't exist (or is deleted), or it does with a different version
+func missingOrStale(db sqlx.Ext, id, version int) error {
+	if version <= 0 {
 		return sql.ErrNoRows
 	}
 
-	return nil
+	var current int
+	err := sqlx.Get(db, &current, db.Rebind("SELECT version FROM players WHERE id = ? AND deleted_at IS NULL"), id)
+	if err != nil {
+		return err
+	}
+	return ErrVersionMismatch
 }
 
-func (p *Player) create(db *sqlx.DB) error {
+func (p *Player) create(db sqlx.Ext) error {
+	p.UpdatedAt = timestamp()
+	insert := `INSERT INTO players
+		(name, number, position, height, weight, birth_date, experience, college, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	if !supportsReturning(db) {
+		result, err := db.Exec(db.Rebind(insert),
+			p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, p.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		p.ID = int(id)
+		p.Version = 1
+		return nil
+	}
+
 	var id int
-	err := db.QueryRow(`INSERT INTO players
-		(name, number, position, height, weight, age, experience, college)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id`,
-		p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College).
+	err := db.QueryRowx(db.Rebind(insert+`
+		RETURNING id`),
+		p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, p.UpdatedAt).
 		Scan(&id)
 	if err != nil {
 		return err
 	}
 
 	p.ID = id
+	p.Version = 1
 	return nil
 }
 
-func (p *Player) update(db *sqlx.DB) error {
-	result, err := db.Exec(`UPDATE players
-		SET name=$1, number=$2, position=$3, height=$4, weight=$5, age=$6, experience=$7, college=$8
-		WHERE id=$9`,
-		p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID)
-	if err != nil {
-		return err
+func (p *Player) update(db sqlx.Ext) error {
+	p.UpdatedAt = timestamp()
+	update := `UPDATE players
+		SET name=?, number=?, position=?, height=?, weight=?, birth_date=?, experience=?, college=?, updated_at=?, version=version+1
+		WHERE id=? AND deleted_at IS NULL`
+	args := []interface{}{p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, p.UpdatedAt, p.ID}
+	if p.Version > 0 {
+		update += " AND version=?"
+		args = append(args, p.Version)
 	}
-	count, err := result.RowsAffected()
+
+	ok, err := p.exec(db, update, args)
 	if err != nil {
 		return err
 	}
-	if count != 1 {
-		return sql.ErrNoRows
+	if !ok {
+		return missingOrStale(db, p.ID, p.Version)
 	}
 	return nil
 }
+
+// exec runs an update that increments the player's version, storing the
+// new version, and reports whether it matched the player
+func (p *Player) exec(db sqlx.Ext, update string, args []interface{}) (bool, error) {
+	if !supportsReturning(db) {
+		result, err := db.Exec(db.Rebind(update), args...)
+		if err != nil {
+			return false, err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+		if count != 1 {
+			return false, nil
+		}
+		return true, sqlx.Get(db, &p.Version, db.Rebind("SELECT version FROM players WHERE id = ?"), p.ID)
+	}
+
+	err := db.QueryRowx(db.Rebind(update+`
+		RETURNING version`),
+		args...).
+		Scan(&p.Version)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// timestamp returns the current time in UTC, at the microsecond
+// precision every store can hold
+func timestamp() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// supportsReturning reports whether the database's dialect supports INSERT ... RETURNING
+func supportsReturning(db sqlx.Ext) bool {
+	return db.DriverName() == "postgres"
+}
@@ -2,8 +2,12 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
@@ -14,17 +18,51 @@ func TestPlayerStringShouldIncludeAllFields(t *testing.T) {
 	player := &Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  NewDate(time.Now().Year()-26, time.January, 1),
 		Experience: 5,
 		College:    "Central Florida",
 	}
 	assert.Equal(t, "[1] Blake Bortles (QB) -- #5, 6-5, 236lb, 26yo, 5exp -- Central Florida", player.String())
 }
 
+func TestPlayerMarshalJSONShouldIncludeLegacyStringFields(t *testing.T) {
+	player := Player{
+		ID:        1,
+		Name:      "Blake Bortles",
+		Number:    5,
+		Height:    77,
+		Weight:    236,
+		BirthDate: NewDate(time.Now().Year()-26, time.January, 1),
+	}
+	b, err := json.Marshal(player)
+	assert.Nil(t, err)
+	s := string(b)
+	assert.True(t, strings.Contains(s, `"number":"5"`))
+	assert.True(t, strings.Contains(s, `"height":"6-5"`))
+	assert.True(t, strings.Contains(s, `"weight":"236"`))
+	assert.True(t, strings.Contains(s, `"age":"26"`))
+	assert.True(t, strings.Contains(s, fmt.Sprintf(`"birth_date":"%d-01-01"`, time.Now().Year()-26)))
+}
+
+func TestPlayerUnmarshalJSONShouldAcceptStringsAndNumbers(t *testing.T) {
+	var player Player
+	err := json.Unmarshal([]byte(`{"number":"5","height":"6-5","weight":236,"age":"26","birth_date":"1992-04-29"}`), &player)
+	assert.Nil(t, err)
+	assert.Equal(t, Number(5), player.Number)
+	assert.Equal(t, Height(77), player.Height)
+	assert.Equal(t, Weight(236), player.Weight)
+	assert.Equal(t, NewDate(1992, time.April, 29), player.BirthDate)
+
+	err = json.Unmarshal([]byte(`{"number":5,"height":77}`), &player)
+	assert.Nil(t, err)
+	assert.Equal(t, Number(5), player.Number)
+	assert.Equal(t, Height(77), player.Height)
+}
+
 func TestListPlayersShouldReturnAllPlayers(t *testing.T) {
 	db, mock, err := createDB()
 	assert.Nil(t, err)
@@ -34,24 +72,68 @@ func TestListPlayersShouldReturnAllPlayers(t *testing.T) {
 		AddRow(1, "Blake Bortles", "5").
 		AddRow(2, "Jalen Ramsey", "20")
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC, id ASC LIMIT \$1$`).
 		WillReturnRows(rows)
 
-	players, err := ListPlayers(db, "")
+	players, _, err := ListPlayers(db, PlayerFilter{}, PageRequest{})
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(players))
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
+func TestListPlayersShouldStartAfterCursorAndReturnNextPageToken(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "number"}).
+		AddRow(2, "Jalen Ramsey", "20").
+		AddRow(3, "Leonard Fournette", "27")
+
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL AND \(number > \$1 OR \(number = \$2 AND id > \$3\)\) ORDER BY number ASC, id ASC LIMIT \$4$`).
+		WithArgs(5, 5, 1, 2).
+		WillReturnRows(rows)
+
+	players, next, err := ListPlayers(db, PlayerFilter{}, PageRequest{Size: 1, Token: newCursor(Player{ID: 1, Number: 5}, numberOrder).encode()})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(players))
+	assert.Equal(t, newCursor(Player{ID: 2, Number: 20}, numberOrder).encode(), next)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestListPlayersShouldApplyFilterAndSort(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "number"}).
+		AddRow(3, "Leonard Fournette", "27")
+
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL AND college = \$1 AND experience >= \$2 ORDER BY experience DESC, name ASC, id ASC LIMIT \$3$`).
+		WithArgs("LSU", 1, DefaultPageSize+1).
+		WillReturnRows(rows)
+
+	one := 1
+	players, next, err := ListPlayers(db, PlayerFilter{
+		College:       "LSU",
+		MinExperience: &one,
+		Sort:          ParseSort("-experience,name"),
+	}, PageRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(players))
+	assert.Equal(t, "", next)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
 func TestListPlayersShouldReturnNoRowsWhenNoPlayers(t *testing.T) {
 	db, mock, err := createDB()
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC, id ASC LIMIT \$1$`).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "number"}))
 
-	players, err := ListPlayers(db, "")
+	players, _, err := ListPlayers(db, PlayerFilter{}, PageRequest{})
 	assert.Error(t, sql.ErrNoRows, err)
 	assert.Equal(t, 0, len(players))
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -66,11 +148,11 @@ func TestListPlayersShouldUsePositionWhenSpecified(t *testing.T) {
 		AddRow(1, "Blake Bortles", "5", "QB").
 		AddRow(2, "Cody Kessler", "6", "QB")
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE position = \$1 ORDER BY number ASC$`).
-		WithArgs("QB").
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL AND position IN \(\$1\) ORDER BY number ASC, id ASC LIMIT \$2$`).
+		WithArgs("QB", DefaultPageSize+1).
 		WillReturnRows(rows)
 
-	players, err := ListPlayers(db, "QB")
+	players, _, err := ListPlayers(db, PlayerFilter{Positions: []string{"QB"}}, PageRequest{})
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(players))
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -81,10 +163,10 @@ func TestListPlayersShouldReturnErrorWhenDatabaseError(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC`).
 		WillReturnError(errors.New("database error"))
 
-	players, err := ListPlayers(db, "")
+	players, _, err := ListPlayers(db, PlayerFilter{}, PageRequest{})
 	assert.NotNil(t, err)
 	assert.Equal(t, 0, len(players))
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -98,11 +180,11 @@ func TestGetPlayerShouldReturnPlayerWhenExists(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "name", "number"}).
 		AddRow(1, "Blake Bortles", "5")
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnRows(rows)
 
-	player, err := GetPlayer(db, 1)
+	player, err := GetPlayer(db, 1, false)
 	assert.Nil(t, err)
 	assert.Equal(t, "Blake Bortles", player.Name)
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -113,11 +195,11 @@ func TestGetPlayerShouldReturnErrorWhenNotExists(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnError(sql.ErrNoRows)
 
-	player, err := GetPlayer(db, 1)
+	player, err := GetPlayer(db, 1, false)
 	assert.NotNil(t, err)
 	assert.Nil(t, player)
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -128,11 +210,11 @@ func TestGetPlayerShouldReturnErrorWhenDatabaseError(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnError(errors.New("database error"))
 
-	player, err := GetPlayer(db, 1)
+	player, err := GetPlayer(db, 1, false)
 	assert.NotNil(t, err)
 	assert.Nil(t, player)
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -145,19 +227,19 @@ func TestPlayerSaveShouldCreatePlayerWhenNoID(t *testing.T) {
 
 	p := &Player{
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
 	mock.ExpectQuery(`^INSERT INTO players
-		\(name, number, position, height, weight, age, experience, college\)
-		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)
+		\(name, number, position, height, weight, birth_date, experience, college, updated_at\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\)
 		RETURNING id$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	_, created, err := p.Save(db)
@@ -174,19 +256,19 @@ func TestPlayerSaveShouldReturnErrorWhenNoIDAndDatabaseError(t *testing.T) {
 
 	p := &Player{
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
 	mock.ExpectQuery(`^INSERT INTO players
-		\(name, number, position, height, weight, age, experience, college\)
-		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)
+		\(name, number, position, height, weight, birth_date, experience, college, updated_at\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\)
 		RETURNING id$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg()).
 		WillReturnError(errors.New("database error"))
 
 	_, created, err := p.Save(db)
@@ -204,19 +286,20 @@ func TestPlayerSaveShouldUpdatePlayerWhenHasID(t *testing.T) {
 	p := &Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
-	mock.ExpectExec(`^UPDATE players
-		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, age=\$6, experience=\$7, college=\$8
-		WHERE id=\$9$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`^UPDATE players
+		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, birth_date=\$6, experience=\$7, college=\$8, updated_at=\$9, version=version\+1
+		WHERE id=\$10 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg(), p.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
 
 	_, created, err := p.Save(db)
 	assert.Nil(t, err)
@@ -233,19 +316,20 @@ func TestPlayerSaveShouldReturnErrWhenUpdatePlayerNotFound(t *testing.T) {
 	p := &Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
-	mock.ExpectExec(`^UPDATE players
-		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, age=\$6, experience=\$7, college=\$8
-		WHERE id=\$9$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID).
-		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`^UPDATE players
+		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, birth_date=\$6, experience=\$7, college=\$8, updated_at=\$9, version=version\+1
+		WHERE id=\$10 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg(), p.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
 
 	_, created, err := p.Save(db)
 	assert.Error(t, sql.ErrNoRows, err)
@@ -262,18 +346,19 @@ func TestPlayerSaveShouldReturnErrorWhenHasIDAndDatabaseError(t *testing.T) {
 	p := &Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
-	mock.ExpectExec(`^UPDATE players
-		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, age=\$6, experience=\$7, college=\$8
-		WHERE id=\$9$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID).
+	mock.ExpectQuery(`^UPDATE players
+		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, birth_date=\$6, experience=\$7, college=\$8, updated_at=\$9, version=version\+1
+		WHERE id=\$10 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg(), p.ID).
 		WillReturnError(errors.New("database error"))
 
 	_, created, err := p.Save(db)
@@ -283,21 +368,25 @@ func TestPlayerSaveShouldReturnErrorWhenHasIDAndDatabaseError(t *testing.T) {
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
-func TestDeleteShouldDeletePlayer(t *testing.T) {
+func TestDeleteShouldMarkPlayerDeleted(t *testing.T) {
 	db, mock, err := createDB()
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
 
 	player := &Player{
 		ID: 1,
 	}
 	err = player.Delete(db)
 	assert.Nil(t, err)
+	assert.Equal(t, 2, player.Version)
+	assert.NotNil(t, player.DeletedAt)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
@@ -306,10 +395,12 @@ func TestDeleteShouldReturnNoRowsWhenNotFound(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
 
 	player := &Player{
 		ID: 1,
@@ -324,9 +415,11 @@ func TestDeleteShouldReturnErrorWhenError(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
 		WillReturnError(errors.New("database error"))
 
 	player := &Player{
@@ -337,12 +430,180 @@ func TestDeleteShouldReturnErrorWhenError(t *testing.T) {
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
+func TestSaveShouldReturnVersionMismatchWhenStale(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	p := &Player{
+		ID:      1,
+		Name:    "Blake Bortles",
+		Version: 2,
+	}
+	mock.ExpectQuery(`^UPDATE players
+		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, birth_date=\$6, experience=\$7, college=\$8, updated_at=\$9, version=version\+1
+		WHERE id=\$10 AND deleted_at IS NULL AND version=\$11
+		RETURNING version$`).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg(), p.ID, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	mock.ExpectQuery(`^SELECT version FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+	_, _, err = p.Save(db)
+	assert.Equal(t, ErrVersionMismatch, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveShouldReturnNewVersion(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	p := &Player{
+		ID:      1,
+		Name:    "Blake Bortles",
+		Version: 2,
+	}
+	mock.ExpectQuery(`WHERE id=\$10 AND deleted_at IS NULL AND version=\$11 RETURNING version$`).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg(), p.ID, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+	_, _, err = p.Save(db)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, p.Version)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteShouldCheckVersion(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL AND version=\$4
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	mock.ExpectQuery(`^SELECT version FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+	player := &Player{
+		ID:      1,
+		Version: 2,
+	}
+	err = player.Delete(db)
+	assert.Equal(t, ErrVersionMismatch, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteShouldReturnNoRowsWhenVersionedPlayerNotFound(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL AND version=\$4
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	mock.ExpectQuery(`^SELECT version FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+	player := &Player{
+		ID:      1,
+		Version: 2,
+	}
+	err = player.Delete(db)
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
 func createDB() (*sqlx.DB, sqlmock.Sqlmock, error) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	xdb := sqlx.NewDb(db, "sqlmock")
+	xdb := sqlx.NewDb(db, "postgres")
 	return xdb, mock, nil
 }
+
+func TestRestoreShouldClearDeletedAt(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=NULL, updated_at=\$1, version=version\+1
+		WHERE id=\$2 AND deleted_at IS NOT NULL AND version=\$3
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+	deletedAt := time.Now()
+	player := &Player{
+		ID:        1,
+		Version:   2,
+		DeletedAt: &deletedAt,
+	}
+	err = player.Restore(db)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, player.Version)
+	assert.Nil(t, player.DeletedAt)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreShouldReturnNotDeletedWhenPlayerIsLive(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`^UPDATE players SET deleted_at=NULL`).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "deleted_at"}).AddRow(1, "Jalen Ramsey", nil))
+
+	err = (&Player{ID: 1}).Restore(db)
+	assert.Equal(t, ErrNotDeleted, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgePlayersShouldDeletePlayersDeletedBeforeTime(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	before := time.Now().Add(-time.Hour)
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at < \$1 ORDER BY id ASC FOR UPDATE$`).
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "Blake Bortles").AddRow(5, "Chad Henne"))
+	mock.ExpectExec(`^DELETE FROM players WHERE id IN \(\$1, \$2\)$`).
+		WithArgs(2, 5).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	players, err := PurgePlayers(db, before)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(players))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgePlayersShouldDoNothingWhenNoneExpired(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at < \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	players, err := PurgePlayers(db, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(players))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,232 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PlayerRepository defines the storage operations for players
+type PlayerRepository interface {
+	ListPlayers(context.Context, PlayerFilter, PageRequest) ([]Player, string, error)
+	ExportPlayers(context.Context, PlayerFilter, func(*Player) error) error
+	SearchPlayers(context.Context, string, int) ([]SearchResult, error)
+	GetPlayer(context.Context, int, bool) (*Player, error)
+	SavePlayer(context.Context, *Player) (*Player, bool, error)
+	PatchPlayer(context.Context, int, *PlayerPatch) (*Player, error)
+	DeletePlayer(context.Context, int, int) error
+	RestorePlayer(context.Context, int, int) (*Player, error)
+	PurgePlayers(context.Context, time.Time) (int, error)
+	ImportPlayers(context.Context, []ImportRow) ([]ImportResult, error)
+	GetPlayerHistory(context.Context, int) ([]HistoryEntry, error)
+}
+
+type sqlRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresRepository returns a player repository backed by PostgreSQL
+func NewPostgresRepository(db *sqlx.DB) PlayerRepository {
+	return &sqlRepository{
+		db: db,
+	}
+}
+
+// NewSQLiteRepository returns a player repository backed by SQLite
+func NewSQLiteRepository(db *sqlx.DB) PlayerRepository {
+	return &sqlRepository{
+		db: db,
+	}
+}
+
+func (r *sqlRepository) ListPlayers(_ context.Context, filter PlayerFilter, page PageRequest) ([]Player, string, error) {
+	return ListPlayers(r.db, filter, page)
+}
+
+func (r *sqlRepository) ExportPlayers(ctx context.Context, filter PlayerFilter, fn func(*Player) error) error {
+	return ExportPlayers(ctx, r.db, filter, fn)
+}
+
+func (r *sqlRepository) SearchPlayers(_ context.Context, query string, limit int) ([]SearchResult, error) {
+	return SearchPlayers(r.db, query, limit)
+}
+
+func (r *sqlRepository) GetPlayer(_ context.Context, id int, includeDeleted bool) (*Player, error) {
+	return GetPlayer(r.db, id, includeDeleted)
+}
+
+// SavePlayer saves a player and records the change in its history, in
+// one transaction
+func (r *sqlRepository) SavePlayer(ctx context.Context, player *Player) (*Player, bool, error) {
+	created := false
+	err := transact(r.db, func(tx *sqlx.Tx) error {
+		var before *Player
+		if player.ID > 0 {
+			var err error
+			if before, err = lockPlayer(tx, player.ID); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if _, created, err = player.Save(tx); err != nil {
+			return err
+		}
+
+		operation := OperationUpdate
+		if created {
+			operation = OperationCreate
+		}
+		return recordChange(tx, operation, ActorFrom(ctx), before, player)
+	})
+	return player, created && err == nil, err
+}
+
+func (r *sqlRepository) PatchPlayer(ctx context.Context, id int, patch *PlayerPatch) (*Player, error) {
+	var player *Player
+	err := transact(r.db, func(tx *sqlx.Tx) error {
+		before, err := lockPlayer(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if player, err = PatchPlayer(tx, id, patch); err != nil {
+			return err
+		}
+		if len(patch.changes()) == 0 {
+			return nil
+		}
+		return recordChange(tx, OperationUpdate, ActorFrom(ctx), before, player)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+func (r *sqlRepository) DeletePlayer(ctx context.Context, id, version int) error {
+	return transact(r.db, func(tx *sqlx.Tx) error {
+		before, err := lockPlayer(tx, id)
+		if err != nil {
+			return err
+		}
+
+		player := *before
+		player.Version = version
+		if err := player.Delete(tx); err != nil {
+			return err
+		}
+		return recordChange(tx, OperationDelete, ActorFrom(ctx), before, &player)
+	})
+}
+
+func (r *sqlRepository) RestorePlayer(ctx context.Context, id, version int) (*Player, error) {
+	var player Player
+	err := transact(r.db, func(tx *sqlx.Tx) error {
+		before, err := lockPlayer(tx, id)
+		if err != nil {
+			return err
+		}
+
+		player = *before
+		player.Version = version
+		if err := player.Restore(tx); err != nil {
+			return err
+		}
+		return recordChange(tx, OperationRestore, ActorFrom(ctx), before, &player)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &player, nil
+}
+
+// PurgePlayers permanently removes the players deleted before a time,
+// recording each purge in the player's history, and returns how many it
+// removed
+func (r *sqlRepository) PurgePlayers(ctx context.Context, before time.Time) (int, error) {
+	count := 0
+	err := transact(r.db, func(tx *sqlx.Tx) error {
+		players, err := PurgePlayers(tx, before)
+		if err != nil {
+			return err
+		}
+		for i := range players {
+			if err := recordChange(tx, OperationPurge, ActorFrom(ctx), &players[i], nil); err != nil {
+				return err
+			}
+		}
+		count = len(players)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ImportPlayers creates or updates the players in an import, matching
+// them by name, in one transaction
+func (r *sqlRepository) ImportPlayers(ctx context.Context, rows []ImportRow) ([]ImportResult, error) {
+	results := make([]ImportResult, len(rows))
+	err := transact(r.db, func(tx *sqlx.Tx) error {
+		for i, row := range rows {
+			var matches []Player
+			if len(row.Errors) == 0 {
+				var err error
+				if matches, err = findPlayersByName(tx, row.Player.Name); err != nil {
+					return err
+				}
+			}
+
+			result, before, after := planImport(row, matches)
+			if after != nil {
+				if _, _, err := after.Save(tx); err != nil {
+					return err
+				}
+				operation := OperationUpdate
+				if before == nil {
+					operation = OperationCreate
+				}
+				if err := recordChange(tx, operation, ActorFrom(ctx), before, after); err != nil {
+					return err
+				}
+				result.ID = after.ID
+			}
+			results[i] = result
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (r *sqlRepository) GetPlayerHistory(_ context.Context, id int) ([]HistoryEntry, error) {
+	return GetPlayerHistory(r.db, id)
+}
+
+func recordChange(tx *sqlx.Tx, operation, actor string, before, after *Player) error {
+	entry, err := newHistoryEntry(operation, actor, before, after)
+	if err != nil {
+		return err
+	}
+	return recordHistory(tx, entry)
+}
+
+// transact runs a function in a transaction, committing if it succeeds
+// and rolling back if it fails
+func transact(db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestPostgresRepositoryListPlayersShouldQueryDatabase(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "number"}).
+		AddRow(1, "Blake Bortles", "5")
+
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC, id ASC LIMIT \$1$`).
+		WillReturnRows(rows)
+
+	players, _, err := NewPostgresRepository(db).ListPlayers(context.Background(), PlayerFilter{}, PageRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(players))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepositoryDeletePlayerShouldMarkPlayerDeleted(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 FOR UPDATE$`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version"}).AddRow(7, "Jalen Ramsey", 3))
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(4))
+	mock.ExpectExec(`^INSERT INTO player_history
+		\(player_id, version, operation, actor, changed_at, changes\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\)$`).
+		WithArgs(7, 4, OperationDelete, "jaguars", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = NewPostgresRepository(db).DeletePlayer(WithActor(context.Background(), "jaguars"), 7, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresRepositorySavePlayerShouldRollBackWhenHistoryFails(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`^INSERT INTO players`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(`^INSERT INTO player_history`).
+		WithArgs(1, 1, OperationCreate, Anonymous, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
+
+	_, created, err := NewPostgresRepository(db).SavePlayer(context.Background(), &Player{Name: "Jalen Ramsey"})
+	assert.EqualError(t, err, "database error")
+	assert.False(t, created)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoriesShouldSoftDeleteRestoreAndPurge(t *testing.T) {
+	db, err := createSQLiteDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	for name, repo := range map[string]PlayerRepository{
+		"memory": NewMemoryRepository(),
+		"sqlite": NewSQLiteRepository(db),
+	} {
+		ctx := context.Background()
+		for _, p := range []*Player{{Name: "Blake Bortles", Number: 5}, {Name: "Jalen Ramsey", Number: 20}} {
+			_, _, err := repo.SavePlayer(ctx, p)
+			assert.Nil(t, err, name)
+		}
+		assert.Nil(t, repo.DeletePlayer(ctx, 1, 0), name)
+		assert.Equal(t, sql.ErrNoRows, repo.DeletePlayer(ctx, 1, 0), name)
+
+		_, err := repo.GetPlayer(ctx, 1, false)
+		assert.Equal(t, sql.ErrNoRows, err, name)
+		deleted, err := repo.GetPlayer(ctx, 1, true)
+		assert.Nil(t, err, name)
+		assert.NotNil(t, deleted.DeletedAt, name)
+		assert.Equal(t, 2, deleted.Version, name)
+
+		players, _, err := repo.ListPlayers(ctx, PlayerFilter{}, PageRequest{})
+		assert.Nil(t, err, name)
+		assert.Equal(t, 1, len(players), name)
+		players, _, err = repo.ListPlayers(ctx, PlayerFilter{IncludeDeleted: true}, PageRequest{})
+		assert.Nil(t, err, name)
+		assert.Equal(t, 2, len(players), name)
+		results, err := repo.SearchPlayers(ctx, "bortles", 0)
+		assert.Nil(t, err, name)
+		assert.Equal(t, 0, len(results), name)
+
+		_, err = repo.PatchPlayer(ctx, 1, &PlayerPatch{Experience: new(int)})
+		assert.Equal(t, sql.ErrNoRows, err, name)
+		_, err = repo.RestorePlayer(ctx, 1, 1)
+		assert.Equal(t, ErrVersionMismatch, err, name)
+		_, err = repo.RestorePlayer(ctx, 2, 0)
+		assert.Equal(t, ErrNotDeleted, err, name)
+
+		restored, err := repo.RestorePlayer(ctx, 1, 2)
+		assert.Nil(t, err, name)
+		assert.Nil(t, restored.DeletedAt, name)
+		assert.Equal(t, 3, restored.Version, name)
+		assert.Equal(t, "Blake Bortles", restored.Name, name)
+
+		assert.Nil(t, repo.DeletePlayer(ctx, 2, 0), name)
+		n, err := repo.PurgePlayers(ctx, time.Now().UTC().Add(-time.Hour))
+		assert.Nil(t, err, name)
+		assert.Equal(t, 0, n, name)
+		n, err = repo.PurgePlayers(WithActor(ctx, "purger"), time.Now().UTC().Add(time.Hour))
+		assert.Nil(t, err, name)
+		assert.Equal(t, 1, n, name)
+		_, err = repo.GetPlayer(ctx, 2, true)
+		assert.Equal(t, sql.ErrNoRows, err, name)
+		_, err = repo.GetPlayer(ctx, 1, false)
+		assert.Nil(t, err, name)
+
+		entries, err := repo.GetPlayerHistory(ctx, 1)
+		assert.Nil(t, err, name)
+		assert.Equal(t, OperationRestore, entries[len(entries)-1].Operation, name)
+		assert.Equal(t, "name", entries[len(entries)-1].Changes[0].Field, name)
+		assert.Equal(t, json.RawMessage("null"), entries[len(entries)-1].Changes[0].Before, name)
+
+		entries, err = repo.GetPlayerHistory(ctx, 2)
+		assert.Nil(t, err, name)
+		purge := entries[len(entries)-1]
+		assert.Equal(t, OperationPurge, purge.Operation, name)
+		assert.Equal(t, "purger", purge.Actor, name)
+		assert.Equal(t, 0, len(purge.Changes), name)
+	}
+}
@@ -0,0 +1,268 @@
+package models
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultSearchLimit is the number of results returned when no limit is requested
+const DefaultSearchLimit = 20
+
+// MaxSearchLimit is the largest number of results returned by a search
+const MaxSearchLimit = 100
+
+// SearchResult is a player matching a search. Rank orders results from
+// best to worst, and Highlight is the player's name with the matching
+// words wrapped in <em> tags.
+type SearchResult struct {
+	Player    Player  `json:"player"`
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+// escapedName is the name column escaped as html.EscapeString does, so
+// ts_headline's tags are the only markup in a highlight
+const escapedName = `replace(replace(replace(replace(replace(name,
+		'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`
+
+// SearchPlayers finds the players whose names best match a query,
+// allowing partial words and misspellings
+func SearchPlayers(db *sqlx.DB, query string, limit int) ([]SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	if !supportsFullText(db) {
+		var players []Player
+		if err := db.Select(&players, "SELECT * FROM players WHERE deleted_at IS NULL"); err != nil {
+			return nil, err
+		}
+		return searchPlayers(players, terms, limit), nil
+	}
+
+	// Full-text prefix matches catch partial words, and trigram word
+	// similarity catches misspellings; both are indexed by migration 3
+	var rows []struct {
+		Player
+		Rank      float64 `db:"rank"`
+		Highlight string  `db:"highlight"`
+	}
+	err := db.Select(&rows, `SELECT players.*,
+			GREATEST(ts_rank(to_tsvector('simple', name), to_tsquery('simple', $1)),
+				word_similarity($2, name)) AS rank,
+			ts_headline('simple', `+escapedName+`, to_tsquery('simple', $1),
+				'StartSel=<em>, StopSel=</em>, HighlightAll=true') AS highlight
+		FROM players
+		WHERE deleted_at IS NULL
+			AND (to_tsvector('simple', name) @@ to_tsquery('simple', $1) OR $2 <% name)
+		ORDER BY rank DESC, id ASC
+		LIMIT $3`,
+		prefixQuery(terms), strings.Join(terms, " "), searchLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, len(rows))
+	for i, r := range rows {
+		// ts_headline only marks full-text matches, so highlight rows that
+		// only matched by trigram similarity the way other stores do
+		if !strings.Contains(r.Highlight, "<em>") {
+			matched, _ := matchWords(r.Name, terms)
+			r.Highlight = highlight(r.Name, matched)
+		}
+		results[i] = SearchResult{
+			Player:    r.Player,
+			Rank:      r.Rank,
+			Highlight: r.Highlight,
+		}
+	}
+	return results, nil
+}
+
+func supportsFullText(db *sqlx.DB) bool {
+	return db.DriverName() == "postgres"
+}
+
+func searchLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		return MaxSearchLimit
+	}
+	return limit
+}
+
+// searchTerms splits a query into lowercase words, dropping punctuation
+// so the words are safe to use in a tsquery
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// prefixQuery builds a tsquery matching any word starting with any term,
+// e.g., "jal:* | ram:*"
+func prefixQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t + ":*"
+	}
+	return strings.Join(parts, " | ")
+}
+
+// searchPlayers is the naive search for stores without full-text
+// indexes. Each term scores against its best-matching word in the name:
+// an exact match scores 1, a prefix less the more of the word it leaves
+// out, and a near miss less still.
+func searchPlayers(players []Player, terms []string, limit int) []SearchResult {
+	results := []SearchResult{}
+	for _, p := range players {
+		matched, total := matchWords(p.Name, terms)
+		if total == 0 {
+			continue
+		}
+
+		results = append(results, SearchResult{
+			Player:    p,
+			Rank:      total / float64(len(terms)),
+			Highlight: highlight(p.Name, matched),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Player.ID < results[j].Player.ID
+	})
+
+	if limit = searchLimit(limit); len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// matchWords finds the word in a name that best matches each term, and
+// returns the matched words with their total score
+func matchWords(name string, terms []string) (map[string]bool, float64) {
+	matched := map[string]bool{}
+	var total float64
+	for _, t := range terms {
+		var best float64
+		var bestWord string
+		for _, w := range nameWords(name) {
+			if s := termScore(t, strings.ToLower(w)); s > best {
+				best, bestWord = s, w
+			}
+		}
+		if best > 0 {
+			total += best
+			matched[bestWord] = true
+		}
+	}
+	return matched, total
+}
+
+func termScore(term, word string) float64 {
+	switch {
+	case term == word:
+		return 1
+	case strings.HasPrefix(word, term):
+		return 0.5 + 0.5*float64(len(term))/float64(len(word))
+	}
+
+	// Allow one edit in four, comparing against the start of the word so
+	// a misspelled prefix still matches
+	allowed := len([]rune(term)) / 4
+	if allowed == 0 {
+		return 0
+	}
+	prefix := []rune(word)
+	if len(prefix) > len([]rune(term))+allowed {
+		prefix = prefix[:len([]rune(term))]
+	}
+	if d := editDistance([]rune(term), prefix); d <= allowed {
+		return 0.4 * (1 - float64(d)/float64(len([]rune(term))))
+	}
+	return 0
+}
+
+// editDistance returns the number of insertions, deletions,
+// substitutions, and adjacent transpositions that turn one word into another
+func editDistance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func nameWords(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+// highlight wraps the matched words of a name in <em> tags, escaping the
+// rest of the name so the tags are the only markup
+func highlight(name string, matched map[string]bool) string {
+	var b strings.Builder
+	var word []rune
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		if matched[string(word)] {
+			b.WriteString("<em>" + html.EscapeString(string(word)) + "</em>")
+		} else {
+			b.WriteString(html.EscapeString(string(word)))
+		}
+		word = word[:0]
+	}
+	for _, r := range name {
+		if isWordRune(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		b.WriteString(html.EscapeString(string(r)))
+	}
+	flush()
+	return b.String()
+}
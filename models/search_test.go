@@ -0,0 +1,126 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+var searchRoster = []Player{
+	{ID: 1, Name: "Jalen Ramsey"},
+	{ID: 2, Name: "Jarrod Wilson"},
+	{ID: 3, Name: "Blake Bortles"},
+	{ID: 4, Name: "Leonard Fournette"},
+}
+
+func TestSearchTermsShouldDropPunctuation(t *testing.T) {
+	assert.Equal(t, []string{"a", "j", "bouye"}, searchTerms("A.J. Bouye!"))
+	assert.Equal(t, []string{"o", "neil"}, searchTerms("o'neil:*"))
+}
+
+func TestPrefixQueryShouldMatchAnyTermPrefix(t *testing.T) {
+	assert.Equal(t, "jal:* | ram:*", prefixQuery([]string{"jal", "ram"}))
+}
+
+func TestSearchPlayersShouldRankExactAbovePrefix(t *testing.T) {
+	results := searchPlayers(searchRoster, []string{"ja"}, 0)
+	assert.Equal(t, 2, len(results))
+
+	results = searchPlayers(searchRoster, []string{"jalen"}, 0)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, 1.0, results[0].Rank)
+	assert.Equal(t, "<em>Jalen</em> Ramsey", results[0].Highlight)
+}
+
+func TestSearchPlayersShouldMatchMisspellings(t *testing.T) {
+	results := searchPlayers(searchRoster, searchTerms("Leonrad Fornette"), 0)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, 4, results[0].Player.ID)
+	assert.Equal(t, "<em>Leonard</em> <em>Fournette</em>", results[0].Highlight)
+}
+
+func TestSearchPlayersShouldApplyLimit(t *testing.T) {
+	results := searchPlayers(searchRoster, []string{"ja"}, 1)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, 1, results[0].Player.ID)
+}
+
+func TestEditDistanceShouldCountEdits(t *testing.T) {
+	assert.Equal(t, 0, editDistance([]rune("ramsey"), []rune("ramsey")))
+	assert.Equal(t, 1, editDistance([]rune("ramsy"), []rune("ramsey")))
+	assert.Equal(t, 1, editDistance([]rune("leonrad"), []rune("leonard")))
+	assert.Equal(t, 2, editDistance([]rune("leanord"), []rune("leonard")))
+	assert.Equal(t, 3, editDistance([]rune("kitten"), []rune("sitting")))
+}
+
+func TestMemoryRepositorySearchPlayersShouldReturnEmptyWhenNoTerms(t *testing.T) {
+	results, err := NewMemoryRepository().SearchPlayers(context.Background(), "!!", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+}
+
+func TestSearchPlayersShouldUseFullTextOnPostgres(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "number", "rank", "highlight"}).
+		AddRow(1, "Jalen Ramsey", "20", 0.6, "Jalen <em>Ramsey</em>")
+
+	mock.ExpectQuery(`^SELECT players\.\*, .* FROM players WHERE .* @@ to_tsquery\('simple', \$1\) OR \$2 <% name\) ORDER BY rank DESC, id ASC LIMIT \$3$`).
+		WithArgs("ram:*", "ram", DefaultSearchLimit).
+		WillReturnRows(rows)
+
+	results, err := SearchPlayers(db, "Ram", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "Jalen Ramsey", results[0].Player.Name)
+	assert.Equal(t, 0.6, results[0].Rank)
+	assert.Equal(t, "Jalen <em>Ramsey</em>", results[0].Highlight)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchPlayersShouldHighlightTrigramMatchesOnPostgres(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "number", "rank", "highlight"}).
+		AddRow(1, "Jalen Ramsey", "20", 0.5, "Jalen Ramsey")
+
+	mock.ExpectQuery(`^SELECT players\.\*, .* FROM players WHERE .*$`).
+		WithArgs("ramsy:*", "ramsy", DefaultSearchLimit).
+		WillReturnRows(rows)
+
+	results, err := SearchPlayers(db, "ramsy", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "Jalen <em>Ramsey</em>", results[0].Highlight)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchPlayersShouldEscapeMarkupInNames(t *testing.T) {
+	roster := []Player{{ID: 1, Name: `Bob <img src=x onerror=alert(1)> "O'Neil" & Co`}}
+	results := searchPlayers(roster, []string{"bob"}, 0)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, `<em>Bob</em> &lt;img src=x onerror=alert(1)&gt; &#34;O&#39;Neil&#34; &amp; Co`, results[0].Highlight)
+
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "number", "rank", "highlight"}).
+		AddRow(1, "Bob <em>Ramsey</em>", "20", 0.5, "Bob &lt;em&gt;Ramsey&lt;/em&gt;")
+
+	mock.ExpectQuery(`^SELECT players\.\*, .* ts_headline\('simple', replace\(.*name, '&', '&amp;'\), '<', '&lt;'\), .* FROM players WHERE .*$`).
+		WithArgs("ramsy:*", "ramsy", DefaultSearchLimit).
+		WillReturnRows(rows)
+
+	results, err = SearchPlayers(db, "ramsy", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "Bob &lt;em&gt;<em>Ramsey</em>&lt;/em&gt;", results[0].Highlight)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
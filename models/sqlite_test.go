@@ -0,0 +1,210 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/hoop33/roster/migrations"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteRepositoryShouldCreateAndGetPlayer(t *testing.T) {
+	db, err := createSQLiteDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	repo := NewSQLiteRepository(db)
+	ctx := context.Background()
+
+	p := &Player{
+		Name:       "Blake Bortles",
+		Number:     5,
+		Position:   "QB",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  NewDate(1992, time.April, 29),
+		Experience: 5,
+	}
+	_, created, err := repo.SavePlayer(ctx, p)
+	assert.Nil(t, err)
+	assert.True(t, created)
+	assert.Equal(t, 1, p.ID)
+
+	player, err := repo.GetPlayer(ctx, 1, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Blake Bortles", player.Name)
+	assert.Equal(t, Height(77), player.Height)
+	assert.Equal(t, Weight(236), player.Weight)
+	assert.Equal(t, NewDate(1992, time.April, 29), player.BirthDate)
+	assert.Equal(t, 5, player.Experience)
+	assert.True(t, p.UpdatedAt.Equal(player.UpdatedAt))
+}
+
+func TestSQLiteRepositoryShouldUpdateListAndDeletePlayers(t *testing.T) {
+	db, err := createSQLiteDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	repo := NewSQLiteRepository(db)
+	ctx := context.Background()
+
+	_, _, err = repo.SavePlayer(ctx, &Player{Name: "Blake Bortles", Number: 5, Position: "QB"})
+	assert.Nil(t, err)
+	_, _, err = repo.SavePlayer(ctx, &Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+
+	_, created, err := repo.SavePlayer(ctx, &Player{ID: 1, Name: "Cody Kessler", Number: 6, Position: "QB"})
+	assert.Nil(t, err)
+	assert.False(t, created)
+
+	players, _, err := repo.ListPlayers(ctx, PlayerFilter{Positions: []string{"QB"}}, PageRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(players))
+	assert.Equal(t, "Cody Kessler", players[0].Name)
+
+	assert.Nil(t, repo.DeletePlayer(ctx, 1, 0))
+	assert.Equal(t, sql.ErrNoRows, repo.DeletePlayer(ctx, 1, 0))
+
+	_, _, err = repo.ListPlayers(ctx, PlayerFilter{Positions: []string{"QB"}}, PageRequest{})
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func TestSQLiteRepositoryShouldCheckVersions(t *testing.T) {
+	db, err := createSQLiteDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	repo := NewSQLiteRepository(db)
+	ctx := context.Background()
+
+	player, _, err := repo.SavePlayer(ctx, &Player{Name: "Blake Bortles", Number: 5, Position: "QB"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, player.Version)
+
+	player, _, err = repo.SavePlayer(ctx, &Player{ID: 1, Name: "Cody Kessler", Number: 6, Position: "QB", Version: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, player.Version)
+
+	_, _, err = repo.SavePlayer(ctx, &Player{ID: 1, Name: "Blake Bortles", Version: 1})
+	assert.Equal(t, ErrVersionMismatch, err)
+
+	number := Number(7)
+	player, err = repo.PatchPlayer(ctx, 1, &PlayerPatch{Number: &number, Version: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, player.Version)
+	assert.Equal(t, "Cody Kessler", player.Name)
+
+	_, err = repo.PatchPlayer(ctx, 1, &PlayerPatch{Number: &number, Version: 2})
+	assert.Equal(t, ErrVersionMismatch, err)
+
+	assert.Equal(t, ErrVersionMismatch, repo.DeletePlayer(ctx, 1, 2))
+	assert.Nil(t, repo.DeletePlayer(ctx, 1, 3))
+	assert.Equal(t, sql.ErrNoRows, repo.DeletePlayer(ctx, 1, 3))
+}
+
+func TestSQLiteRepositoryShouldFilterSortAndPagePlayers(t *testing.T) {
+	db, err := createSQLiteDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	repo := NewSQLiteRepository(db)
+	ctx := context.Background()
+
+	for _, p := range []Player{
+		{Name: "Jalen Ramsey", Number: 20, Position: "CB", BirthDate: NewDate(1994, time.October, 24)},
+		{Name: "Jarrod Wilson", Number: 26, Position: "S"},
+		{Name: "Blake Bortles", Number: 5, Position: "QB", BirthDate: NewDate(1992, time.April, 29)},
+		{Name: "Jaydon Mickens", Number: 14, Position: "WR", BirthDate: NewDate(1994, time.October, 24)},
+	} {
+		p := p
+		_, _, err = repo.SavePlayer(ctx, &p)
+		assert.Nil(t, err)
+	}
+
+	filter := PlayerFilter{NamePrefix: "ja", Sort: ParseSort("birth_date,-name")}
+	var names []string
+	token := ""
+	for {
+		players, next, err := repo.ListPlayers(ctx, filter, PageRequest{Size: 1, Token: token})
+		assert.Nil(t, err)
+		for _, p := range players {
+			names = append(names, p.Name)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	assert.Equal(t, []string{"Jarrod Wilson", "Jaydon Mickens", "Jalen Ramsey"}, names)
+}
+
+func TestSQLiteRepositoryShouldPageMigratedAndSavedBirthDates(t *testing.T) {
+	db, err := createSQLiteDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	repo := NewSQLiteRepository(db)
+	ctx := context.Background()
+
+	// Migrated rows have plain dates, as date('now', ...) returns them
+	_, err = db.Exec(`INSERT INTO players (name, number, position, birth_date, experience, college) VALUES
+		('Jalen Ramsey', 20, 'CB', '1994-10-24', 0, ''),
+		('Blake Bortles', 5, 'QB', '1992-04-29', 0, '')`)
+	assert.Nil(t, err)
+	for _, p := range []Player{
+		{Name: "Jaydon Mickens", Number: 14, Position: "WR", BirthDate: NewDate(1994, time.October, 24)},
+		{Name: "Jarrod Wilson", Number: 26, Position: "S"},
+	} {
+		p := p
+		_, _, err = repo.SavePlayer(ctx, &p)
+		assert.Nil(t, err)
+	}
+
+	var stored string
+	assert.Nil(t, db.Get(&stored, "SELECT CAST(birth_date AS TEXT) FROM players WHERE id = 3"))
+	assert.Equal(t, "1994-10-24", stored)
+
+	for sort, expected := range map[string][]int{
+		"birth_date":  {4, 2, 1, 3},
+		"-birth_date": {1, 3, 2, 4},
+	} {
+		filter := PlayerFilter{Sort: ParseSort(sort)}
+		all, _, err := repo.ListPlayers(ctx, filter, PageRequest{})
+		assert.Nil(t, err)
+		var ids []int
+		for _, p := range all {
+			ids = append(ids, p.ID)
+		}
+		assert.Equal(t, expected, ids, sort)
+
+		ids = nil
+		token := ""
+		for {
+			players, next, err := repo.ListPlayers(ctx, filter, PageRequest{Size: 1, Token: token})
+			assert.Nil(t, err)
+			for _, p := range players {
+				ids = append(ids, p.ID)
+			}
+			if next == "" {
+				break
+			}
+			token = next
+		}
+		assert.Equal(t, expected, ids, sort)
+	}
+}
+
+func createSQLiteDB() (*sqlx.DB, error) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	_, err = migrations.NewMigrator(db).Up()
+	return db, err
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/players"
+	"github.com/hoop33/roster/players/client"
+	"gopkg.in/yaml.v2"
+)
+
+var errPlayersUsage = errors.New("usage: roster players list|get|create|update|delete [-transport http|grpc] [-addr address] [-output table|json|yaml] [args]")
+
+// playersCommand holds the flags every players command takes
+type playersCommand struct {
+	fs        *flag.FlagSet
+	transport *string
+	addr      *string
+	output    *string
+	timeout   *time.Duration
+	// args are the arguments left once the flags are parsed
+	args []string
+}
+
+func newPlayersCommand(name string) *playersCommand {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return &playersCommand{
+		fs:        fs,
+		transport: fs.String("transport", "http", "transport to call the server with (http or grpc)"),
+		addr:      fs.String("addr", "", "server address (default http://localhost:9090, or localhost:9091 for grpc)"),
+		output:    fs.String("output", "table", "output format (table, json, or yaml)"),
+		timeout:   fs.Duration("timeout", client.DefaultTimeout, "how long to wait for the server"),
+	}
+}
+
+// parse parses the command's arguments, requiring nargs of them besides
+// the flags. Flags may come before or after the arguments, up to a "--".
+func (c *playersCommand) parse(args []string, nargs int) error {
+	c.args = nil
+	for len(args) > 0 {
+		if err := c.fs.Parse(args); err != nil {
+			return errPlayersUsage
+		}
+		rest := c.fs.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			c.args = append(c.args, rest...)
+			break
+		}
+		if len(rest) > 0 {
+			c.args = append(c.args, rest[0])
+			rest = rest[1:]
+		}
+		args = rest
+	}
+	if len(c.args) != nargs {
+		return errPlayersUsage
+	}
+	switch *c.output {
+	case "table", "json", "yaml":
+	default:
+		return fmt.Errorf("unknown output format %q", *c.output)
+	}
+	return nil
+}
+
+// service returns a client for the server, and a function to close it
+func (c *playersCommand) service() (players.Service, func() error, error) {
+	switch *c.transport {
+	case "http":
+		addr := *c.addr
+		if addr == "" {
+			addr = "http://localhost:9090"
+		}
+		ps, err := client.New(addr, client.Timeout(*c.timeout))
+		return ps, func() error { return nil }, err
+	case "grpc":
+		addr := *c.addr
+		if addr == "" {
+			addr = "localhost:9091"
+		}
+		gc, err := client.DialGRPC(addr, client.Timeout(*c.timeout))
+		if err != nil {
+			return nil, nil, err
+		}
+		return gc, gc.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown transport %q", *c.transport)
+	}
+}
+
+// runPlayers manages the players of a running server. Changes are
+// attributed to the $USER running the command.
+func runPlayers(args []string, stdin io.Reader, w io.Writer) error {
+	if len(args) == 0 {
+		return errPlayersUsage
+	}
+
+	var run func(*playersCommand, []string, io.Reader, io.Writer) error
+	switch args[0] {
+	case "list":
+		run = runPlayersList
+	case "get":
+		run = runPlayersGet
+	case "create":
+		run = runPlayersCreate
+	case "update":
+		run = runPlayersUpdate
+	case "delete":
+		run = runPlayersDelete
+	default:
+		return errPlayersUsage
+	}
+	return run(newPlayersCommand(args[0]), args[1:], stdin, w)
+}
+
+// callPlayers calls the server with a client for the command
+func callPlayers(c *playersCommand, call func(context.Context, players.Service) error) error {
+	ps, closer, err := c.service()
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	ctx := context.Background()
+	if user := os.Getenv("USER"); user != "" {
+		ctx = models.WithActor(ctx, user)
+	}
+	return call(ctx, ps)
+}
+
+func runPlayersList(c *playersCommand, args []string, _ io.Reader, w io.Writer) error {
+	filter := filterFlags(c.fs)
+	size := c.fs.Int("page-size", 0, "players to list (default 100)")
+	token := c.fs.String("page-token", "", "page to list, from the previous page")
+	if err := c.parse(args, 0); err != nil {
+		return err
+	}
+
+	return callPlayers(c, func(ctx context.Context, ps players.Service) error {
+		list, next, err := ps.ListPlayers(ctx, filter(), models.PageRequest{Size: *size, Token: *token})
+		if err != nil && players.KindOf(err) != players.KindNotFound {
+			return err
+		}
+
+		response := struct {
+			Players       []models.Player `json:"players"`
+			NextPageToken string          `json:"next_page_token,omitempty"`
+		}{
+			Players:       list,
+			NextPageToken: next,
+		}
+		if response.Players == nil {
+			response.Players = []models.Player{}
+		}
+		return writeOutput(w, *c.output, response, func(tw *tabwriter.Writer) {
+			writePlayerTable(tw, list...)
+			if next != "" {
+				fmt.Fprintf(tw, "\nnext page: -page-token %s\n", next)
+			}
+		})
+	})
+}
+
+func runPlayersGet(c *playersCommand, args []string, _ io.Reader, w io.Writer) error {
+	includeDeleted := c.fs.Bool("include-deleted", false, "get the player even if it's deleted")
+	if err := c.parse(args, 1); err != nil {
+		return err
+	}
+	id, err := parsePlayerID(c.args[0])
+	if err != nil {
+		return err
+	}
+
+	return callPlayers(c, func(ctx context.Context, ps players.Service) error {
+		player, err := ps.GetPlayer(ctx, id, *includeDeleted)
+		if err != nil {
+			return err
+		}
+		return writePlayer(w, *c.output, player)
+	})
+}
+
+func runPlayersCreate(c *playersCommand, args []string, stdin io.Reader, w io.Writer) error {
+	file := c.fs.String("f", "", "JSON file with the player to create, or - for standard input")
+	if err := c.parse(args, 0); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errPlayersUsage
+	}
+	b, err := readInput(*file, stdin)
+	if err != nil {
+		return err
+	}
+
+	var player models.Player
+	if err := json.Unmarshal(b, &player); err != nil {
+		return fmt.Errorf("invalid player: %v", err)
+	}
+	if player.ID != 0 {
+		return errors.New("a new player can't have an id; use update to change a player")
+	}
+
+	return callPlayers(c, func(ctx context.Context, ps players.Service) error {
+		created, _, err := ps.SavePlayer(ctx, &player)
+		if err != nil {
+			return err
+		}
+		return writePlayer(w, *c.output, created)
+	})
+}
+
+func runPlayersUpdate(c *playersCommand, args []string, stdin io.Reader, w io.Writer) error {
+	file := c.fs.String("f", "", "JSON merge patch with the fields to change, or - for standard input")
+	version := c.fs.Int("version", 0, "version the player must be at")
+	if err := c.parse(args, 1); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errPlayersUsage
+	}
+	id, err := parsePlayerID(c.args[0])
+	if err != nil {
+		return err
+	}
+	b, err := readInput(*file, stdin)
+	if err != nil {
+		return err
+	}
+	patch, err := models.ParseMergePatch(b)
+	if err != nil {
+		return err
+	}
+	patch.Version = *version
+
+	return callPlayers(c, func(ctx context.Context, ps players.Service) error {
+		player, err := ps.PatchPlayer(ctx, id, patch)
+		if err != nil {
+			return err
+		}
+		return writePlayer(w, *c.output, player)
+	})
+}
+
+func runPlayersDelete(c *playersCommand, args []string, _ io.Reader, w io.Writer) error {
+	version := c.fs.Int("version", 0, "version the player must be at")
+	if err := c.parse(args, 1); err != nil {
+		return err
+	}
+	id, err := parsePlayerID(c.args[0])
+	if err != nil {
+		return err
+	}
+
+	return callPlayers(c, func(ctx context.Context, ps players.Service) error {
+		if err := ps.DeletePlayer(ctx, id, *version); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "deleted player %d\n", id)
+		return nil
+	})
+}
+
+func parsePlayerID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid player id %q", s)
+	}
+	return id, nil
+}
+
+// readInput reads the named file, or stdin for "-"
+func readInput(file string, stdin io.Reader) ([]byte, error) {
+	if file == "-" {
+		return ioutil.ReadAll(stdin)
+	}
+	return ioutil.ReadFile(file)
+}
+
+func writePlayer(w io.Writer, output string, player *models.Player) error {
+	return writeOutput(w, output, player, func(tw *tabwriter.Writer) {
+		writePlayerTable(tw, *player)
+	})
+}
+
+func writePlayerTable(tw *tabwriter.Writer, list ...models.Player) {
+	fmt.Fprintln(tw, "ID\tNUMBER\tNAME\tPOSITION\tHEIGHT\tWEIGHT\tBIRTH DATE\tEXP\tCOLLEGE\tVERSION")
+	for _, p := range list {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%d\n",
+			p.ID, p.Number, p.Name, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, p.Version)
+	}
+}
+
+// writeOutput writes v as it's encoded in JSON, as YAML with the same
+// keys in the same order, or as the table that table writes
+func writeOutput(w io.Writer, output string, v interface{}, table func(*tabwriter.Writer)) error {
+	switch output {
+	case "json":
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case "yaml":
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		// JSON is YAML, and objects decoded into MapSlice keep their order
+		var ordered yaml.MapSlice
+		if err := yaml.Unmarshal(b, &ordered); err != nil {
+			return err
+		}
+		y, err := yaml.Marshal(ordered)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(y)
+		return err
+	default:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	}
+}
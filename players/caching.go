@@ -0,0 +1,90 @@
+package players
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/hoop33/roster/models"
+)
+
+type cacheContextKey int
+
+const (
+	contextKeyCacheControl cacheContextKey = iota
+	contextKeyIfNoneMatch
+	contextKeyIfModifiedSince
+)
+
+// populateCacheContext stores the cache policy and the request's
+// conditional headers in the context, for the response encoders
+func populateCacheContext(cacheControl string) kithttp.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		ctx = context.WithValue(ctx, contextKeyCacheControl, cacheControl)
+		if r.Method != "GET" && r.Method != "HEAD" {
+			return ctx
+		}
+		ctx = context.WithValue(ctx, contextKeyIfNoneMatch, r.Header.Get("If-None-Match"))
+		return context.WithValue(ctx, contextKeyIfModifiedSince, r.Header.Get("If-Modified-Since"))
+	}
+}
+
+// notModified sets the caching headers for a response, and reports
+// whether the request's conditions show the client's copy is current.
+// As in RFC 7232, If-None-Match wins over If-Modified-Since.
+func notModified(ctx context.Context, w http.ResponseWriter, etag string, modified time.Time) bool {
+	h := w.Header()
+	if cc, _ := ctx.Value(contextKeyCacheControl).(string); cc != "" {
+		h.Set("Cache-Control", cc)
+	}
+	if etag != "" {
+		h.Set("ETag", etag)
+	}
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm, _ := ctx.Value(contextKeyIfNoneMatch).(string); inm != "" {
+		return etag != "" && etagMatches(inm, etag)
+	}
+
+	ims, _ := ctx.Value(contextKeyIfModifiedSince).(string)
+	if ims == "" || modified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// etagMatches reports whether an If-None-Match header lists an entity
+// tag, using the weak comparison RFC 7232 requires for it
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// listETag returns a weak entity tag for a page of players, which
+// changes whenever a player on it is saved, added, or removed. Lists have
+// no Last-Modified date, because removing a player doesn't change when
+// the rest were saved.
+func listETag(players []models.Player, next string) string {
+	h := fnv.New64a()
+	for _, p := range players {
+		fmt.Fprintf(h, "%d:%d;", p.ID, p.Version)
+	}
+	fmt.Fprint(h, next)
+	return fmt.Sprintf(`W/"%x"`, h.Sum64())
+}
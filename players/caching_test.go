@@ -0,0 +1,136 @@
+package players
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/hoop33/roster/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETagMatchesShouldUseWeakComparison(t *testing.T) {
+	assert.True(t, etagMatches(`"3"`, `"3"`))
+	assert.True(t, etagMatches(`W/"3"`, `"3"`))
+	assert.True(t, etagMatches(`"1", "3"`, `W/"3"`))
+	assert.True(t, etagMatches(`*`, `"3"`))
+	assert.False(t, etagMatches(`"2"`, `"3"`))
+}
+
+func TestNotModifiedShouldPreferIfNoneMatch(t *testing.T) {
+	modified := time.Date(2018, time.June, 1, 12, 0, 0, 500, time.UTC)
+	for _, tc := range []struct {
+		ifNoneMatch     string
+		ifModifiedSince string
+		want            bool
+	}{
+		{"", "", false},
+		{`"3"`, "", true},
+		{`"2"`, "", false},
+		{`"2"`, "Fri, 01 Jun 2018 12:00:00 GMT", false},
+		{"", "Fri, 01 Jun 2018 12:00:00 GMT", true},
+		{"", "Fri, 01 Jun 2018 11:59:59 GMT", false},
+		{"", "not a date", false},
+	} {
+		r := httptest.NewRequest("GET", "/v1/players/1", nil)
+		if tc.ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", tc.ifNoneMatch)
+		}
+		if tc.ifModifiedSince != "" {
+			r.Header.Set("If-Modified-Since", tc.ifModifiedSince)
+		}
+		ctx := populateCacheContext("no-cache")(context.Background(), r)
+
+		w := httptest.NewRecorder()
+		assert.Equal(t, tc.want, notModified(ctx, w, `"3"`, modified), tc.ifNoneMatch+tc.ifModifiedSince)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+		assert.Equal(t, "Fri, 01 Jun 2018 12:00:00 GMT", w.Header().Get("Last-Modified"))
+		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestListETagShouldChangeWithPlayers(t *testing.T) {
+	players := []models.Player{{ID: 1, Version: 1}, {ID: 2, Version: 1}}
+	tag := listETag(players, "")
+	assert.Equal(t, tag, listETag(players, ""))
+
+	assert.NotEqual(t, tag, listETag(players, "next"))
+	assert.NotEqual(t, tag, listETag(players[:1], ""))
+	assert.NotEqual(t, tag, listETag([]models.Player{{ID: 1, Version: 1}, {ID: 2, Version: 2}}, ""))
+}
+
+func TestHTTPListShouldChangeWhenAPlayerIsDeleted(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	for _, name := range []string{"Jalen Ramsey", "A.J. Bouye"} {
+		_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: name, Number: 20, Position: "CB"})
+		assert.Nil(t, err)
+	}
+	handler := NewHTTPTransport(NewEndpoints(NewService(repo)), log.NewNopLogger())
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", "/v1/players", nil))
+	assert.Equal(t, "", resp.Header().Get("Last-Modified"))
+	etag := resp.Header().Get("ETag")
+
+	assert.Nil(t, repo.DeletePlayer(context.Background(), 2, 0))
+
+	req := httptest.NewRequest("GET", "/v1/players", nil)
+	req.Header.Set("If-None-Match", etag)
+	req.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	req = httptest.NewRequest("GET", "/v1/players", nil)
+	req.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestHTTPShouldAnswerConditionalGets(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+	handler := NewHTTPTransport(NewEndpoints(NewService(repo)), log.NewNopLogger(), CacheControl("public, max-age=60"))
+
+	for _, path := range []string{"/v1/players/1", "/v1/players"} {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "public, max-age=60", resp.Header().Get("Cache-Control"))
+		etag := resp.Header().Get("ETag")
+		assert.NotEqual(t, "", etag)
+
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("If-None-Match", etag)
+		resp = httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusNotModified, resp.Code, path)
+		assert.Equal(t, 0, resp.Body.Len())
+	}
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", "/v1/players/1", nil))
+	lastModified := resp.Header().Get("Last-Modified")
+	assert.NotEqual(t, "", lastModified)
+	req := httptest.NewRequest("GET", "/v1/players/1", nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotModified, resp.Code)
+
+	etag := `"1"`
+	_, _, err = repo.SavePlayer(context.Background(), &models.Player{ID: 1, Name: "Jalen Ramsey", Number: 5, Position: "CB"})
+	assert.Nil(t, err)
+
+	req = httptest.NewRequest("GET", "/v1/players/1", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"2"`, resp.Header().Get("ETag"))
+}
@@ -0,0 +1,147 @@
+// Package client calls a remote players service over HTTP or gRPC. The
+// services it returns implement players.Service, so they can stand in for
+// a local one.
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/hoop33/roster/players"
+)
+
+// DefaultTimeout limits each attempt at a call
+const DefaultTimeout = 30 * time.Second
+
+// DefaultBackoff is how long to wait before the first retry
+const DefaultBackoff = 100 * time.Millisecond
+
+type client struct {
+	httpClient *http.Client
+	tlsConfig  *tls.Config
+	keepalive  time.Duration
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+}
+
+// Option sets an optional parameter for the client
+type Option func(*client)
+
+// Timeout limits each attempt at a call, including reading the response,
+// so long exports may need more; 0 means no limit
+func Timeout(d time.Duration) Option {
+	return func(c *client) {
+		c.timeout = d
+	}
+}
+
+// Retries sets how many times a call is retried when the service can't
+// be reached, or reports that it's unavailable. Errors the service
+// returns aren't retried. A create or import whose response is lost may
+// be applied twice.
+func Retries(n int) Option {
+	return func(c *client) {
+		c.retries = n
+	}
+}
+
+// Backoff sets how long to wait before the first retry; the wait doubles
+// for each retry after
+func Backoff(d time.Duration) Option {
+	return func(c *client) {
+		c.backoff = d
+	}
+}
+
+// HTTPClient sets the HTTP client to make requests with, e.g., to
+// configure TLS. Its timeout is replaced by the client's.
+func HTTPClient(hc *http.Client) Option {
+	return func(c *client) {
+		c.httpClient = hc
+	}
+}
+
+// TLS makes gRPC connections with TLS; without it, they're insecure
+func TLS(config *tls.Config) Option {
+	return func(c *client) {
+		c.tlsConfig = config
+	}
+}
+
+// Keepalive pings the gRPC service after the connection has been idle for
+// d, so broken connections are noticed before the next call; 0 never pings
+func Keepalive(d time.Duration) Option {
+	return func(c *client) {
+		c.keepalive = d
+	}
+}
+
+func newClient(options []Option) *client {
+	c := &client{
+		httpClient: http.DefaultClient,
+		timeout:    DefaultTimeout,
+		backoff:    DefaultBackoff,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// New returns a players service that calls the HTTP transport at
+// baseURL, e.g., "http://localhost:9090"
+func New(baseURL string, options ...Option) (players.Service, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+
+	c := newClient(options)
+	hc := *c.httpClient
+	hc.Timeout = c.timeout
+	ep := players.NewHTTPClient(u, &hc)
+	if c.retries > 0 {
+		ep = ep.Wrap(c.retry)
+	}
+	return ep, nil
+}
+
+// retry calls an endpoint again when it fails. The HTTP endpoints only
+// fail when the service can't be reached, or a gateway reports it
+// unavailable.
+func (c *client) retry(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		err = c.withRetries(ctx, func() error {
+			response, err = next(ctx, request)
+			return err
+		}, func(error) bool {
+			return true
+		})
+		return response, err
+	}
+}
+
+// withRetries makes an attempt until it succeeds, fails in a way that
+// isn't retryable, runs out of retries, or the context is done, waiting
+// twice as long before each retry as the one before
+func (c *client) withRetries(ctx context.Context, attempt func() error, retryable func(error) bool) error {
+	wait := c.backoff
+	for i := 0; ; i++ {
+		err := attempt()
+		if err == nil || i >= c.retries || !retryable(err) {
+			return err
+		}
+		select {
+		case <-time.After(wait):
+			wait *= 2
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
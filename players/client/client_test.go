@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/players"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestService() players.Service {
+	return players.NewValidatingService(players.NewService(models.NewMemoryRepository()))
+}
+
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(players.NewHTTPTransport(players.NewEndpoints(newTestService()), log.NewNopLogger()))
+}
+
+func TestNewShouldRejectInvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:9090", "ftp://localhost", "http://"} {
+		_, err := New(baseURL)
+		assert.NotNil(t, err, baseURL)
+	}
+}
+
+func TestClientShouldSendAnImportInOneRequest(t *testing.T) {
+	var calls int32
+	handler := players.NewHTTPTransport(players.NewEndpoints(newTestService()), log.NewNopLogger())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	ps, err := New(srv.URL)
+	assert.Nil(t, err)
+	results, err := ps.ImportPlayers(context.Background(), importRowsSettingDifferentFields())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestClientShouldRetryUnavailableService(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	direct, err := New(srv.URL)
+	assert.Nil(t, err)
+	_, _, err = direct.SavePlayer(context.Background(), &models.Player{Name: "Blake Bortles", Number: 5, Position: "QB"})
+	assert.Nil(t, err)
+
+	var calls int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.Redirect(w, r, srv.URL+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+	}))
+	defer flaky.Close()
+
+	ps, err := New(flaky.URL, Retries(1), Backoff(time.Millisecond))
+	assert.Nil(t, err)
+	_, _, err = ps.ListPlayers(context.Background(), models.PlayerFilter{}, models.PageRequest{})
+	assert.Equal(t, "players service unavailable: 503 Service Unavailable", err.Error())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	ps, err = New(flaky.URL, Retries(2), Backoff(time.Millisecond))
+	assert.Nil(t, err)
+	_, _, err = ps.ListPlayers(context.Background(), models.PlayerFilter{}, models.PageRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	_, err = ps.GetPlayer(context.Background(), 2, false)
+	assert.Equal(t, players.KindNotFound, players.KindOf(err))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+}
+
+func TestClientShouldTimeOut(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	ps, err := New(slow.URL, Timeout(10*time.Millisecond))
+	assert.Nil(t, err)
+	_, err = ps.GetPlayer(context.Background(), 1, false)
+	assert.NotNil(t, err)
+	assert.Equal(t, players.KindInternal, players.KindOf(err))
+}
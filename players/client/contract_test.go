@@ -0,0 +1,262 @@
+package client
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/pb"
+	"github.com/hoop33/roster/players"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestGRPCServer serves a players service over gRPC on a free local port
+func newTestGRPCServer(t *testing.T) (string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	srv := grpc.NewServer()
+	pb.RegisterPlayersServer(srv, players.NewGRPCTransport(players.NewEndpoints(newTestService()), log.NewNopLogger()))
+	go srv.Serve(lis)
+	return lis.Addr().String(), srv.Stop
+}
+
+// testContract runs the same assertions against a local service and the
+// clients for each transport, so the clients behave just like the local one
+func testContract(t *testing.T, contract func(*testing.T, players.Service)) {
+	t.Run("local", func(t *testing.T) {
+		contract(t, newTestService())
+	})
+
+	t.Run("http", func(t *testing.T) {
+		srv := newTestServer()
+		defer srv.Close()
+		ps, err := New(srv.URL + "/")
+		assert.Nil(t, err)
+		contract(t, ps)
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		addr, stop := newTestGRPCServer(t)
+		defer stop()
+		ps, err := DialGRPC(addr)
+		assert.Nil(t, err)
+		defer ps.Close()
+		contract(t, ps)
+	})
+}
+
+func TestContractShouldManagePlayers(t *testing.T) {
+	testContract(t, func(t *testing.T, ps players.Service) {
+		ctx := models.WithActor(context.Background(), "tcoughlin")
+
+		created, isNew, err := ps.SavePlayer(ctx, &models.Player{
+			Name:      "Blake Bortles",
+			Number:    5,
+			Position:  "QB",
+			Height:    77,
+			BirthDate: models.NewDate(1992, time.April, 29),
+		})
+		assert.Nil(t, err)
+		assert.True(t, isNew)
+		assert.Equal(t, 1, created.ID)
+		assert.Equal(t, 1, created.Version)
+
+		stale := *created
+		created.College = "Central Florida"
+		updated, isNew, err := ps.SavePlayer(ctx, created)
+		assert.Nil(t, err)
+		assert.False(t, isNew)
+		assert.Equal(t, 2, updated.Version)
+
+		_, _, err = ps.SavePlayer(ctx, &stale)
+		assert.Equal(t, players.KindPreconditionFailed, players.KindOf(err))
+
+		number := models.Number(6)
+		patched, err := ps.PatchPlayer(ctx, 1, &models.PlayerPatch{Number: &number, Version: 2})
+		assert.Nil(t, err)
+		assert.Equal(t, models.Number(6), patched.Number)
+		assert.Equal(t, "Central Florida", patched.College)
+
+		player, err := ps.GetPlayer(ctx, 1, false)
+		assert.Nil(t, err)
+		assert.Equal(t, models.NewDate(1992, time.April, 29), player.BirthDate)
+		assert.Equal(t, models.Height(77), player.Height)
+		assert.Equal(t, 3, player.Version)
+
+		_, _, err = ps.SavePlayer(ctx, &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+		assert.Nil(t, err)
+
+		filter := models.PlayerFilter{Positions: []string{"CB", "QB"}, Sort: models.ParseSort("-number")}
+		list, next, err := ps.ListPlayers(ctx, filter, models.PageRequest{Size: 1})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(list))
+		assert.Equal(t, "Jalen Ramsey", list[0].Name)
+		list, next, err = ps.ListPlayers(ctx, filter, models.PageRequest{Size: 1, Token: next})
+		assert.Nil(t, err)
+		assert.Equal(t, "Blake Bortles", list[0].Name)
+		assert.Equal(t, "", next)
+
+		results, err := ps.SearchPlayers(ctx, "ramsey", 0)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(results))
+		assert.Equal(t, 2, results[0].Player.ID)
+
+		assert.Nil(t, ps.DeletePlayer(ctx, 2, 0))
+		_, err = ps.GetPlayer(ctx, 2, false)
+		assert.Equal(t, players.KindNotFound, players.KindOf(err))
+		assert.Equal(t, "not found", err.Error())
+		deleted, err := ps.GetPlayer(ctx, 2, true)
+		assert.Nil(t, err)
+		assert.NotNil(t, deleted.DeletedAt)
+		restored, err := ps.RestorePlayer(ctx, 2, 0)
+		assert.Nil(t, err)
+		assert.Nil(t, restored.DeletedAt)
+
+		history, err := ps.GetPlayerHistory(ctx, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(history))
+		assert.Equal(t, "tcoughlin", history[0].Actor)
+
+		var names []string
+		err = ps.ExportPlayers(ctx, models.PlayerFilter{}, func(p *models.Player) error {
+			names = append(names, p.Name)
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"Blake Bortles", "Jalen Ramsey"}, names)
+
+		err = ps.ExportPlayers(ctx, models.PlayerFilter{Sort: models.ParseSort("salary")}, func(*models.Player) error {
+			return nil
+		})
+		assert.Equal(t, players.KindInvalidArgument, players.KindOf(err))
+	})
+}
+
+func TestContractShouldReturnValidationErrors(t *testing.T) {
+	testContract(t, func(t *testing.T, ps players.Service) {
+		_, _, err := ps.SavePlayer(context.Background(), &models.Player{Number: 100})
+		ve, ok := err.(*players.ValidationError)
+		assert.True(t, ok)
+		assert.Equal(t, "name", ve.Fields[0].Field)
+		assert.True(t, strings.HasPrefix(err.Error(), "invalid player: name "))
+		assert.Equal(t, players.KindInvalidArgument, players.KindOf(err))
+	})
+}
+
+func TestContractShouldImportRows(t *testing.T) {
+	testContract(t, func(t *testing.T, ps players.Service) {
+		rows, err := models.ParseImport(strings.NewReader("name,number,position\nBlake Bortles,5,QB\nJalen Ramsey,x,CB\nCody Kessler,6,EDGE\n"), time.Now())
+		assert.Nil(t, err)
+		results, err := ps.ImportPlayers(context.Background(), rows)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(results))
+		assert.Equal(t, models.ImportCreated, results[0].Status)
+		assert.Equal(t, 2, results[1].Row)
+		assert.Equal(t, models.ImportRejected, results[1].Status)
+		assert.Equal(t, []string{`number: invalid number "x"`}, results[1].Errors)
+		assert.Equal(t, 3, results[2].Row)
+		assert.Equal(t, models.ImportRejected, results[2].Status)
+	})
+}
+
+func TestContractShouldImportOnlyTheGivenColumns(t *testing.T) {
+	testContract(t, func(t *testing.T, ps players.Service) {
+		ctx := context.Background()
+		saved, _, err := ps.SavePlayer(ctx, &models.Player{Name: "Blake Bortles", Number: 5, Position: "QB", College: "UCF"})
+		assert.Nil(t, err)
+
+		rows, err := models.ParseImport(strings.NewReader("name,number\nBlake Bortles,9\n"), time.Now())
+		assert.Nil(t, err)
+		results, err := ps.ImportPlayers(ctx, rows)
+		assert.Nil(t, err)
+		assert.Equal(t, models.ImportUpdated, results[0].Status)
+
+		player, err := ps.GetPlayer(ctx, saved.ID, false)
+		assert.Nil(t, err)
+		assert.Equal(t, models.Number(9), player.Number)
+		assert.Equal(t, "QB", player.Position)
+		assert.Equal(t, "UCF", player.College)
+	})
+}
+
+func TestContractShouldImportRowsSettingDifferentFields(t *testing.T) {
+	testContract(t, func(t *testing.T, ps players.Service) {
+		ctx := context.Background()
+		_, _, err := ps.SavePlayer(ctx, &models.Player{Name: "Blake Bortles", Number: 5, Position: "QB", College: "UCF"})
+		assert.Nil(t, err)
+
+		results, err := ps.ImportPlayers(ctx, importRowsSettingDifferentFields())
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(results))
+		assert.Equal(t, 1, results[0].Row)
+		assert.Equal(t, models.ImportUpdated, results[0].Status)
+		assert.Equal(t, 2, results[1].Row)
+		assert.Equal(t, models.ImportRejected, results[1].Status)
+		assert.Equal(t, []string{`number: invalid number "x"`}, results[1].Errors)
+		assert.Equal(t, 3, results[2].Row)
+		assert.Equal(t, models.ImportCreated, results[2].Status)
+
+		player, err := ps.GetPlayer(ctx, results[0].ID, false)
+		assert.Nil(t, err)
+		assert.Equal(t, models.Number(9), player.Number)
+		assert.Equal(t, "UCF", player.College)
+		player, err = ps.GetPlayer(ctx, results[2].ID, false)
+		assert.Nil(t, err)
+		assert.Equal(t, "Florida State", player.College)
+	})
+}
+
+// importRowsSettingDifferentFields returns an update of one field, a row
+// that failed to parse, and a new player setting every field
+func importRowsSettingDifferentFields() []models.ImportRow {
+	return []models.ImportRow{
+		{Row: 1, Player: models.Player{Name: "Blake Bortles", Number: 9}, Fields: []string{"name", "number"}},
+		{Row: 2, Player: models.Player{Name: "Jalen Ramsey"}, Errors: []string{`number: invalid number "x"`}},
+		{Row: 3, Player: models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB", College: "Florida State"}},
+	}
+}
+
+func TestGRPCClientShouldRetryUnavailableService(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := lis.Addr().String()
+	assert.Nil(t, lis.Close())
+
+	ps, err := DialGRPC(addr, Retries(1), Backoff(time.Millisecond))
+	assert.Nil(t, err)
+	defer ps.Close()
+	_, err = ps.GetPlayer(context.Background(), 1, false)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, players.KindInternal, players.KindOf(err))
+
+	lis, err = net.Listen("tcp", addr)
+	assert.Nil(t, err)
+	srv := grpc.NewServer()
+	pb.RegisterPlayersServer(srv, players.NewGRPCTransport(players.NewEndpoints(newTestService()), log.NewNopLogger()))
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	ps, err = DialGRPC(addr, Retries(5), Backoff(10*time.Millisecond))
+	assert.Nil(t, err)
+	defer ps.Close()
+	_, err = ps.GetPlayer(context.Background(), 1, false)
+	assert.Equal(t, players.KindNotFound, players.KindOf(err))
+}
+
+func TestGRPCClientShouldTimeOut(t *testing.T) {
+	addr, stop := newTestGRPCServer(t)
+	defer stop()
+
+	ps, err := DialGRPC(addr, Timeout(time.Nanosecond))
+	assert.Nil(t, err)
+	defer ps.Close()
+	_, err = ps.GetPlayer(context.Background(), 1, false)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+
+	"github.com/hoop33/roster/players"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCClient is a players service that calls a remote one over gRPC
+type GRPCClient struct {
+	players.Service
+	conn *grpc.ClientConn
+}
+
+// Close closes the connection to the service
+func (g *GRPCClient) Close() error {
+	return g.conn.Close()
+}
+
+// DialGRPC returns a players service that calls the gRPC transport at
+// target, e.g., "localhost:9091". It connects in the background, so an
+// unreachable service is reported by calls rather than here.
+func DialGRPC(target string, options ...Option) (*GRPCClient, error) {
+	c := newClient(options)
+
+	dialOptions := []grpc.DialOption{
+		grpc.WithUnaryInterceptor(c.intercept),
+	}
+	if c.tlsConfig != nil {
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(credentials.NewTLS(c.tlsConfig)))
+	} else {
+		dialOptions = append(dialOptions, grpc.WithInsecure())
+	}
+	if c.keepalive > 0 {
+		dialOptions = append(dialOptions, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                c.keepalive,
+			Timeout:             c.keepalive,
+			PermitWithoutStream: true,
+		}))
+	}
+
+	conn, err := grpc.Dial(target, dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCClient{
+		Service: players.NewGRPCClient(conn),
+		conn:    conn,
+	}, nil
+}
+
+// intercept gives each attempt at a call its own deadline, and retries
+// calls the service was unavailable for. Exports list a page per call, so
+// the deadline doesn't limit a whole export.
+func (c *client) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return c.withRetries(ctx, func() error {
+		attemptCtx := ctx
+		if c.timeout > 0 {
+			var cancel context.CancelFunc
+			attemptCtx, cancel = context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+		}
+		return invoker(attemptCtx, method, req, reply, cc, opts...)
+	}, func(err error) bool {
+		return status.Code(err) == codes.Unavailable
+	})
+}
@@ -0,0 +1,602 @@
+package players
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/pb"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+// The media types the HTTP transport reads and writes
+const (
+	mediaJSON     = "application/json"
+	mediaYAML     = "application/yaml"
+	mediaXML      = "application/xml"
+	mediaCSV      = "text/csv"
+	mediaProtobuf = "application/x-protobuf"
+	mediaTSV      = "text/tab-separated-values"
+	mediaJSONL    = "application/x-ndjson"
+)
+
+// mediaAliases maps other names in common use to the media types above
+var mediaAliases = map[string]string{
+	"application/x-yaml":              mediaYAML,
+	"text/yaml":                       mediaYAML,
+	"text/x-yaml":                     mediaYAML,
+	"text/xml":                        mediaXML,
+	"application/csv":                 mediaCSV,
+	"application/protobuf":            mediaProtobuf,
+	"application/vnd.google.protobuf": mediaProtobuf,
+}
+
+// responseMediaTypes are the media types every route but export responds
+// in, most preferred first
+var responseMediaTypes = []string{mediaJSON, mediaYAML, mediaXML, mediaCSV, mediaProtobuf}
+
+var errNotAcceptable = newError(KindNotAcceptable, "none of the accepted media types can be produced")
+var errUnsupportedMediaType = newError(KindUnsupportedMediaType, "unsupported media type")
+
+type contentContextKey int
+
+const contextKeyMediaType contentContextKey = iota
+
+// negotiate picks the offered media type a request's Accept header likes
+// best, passing it to the handler in the request context, and responds
+// 406 Not Acceptable when it likes none of them
+func negotiate(offers ...string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			mt := bestMediaType(r.Header.Get("Accept"), offers)
+			if mt == "" {
+				encodeHTTPError(r.Context(), errNotAcceptable, w)
+				return
+			}
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKeyMediaType, mt)))
+		})
+	}
+}
+
+// bestMediaType returns the offer with the highest quality in an Accept
+// header, taking each offer's quality from the most specific range that
+// matches it; ties go to the earlier offer. A missing header accepts the
+// first offer, and "" means none are acceptable.
+func bestMediaType(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if alias, ok := mediaAliases[mt]; ok {
+			mt = alias
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mt, q: q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, 0
+		for _, r := range ranges {
+			s := 0
+			switch {
+			case r.mediaType == offer:
+				s = 3
+			case strings.HasSuffix(r.mediaType, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(r.mediaType, "*")):
+				s = 2
+			case r.mediaType == "*/*":
+				s = 1
+			}
+			if s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// responseMediaType returns the media type negotiated for a request,
+// defaulting to JSON
+func responseMediaType(ctx context.Context) string {
+	if mt, _ := ctx.Value(contextKeyMediaType).(string); mt != "" {
+		return mt
+	}
+	return mediaJSON
+}
+
+// requestMediaType returns the media type of a request's body, treating
+// a missing Content-Type as JSON
+func requestMediaType(r *http.Request) string {
+	mt := mediaType(r.Header.Get("Content-Type"))
+	if mt == "" {
+		return mediaJSON
+	}
+	if alias, ok := mediaAliases[mt]; ok {
+		return alias
+	}
+	return mt
+}
+
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mt
+}
+
+func contentType(mt string) string {
+	if mt == mediaProtobuf {
+		return mt
+	}
+	return mt + "; charset=utf-8"
+}
+
+// marshalResponse encodes a response in a media type. YAML and XML are
+// derived from the JSON form, so all three carry the same fields.
+func marshalResponse(ctx context.Context, mt string, response interface{}) ([]byte, error) {
+	switch mt {
+	case mediaCSV:
+		return marshalCSV(response)
+	case mediaProtobuf:
+		m, err := protoResponse(ctx, response)
+		if err != nil {
+			return nil, err
+		}
+		return proto.Marshal(m)
+	}
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	switch mt {
+	case mediaYAML:
+		return jsonToYAML(b)
+	case mediaXML:
+		return jsonToXML(b)
+	}
+	return append(b, '\n'), nil
+}
+
+// marshalError encodes an error in a media type. Errors have no CSV form,
+// so they're sent as JSON instead; over protobuf they're a google.rpc.Status,
+// as the gRPC transport would return.
+func marshalError(mt string, err error) (string, []byte, error) {
+	switch mt {
+	case mediaProtobuf:
+		b, e := proto.Marshal(status.Convert(grpcError(err)).Proto())
+		return mt, b, e
+	case mediaYAML, mediaXML:
+	default:
+		mt = mediaJSON
+	}
+
+	body := errorResponse{
+		Error: err.Error(),
+	}
+	if ve, ok := err.(*ValidationError); ok {
+		body.Fields = ve.Fields
+	}
+	b, e := marshalResponse(context.Background(), mt, body)
+	return mt, b, e
+}
+
+type errorResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// jsonToYAML converts JSON to YAML, keeping object keys in order
+func jsonToYAML(b []byte) ([]byte, error) {
+	v, err := decodeOrdered(b)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// xmlItemNames name the elements of arrays in XML, after their parent
+var xmlItemNames = map[string]string{
+	"players": "player",
+	"results": "result",
+	"history": "entry",
+	"changes": "change",
+	"fields":  "field",
+	"errors":  "error",
+}
+
+// jsonToXML converts JSON to XML with a root response element. Object
+// keys become elements, arrays repeat an element named after their parent,
+// and nulls are empty elements marked nil="true".
+func jsonToXML(b []byte) ([]byte, error) {
+	v, err := decodeOrdered(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if err := encodeXML(enc, "response", v); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func encodeXML(enc *xml.Encoder, name string, v interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := encodeXML(enc, item.Key.(string), item.Value); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	case []interface{}:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		itemName, ok := xmlItemNames[name]
+		if !ok {
+			itemName = "item"
+		}
+		for _, item := range v {
+			if err := encodeXML(enc, itemName, item); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	case nil:
+		start.Attr = []xml.Attr{{Name: xml.Name{Local: "nil"}, Value: "true"}}
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		return enc.EncodeToken(start.End())
+	}
+	return enc.EncodeElement(fmt.Sprint(v), start)
+}
+
+// decodeOrdered decodes JSON into yaml.MapSlice for objects, so their
+// keys stay in order, []interface{} for arrays, and scalars
+func decodeOrdered(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return decodeOrderedValue(dec)
+}
+
+func decodeOrderedValue(dec *json.Decoder) (interface{}, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := t.(type) {
+	case json.Delim:
+		if t == '{' {
+			object := yaml.MapSlice{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeOrderedValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				object = append(object, yaml.MapItem{Key: key, Value: value})
+			}
+			_, err := dec.Token()
+			return object, err
+		}
+
+		array := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err := dec.Token()
+		return array, err
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n, nil
+		}
+		return t.Float64()
+	}
+	return t, nil
+}
+
+// marshalCSV writes the players, import results, or history in a
+// response as CSV with a header row
+func marshalCSV(response interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch r := response.(type) {
+	case listPlayersResponse:
+		err = writePlayersCSV(&buf, r.Players...)
+	case searchPlayersResponse:
+		players := make([]models.Player, len(r.Results))
+		for i, result := range r.Results {
+			players[i] = result.Player
+		}
+		err = writePlayersCSV(&buf, players...)
+	case getPlayerResponse:
+		err = writePlayersCSV(&buf, *r.Player)
+	case savePlayerResponse:
+		err = writePlayersCSV(&buf, *r.Player)
+	case patchPlayerResponse:
+		err = writePlayersCSV(&buf, *r.Player)
+	case restorePlayerResponse:
+		err = writePlayersCSV(&buf, *r.Player)
+	case importPlayersResponse:
+		err = writeImportCSV(&buf, r.Results)
+	case getHistoryResponse:
+		err = writeHistoryCSV(&buf, r.History)
+	default:
+		return nil, fmt.Errorf("no CSV form for %T", response)
+	}
+	return buf.Bytes(), err
+}
+
+func writePlayersCSV(w io.Writer, players ...models.Player) error {
+	pw, err := models.NewPlayerWriter(w, models.ExportCSV)
+	if err != nil {
+		return err
+	}
+	for i := range players {
+		if err := pw.Write(&players[i]); err != nil {
+			return err
+		}
+	}
+	return pw.Flush()
+}
+
+func writeImportCSV(w io.Writer, results []models.ImportResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"row", "status", "id", "name", "errors"})
+	for _, r := range results {
+		id := ""
+		if r.ID > 0 {
+			id = strconv.Itoa(r.ID)
+		}
+		cw.Write([]string{strconv.Itoa(r.Row), r.Status, id, r.Name, strings.Join(r.Errors, "; ")})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeHistoryCSV writes a row for each changed field, and one for each
+// entry that changed none
+func writeHistoryCSV(w io.Writer, history []models.HistoryEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "player_id", "version", "operation", "actor", "changed_at", "field", "before", "after"})
+	for _, e := range history {
+		entry := []string{strconv.Itoa(e.ID), strconv.Itoa(e.PlayerID), strconv.Itoa(e.Version), e.Operation, e.Actor, formatTimestamp(e.ChangedAt)}
+		if len(e.Changes) == 0 {
+			cw.Write(append(entry, "", "", ""))
+		}
+		for _, c := range e.Changes {
+			cw.Write(append(entry[:6:6], c.Field, jsonValue(c.Before), jsonValue(c.After)))
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// protoResponse converts a response to its protobuf message, the same one
+// the gRPC transport returns
+func protoResponse(ctx context.Context, response interface{}) (proto.Message, error) {
+	var m interface{}
+	var err error
+	switch r := response.(type) {
+	case listPlayersResponse:
+		m, err = encodeGRPCListPlayersResponse(ctx, r)
+	case searchPlayersResponse:
+		m, err = encodeGRPCSearchPlayersResponse(ctx, r)
+	case getPlayerResponse:
+		m, err = encodeGRPCGetPlayerResponse(ctx, r)
+	case savePlayerResponse:
+		m, err = encodeGRPCSavePlayerResponse(ctx, r)
+	case patchPlayerResponse:
+		m, err = encodeGRPCUpdatePlayerResponse(ctx, r)
+	case restorePlayerResponse:
+		m, err = encodeGRPCRestorePlayerResponse(ctx, r)
+	case getHistoryResponse:
+		m, err = encodeGRPCGetPlayerHistoryResponse(ctx, r)
+	case importPlayersResponse:
+		m, err = encodeGRPCImportPlayersResponse(ctx, r)
+	default:
+		return nil, fmt.Errorf("no protobuf form for %T", response)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.(proto.Message), nil
+}
+
+// decodeHTTPPlayer reads a player from a request body in any of the
+// response media types. A CSV body is a header and one row, as in an
+// import.
+func decodeHTTPPlayer(r *http.Request) (models.Player, error) {
+	var player models.Player
+	var err error
+	switch requestMediaType(r) {
+	case mediaJSON:
+		err = json.NewDecoder(r.Body).Decode(&player)
+	case mediaYAML:
+		player, err = decodeYAMLPlayer(r.Body)
+	case mediaXML:
+		var xp xmlPlayer
+		if err = xml.NewDecoder(r.Body).Decode(&xp); err == nil {
+			player, err = xp.player()
+		}
+	case mediaCSV:
+		var rows []models.ImportRow
+		rows, err = models.ParseImport(r.Body, time.Now())
+		if err == nil && (len(rows) != 1 || len(rows[0].Errors) > 0) {
+			err = errBadRequest
+		}
+		if err == nil {
+			player = rows[0].Player
+		}
+	case mediaProtobuf:
+		var b []byte
+		var p pb.Player
+		if b, err = ioutil.ReadAll(r.Body); err == nil {
+			if err = proto.Unmarshal(b, &p); err == nil {
+				player, err = protoPlayerToModelsPlayer(p)
+			}
+		}
+	default:
+		return player, errUnsupportedMediaType
+	}
+	if err != nil {
+		return player, errBadRequest
+	}
+	return player, nil
+}
+
+// decodeProtoImport reads the rows of a pb.ImportPlayersRequest
+func decodeProtoImport(r io.Reader) ([]models.ImportRow, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var req pb.ImportPlayersRequest
+	if err := proto.Unmarshal(b, &req); err != nil {
+		return nil, err
+	}
+	request, err := decodeGRPCImportPlayersRequest(context.Background(), &req)
+	if err != nil {
+		return nil, err
+	}
+	return request.(importPlayersRequest).Rows, nil
+}
+
+// decodeYAMLPlayer reads a player from YAML by way of JSON, so it accepts
+// the same fields and values as a JSON body
+func decodeYAMLPlayer(r io.Reader) (models.Player, error) {
+	var player models.Player
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return player, err
+	}
+
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return player, err
+	}
+	j, err := json.Marshal(jsonCompatible(v))
+	if err != nil {
+		return player, err
+	}
+	err = json.Unmarshal(j, &player)
+	return player, err
+}
+
+// jsonCompatible converts the map[interface{}]interface{} values YAML
+// decodes to map[string]interface{}, which JSON can encode
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = jsonCompatible(value)
+		}
+	}
+	return v
+}
+
+// xmlPlayer is a player as XML, with the elements and values of the JSON form
+type xmlPlayer struct {
+	ID         int    `xml:"id"`
+	Name       string `xml:"name"`
+	Number     string `xml:"number"`
+	Position   string `xml:"position"`
+	Height     string `xml:"height"`
+	Weight     string `xml:"weight"`
+	BirthDate  string `xml:"birth_date"`
+	Experience int    `xml:"experience"`
+	College    string `xml:"college"`
+	Version    int    `xml:"version"`
+}
+
+func (x xmlPlayer) player() (models.Player, error) {
+	number, err := models.ParseNumber(x.Number)
+	if err != nil {
+		return models.Player{}, err
+	}
+	height, err := models.ParseHeight(x.Height)
+	if err != nil {
+		return models.Player{}, err
+	}
+	weight, err := models.ParseWeight(x.Weight)
+	if err != nil {
+		return models.Player{}, err
+	}
+	birthDate, err := models.ParseDate(x.BirthDate)
+	if err != nil {
+		return models.Player{}, err
+	}
+
+	return models.Player{
+		ID:         x.ID,
+		Name:       x.Name,
+		Number:     number,
+		Position:   x.Position,
+		Height:     height,
+		Weight:     weight,
+		BirthDate:  birthDate,
+		Experience: x.Experience,
+		College:    x.College,
+		Version:    x.Version,
+	}, nil
+}
@@ -0,0 +1,292 @@
+package players
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/protobuf/proto"
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/pb"
+	"github.com/stretchr/testify/assert"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+func TestBestMediaTypeShouldHonorQualityAndSpecificity(t *testing.T) {
+	for accept, expected := range map[string]string{
+		"":                                      mediaJSON,
+		"*/*":                                   mediaJSON,
+		"application/yaml":                      mediaYAML,
+		"text/yaml":                             mediaYAML,
+		"text/xml, application/json;q=0.5":      mediaXML,
+		"application/json;q=0.5, text/*":        mediaCSV,
+		"*/*;q=0.1, application/x-protobuf":     mediaProtobuf,
+		"application/*, application/json;q=0":   mediaYAML,
+		"image/png":                             "",
+		"text/html, application/json;q=invalid": "",
+	} {
+		assert.Equal(t, expected, bestMediaType(accept, responseMediaTypes), accept)
+	}
+}
+
+func newContentTestHandler(t *testing.T) http.Handler {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{
+		Name:       "Blake Bortles",
+		Number:     5,
+		Position:   "QB",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
+		Experience: 5,
+		College:    "Central Florida",
+	})
+	assert.Nil(t, err)
+	return NewHTTPTransport(NewEndpoints(NewValidatingService(NewService(repo))), log.NewNopLogger())
+}
+
+func serveContent(handler http.Handler, method, path, accept, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestHTTPShouldRespondInAcceptedMediaType(t *testing.T) {
+	handler := newContentTestHandler(t)
+
+	resp := serveContent(handler, "GET", "/v1/players/1", "application/yaml", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/yaml; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", resp.Header().Get("Vary"))
+	assert.True(t, strings.HasPrefix(resp.Body.String(), "player:\n  id: 1\n  name: Blake Bortles\n  number: \"5\"\n"), resp.Body.String())
+
+	resp = serveContent(handler, "GET", "/v1/players", "text/xml", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", resp.Header().Get("Content-Type"))
+	var list struct {
+		Players []struct {
+			Name   string `xml:"name"`
+			Height string `xml:"height"`
+		} `xml:"players>player"`
+	}
+	assert.Nil(t, xml.Unmarshal(resp.Body.Bytes(), &list))
+	assert.Equal(t, 1, len(list.Players))
+	assert.Equal(t, "6-5", list.Players[0].Height)
+
+	resp = serveContent(handler, "GET", "/v1/players/1", "text/csv", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `id,name,number,position,height,weight,birth_date,experience,college
+1,Blake Bortles,5,QB,6-5,236,1992-04-29,5,Central Florida
+`, resp.Body.String())
+
+	resp = serveContent(handler, "GET", "/v1/players/1", "application/x-protobuf", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/x-protobuf", resp.Header().Get("Content-Type"))
+	var gpr pb.GetPlayerResponse
+	assert.Nil(t, proto.Unmarshal(resp.Body.Bytes(), &gpr))
+	assert.Equal(t, "Blake Bortles", gpr.Player.Name)
+	assert.Equal(t, int32(77), gpr.Player.HeightInches)
+
+	resp = serveContent(handler, "GET", "/v1/players/1/history", "text/csv", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+	assert.Equal(t, "id,player_id,version,operation,actor,changed_at,field,before,after", lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], `,name,null,"""Blake Bortles"""`), lines[1])
+}
+
+func TestHTTPShouldReturnNotAcceptableWhenNoMediaTypeMatches(t *testing.T) {
+	handler := newContentTestHandler(t)
+
+	resp := serveContent(handler, "GET", "/v1/players/1", "image/png", "", "")
+	assert.Equal(t, http.StatusNotAcceptable, resp.Code)
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header().Get("Content-Type"))
+
+	resp = serveContent(handler, "GET", "/v1/players/export", "application/json", "", "")
+	assert.Equal(t, http.StatusNotAcceptable, resp.Code)
+
+	resp = serveContent(handler, "GET", "/v1/players/export", "text/tab-separated-values", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.True(t, strings.HasPrefix(resp.Body.String(), "id\tname\t"))
+}
+
+func TestHTTPExportShouldPreferFormatOverAccept(t *testing.T) {
+	handler := newContentTestHandler(t)
+
+	resp := serveContent(handler, "GET", "/v1/players/export?format=csv", "application/json", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header().Get("Content-Type"))
+
+	resp = serveContent(handler, "GET", "/v1/players/export?format=jsonl", "text/csv", "", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/x-ndjson; charset=utf-8", resp.Header().Get("Content-Type"))
+}
+
+func TestHTTPShouldTagEachMediaTypeDifferently(t *testing.T) {
+	handler := newContentTestHandler(t)
+
+	tags := map[string]bool{}
+	for accept, tag := range map[string]string{
+		"application/json":       `"1"`,
+		"application/yaml":       `"1-yaml"`,
+		"application/xml":        `"1-xml"`,
+		"text/csv":               `"1-csv"`,
+		"application/x-protobuf": `"1-protobuf"`,
+	} {
+		resp := serveContent(handler, "GET", "/v1/players/1", accept, "", "")
+		assert.Equal(t, tag, resp.Header().Get("ETag"), accept)
+		tags[tag] = true
+
+		req := httptest.NewRequest("GET", "/v1/players/1", nil)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("If-None-Match", tag)
+		resp = httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, tag == `"1"`, resp.Code == http.StatusNotModified, accept)
+	}
+	assert.Equal(t, 5, len(tags))
+
+	req := httptest.NewRequest("PATCH", "/v1/players/1", strings.NewReader(`{"number":9}`))
+	req.Header.Set("Accept", "application/yaml")
+	req.Header.Set("If-Match", `"1-yaml"`)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"2-yaml"`, resp.Header().Get("ETag"))
+}
+
+func TestHTTPShouldReadPlayerInEachMediaType(t *testing.T) {
+	handler := newContentTestHandler(t)
+
+	protoBody, err := proto.Marshal(&pb.Player{Name: "Dede Westbrook", JerseyNumber: 12, Position: "WR", HeightInches: 72})
+	assert.Nil(t, err)
+
+	for contentType, body := range map[string]string{
+		"application/json":       `{"name":"Jalen Ramsey","number":20,"position":"CB","height":"6-1"}`,
+		"application/x-yaml":     "name: Telvin Smith\nnumber: 50\nposition: LB\nheight: 6-3\nbirth_date: 1991-04-12\n",
+		"application/xml":        "<player><name>Yannick Ngakoue</name><number>91</number><position>DE</position><height>6-2</height><experience>2</experience></player>",
+		"text/csv":               "name,number,position,height,age\nCalais Campbell,93,DE,6-8,32\n",
+		"application/x-protobuf": string(protoBody),
+	} {
+		resp := serveContent(handler, "POST", "/v1/players", "application/yaml", contentType, body)
+		assert.Equal(t, http.StatusCreated, resp.Code, contentType)
+
+		var created struct {
+			Player struct {
+				ID     int    `yaml:"id"`
+				Height string `yaml:"height"`
+			} `yaml:"player"`
+		}
+		assert.Nil(t, yaml.Unmarshal(resp.Body.Bytes(), &created), contentType)
+		assert.True(t, created.Player.ID > 1, contentType)
+		assert.NotEqual(t, "", created.Player.Height, contentType)
+	}
+
+	resp := serveContent(handler, "PUT", "/v1/players/1", "", "text/csv", "id,name,number,position\n1,Blake Bortles,6,QB\n")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.True(t, strings.Contains(resp.Body.String(), `"number":"6"`))
+}
+
+func TestHTTPShouldRejectUnsupportedOrMalformedBodies(t *testing.T) {
+	handler := newContentTestHandler(t)
+
+	resp := serveContent(handler, "POST", "/v1/players", "", "text/plain", "Jalen Ramsey")
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+
+	for contentType, body := range map[string]string{
+		"application/yaml": "name: [",
+		"application/xml":  "<player><number>twenty</number></player>",
+		"text/csv":         "name\nJalen Ramsey\nTelvin Smith\n",
+	} {
+		resp = serveContent(handler, "POST", "/v1/players", "", contentType, body)
+		assert.Equal(t, http.StatusBadRequest, resp.Code, contentType)
+	}
+}
+
+func TestHTTPShouldWriteErrorsInAcceptedMediaType(t *testing.T) {
+	handler := newContentTestHandler(t)
+
+	resp := serveContent(handler, "POST", "/v1/players", "application/xml", "", `{"name":"","number":100}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	var body struct {
+		Error  string `xml:"error"`
+		Fields []struct {
+			Field string `xml:"field"`
+		} `xml:"fields>field"`
+	}
+	assert.Nil(t, xml.Unmarshal(resp.Body.Bytes(), &body))
+	assert.True(t, len(body.Fields) > 1)
+	assert.Equal(t, "name", body.Fields[0].Field)
+
+	resp = serveContent(handler, "GET", "/v1/players/2", "application/x-protobuf", "", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	var st spb.Status
+	b, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Nil(t, proto.Unmarshal(b, &st))
+	assert.Equal(t, "not found", st.Message)
+
+	resp = serveContent(handler, "GET", "/v1/players/2", "text/csv", "", "")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header().Get("Content-Type"))
+}
+
+func TestHTTPImportPlayersShouldReportInProtobuf(t *testing.T) {
+	handler := newContentTestHandler(t)
+
+	resp := serveContent(handler, "POST", "/v1/players:import", "application/x-protobuf", "text/csv", "name,number,position\nBlake Bortles,6,QB\nJalen Ramsey,x,CB\n")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var ipr pb.ImportPlayersResponse
+	assert.Nil(t, proto.Unmarshal(resp.Body.Bytes(), &ipr))
+	assert.Equal(t, int32(1), ipr.Summary.Updated)
+	assert.Equal(t, int32(1), ipr.Summary.Rejected)
+	assert.Equal(t, []string{`number: invalid number "x"`}, ipr.Results[1].Errors)
+}
+
+func TestHTTPImportPlayersShouldReadProtobufRows(t *testing.T) {
+	handler := newContentTestHandler(t)
+
+	b, err := proto.Marshal(&pb.ImportPlayersRequest{Rows: []*pb.ImportRow{
+		{Row: 1, Player: &pb.Player{Name: "Blake Bortles", JerseyNumber: 6}, Fields: []string{"name", "number"}},
+		{Row: 2, Player: &pb.Player{Name: "Jalen Ramsey"}, Errors: []string{"number: invalid number"}},
+		{Row: 3, Player: &pb.Player{Name: "Jalen Ramsey", JerseyNumber: 20, Position: "CB"}},
+	}})
+	assert.Nil(t, err)
+	resp := serveContent(handler, "POST", "/v1/players:import", "", "application/x-protobuf", string(b))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var body importPlayersResponse
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, models.ImportSummary{Created: 1, Updated: 1, Rejected: 1}, body.Summary)
+	assert.Equal(t, []string{"number: invalid number"}, body.Results[1].Errors)
+
+	resp = serveContent(handler, "GET", "/v1/players/1", "", "", "")
+	assert.True(t, strings.Contains(resp.Body.String(), `"college":"Central Florida"`), resp.Body.String())
+
+	resp = serveContent(handler, "POST", "/v1/players:import", "", "application/x-protobuf", "not protobuf")
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestJSONToXMLShouldKeepOrderAndMarkNulls(t *testing.T) {
+	b, err := jsonToXML([]byte(`{"b":1,"a":[{"x":null}],"history":[true]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, xml.Header+`<response><b>1</b><a><item><x nil="true"></x></item></a><history><entry>true</entry></history></response>`+"\n", string(b))
+
+	b, err = jsonToYAML([]byte(`{"b":1.5,"a":"x"}`))
+	assert.Nil(t, err)
+	assert.True(t, bytes.Equal([]byte("b: 1.5\na: x\n"), b))
+}
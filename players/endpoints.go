@@ -7,30 +7,64 @@ import (
 	"github.com/hoop33/roster/models"
 )
 
-// Endpoints contains all the endpoints for the players service
+// Endpoints contains all the endpoints for the players service. It
+// implements Service by calling them, so endpoints that call a remote
+// service can stand in for a local one.
 type Endpoints struct {
-	listPlayersEndpoint  endpoint.Endpoint
-	getPlayerEndpoint    endpoint.Endpoint
-	savePlayerEndpoint   endpoint.Endpoint
-	deletePlayerEndpoint endpoint.Endpoint
+	listPlayersEndpoint   endpoint.Endpoint
+	exportPlayersEndpoint endpoint.Endpoint
+	searchPlayersEndpoint endpoint.Endpoint
+	getPlayerEndpoint     endpoint.Endpoint
+	savePlayerEndpoint    endpoint.Endpoint
+	patchPlayerEndpoint   endpoint.Endpoint
+	deletePlayerEndpoint  endpoint.Endpoint
+	restorePlayerEndpoint endpoint.Endpoint
+	importPlayersEndpoint endpoint.Endpoint
+	getHistoryEndpoint    endpoint.Endpoint
 }
 
 type listPlayersRequest struct {
-	Position string `json:"position,omitempty"`
+	Filter    models.PlayerFilter `json:"filter"`
+	PageSize  int                 `json:"page_size,omitempty"`
+	PageToken string              `json:"page_token,omitempty"`
 }
 
 type listPlayersResponse struct {
-	Players []models.Player `json:"players,omitempty"`
-	Err     string          `json:"error,omitempty"`
+	Players       []models.Player `json:"players,omitempty"`
+	NextPageToken string          `json:"next_page_token,omitempty"`
+	Err           error           `json:"-"`
+}
+
+type exportPlayersRequest struct {
+	Filter models.PlayerFilter `json:"filter"`
+	Format string              `json:"format,omitempty"`
+}
+
+// exportPlayersResponse defers the export until the transport is ready to
+// write players, so they can be streamed rather than held in memory
+type exportPlayersResponse struct {
+	Format string
+	Export func(func(*models.Player) error) error
+}
+
+type searchPlayersRequest struct {
+	Query string `json:"q,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+type searchPlayersResponse struct {
+	Results []models.SearchResult `json:"results"`
+	Err     error                 `json:"-"`
 }
 
 type getPlayerRequest struct {
-	ID int `json:"id,omitempty"`
+	ID             int  `json:"id,omitempty"`
+	IncludeDeleted bool `json:"include_deleted,omitempty"`
 }
 
 type getPlayerResponse struct {
 	Player *models.Player `json:"player,omitempty"`
-	Err    string         `json:"error,omitempty"`
+	Err    error          `json:"-"`
 }
 
 type savePlayerRequest struct {
@@ -40,38 +74,256 @@ type savePlayerRequest struct {
 type savePlayerResponse struct {
 	Player  *models.Player `json:"player,omitempty"`
 	Created bool           `json:"created,omitempty"`
-	Err     string         `json:"error,omitempty"`
+	Err     error          `json:"-"`
+}
+
+type patchPlayerRequest struct {
+	ID    int                 `json:"id,omitempty"`
+	Patch *models.PlayerPatch `json:"patch,omitempty"`
+}
+
+type patchPlayerResponse struct {
+	Player *models.Player `json:"player,omitempty"`
+	Err    error          `json:"-"`
 }
 
 type deletePlayerRequest struct {
-	ID int `json:"id,omitempty"`
+	ID      int `json:"id,omitempty"`
+	Version int `json:"version,omitempty"`
 }
 
 type deletePlayerResponse struct {
-	Err string `json:"error,omitempty"`
+	Err error `json:"-"`
+}
+
+type restorePlayerRequest struct {
+	ID      int `json:"id,omitempty"`
+	Version int `json:"version,omitempty"`
+}
+
+type restorePlayerResponse struct {
+	Player *models.Player `json:"player,omitempty"`
+	Err    error          `json:"-"`
+}
+
+type importPlayersRequest struct {
+	Rows []models.ImportRow `json:"rows"`
+}
+
+type importPlayersResponse struct {
+	Summary models.ImportSummary  `json:"summary"`
+	Results []models.ImportResult `json:"results"`
+	Err     error                 `json:"-"`
+}
+
+type getHistoryRequest struct {
+	ID int `json:"id,omitempty"`
+}
+
+type getHistoryResponse struct {
+	History []models.HistoryEntry `json:"history"`
+	Err     error                 `json:"-"`
 }
 
 // NewEndpoints creates the endpoints
 func NewEndpoints(s Service) *Endpoints {
 	return &Endpoints{
-		listPlayersEndpoint:  makeListPlayersEndpoint(s),
-		getPlayerEndpoint:    makeGetPlayerEndpoint(s),
-		savePlayerEndpoint:   makeSavePlayerEndpoint(s),
-		deletePlayerEndpoint: makeDeletePlayerEndpoint(s),
+		listPlayersEndpoint:   makeListPlayersEndpoint(s),
+		exportPlayersEndpoint: makeExportPlayersEndpoint(s),
+		searchPlayersEndpoint: makeSearchPlayersEndpoint(s),
+		getPlayerEndpoint:     makeGetPlayerEndpoint(s),
+		savePlayerEndpoint:    makeSavePlayerEndpoint(s),
+		patchPlayerEndpoint:   makePatchPlayerEndpoint(s),
+		deletePlayerEndpoint:  makeDeletePlayerEndpoint(s),
+		restorePlayerEndpoint: makeRestorePlayerEndpoint(s),
+		importPlayersEndpoint: makeImportPlayersEndpoint(s),
+		getHistoryEndpoint:    makeGetHistoryEndpoint(s),
+	}
+}
+
+// Wrap returns the endpoints with a middleware applied to each
+func (e *Endpoints) Wrap(m endpoint.Middleware) *Endpoints {
+	return &Endpoints{
+		listPlayersEndpoint:   m(e.listPlayersEndpoint),
+		exportPlayersEndpoint: m(e.exportPlayersEndpoint),
+		searchPlayersEndpoint: m(e.searchPlayersEndpoint),
+		getPlayerEndpoint:     m(e.getPlayerEndpoint),
+		savePlayerEndpoint:    m(e.savePlayerEndpoint),
+		patchPlayerEndpoint:   m(e.patchPlayerEndpoint),
+		deletePlayerEndpoint:  m(e.deletePlayerEndpoint),
+		restorePlayerEndpoint: m(e.restorePlayerEndpoint),
+		importPlayersEndpoint: m(e.importPlayersEndpoint),
+		getHistoryEndpoint:    m(e.getHistoryEndpoint),
+	}
+}
+
+// ListPlayers calls the list players endpoint
+func (e *Endpoints) ListPlayers(ctx context.Context, filter models.PlayerFilter, page models.PageRequest) ([]models.Player, string, error) {
+	response, err := e.listPlayersEndpoint(ctx, listPlayersRequest{
+		Filter:    filter,
+		PageSize:  page.Size,
+		PageToken: page.Token,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	resp := response.(listPlayersResponse)
+	return resp.Players, resp.NextPageToken, resp.Err
+}
+
+// ExportPlayers calls the export players endpoint
+func (e *Endpoints) ExportPlayers(ctx context.Context, filter models.PlayerFilter, fn func(*models.Player) error) error {
+	response, err := e.exportPlayersEndpoint(ctx, exportPlayersRequest{
+		Filter: filter,
+	})
+	if err != nil {
+		return err
+	}
+	return response.(exportPlayersResponse).Export(fn)
+}
+
+// SearchPlayers calls the search players endpoint
+func (e *Endpoints) SearchPlayers(ctx context.Context, query string, limit int) ([]models.SearchResult, error) {
+	response, err := e.searchPlayersEndpoint(ctx, searchPlayersRequest{
+		Query: query,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := response.(searchPlayersResponse)
+	return resp.Results, resp.Err
+}
+
+// GetPlayer calls the get player endpoint
+func (e *Endpoints) GetPlayer(ctx context.Context, id int, includeDeleted bool) (*models.Player, error) {
+	response, err := e.getPlayerEndpoint(ctx, getPlayerRequest{
+		ID:             id,
+		IncludeDeleted: includeDeleted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := response.(getPlayerResponse)
+	return resp.Player, resp.Err
+}
+
+// SavePlayer calls the save player endpoint
+func (e *Endpoints) SavePlayer(ctx context.Context, player *models.Player) (*models.Player, bool, error) {
+	response, err := e.savePlayerEndpoint(ctx, savePlayerRequest{
+		Player: player,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	resp := response.(savePlayerResponse)
+	return resp.Player, resp.Created, resp.Err
+}
+
+// PatchPlayer calls the patch player endpoint
+func (e *Endpoints) PatchPlayer(ctx context.Context, id int, patch *models.PlayerPatch) (*models.Player, error) {
+	response, err := e.patchPlayerEndpoint(ctx, patchPlayerRequest{
+		ID:    id,
+		Patch: patch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := response.(patchPlayerResponse)
+	return resp.Player, resp.Err
+}
+
+// DeletePlayer calls the delete player endpoint
+func (e *Endpoints) DeletePlayer(ctx context.Context, id int, version int) error {
+	response, err := e.deletePlayerEndpoint(ctx, deletePlayerRequest{
+		ID:      id,
+		Version: version,
+	})
+	if err != nil {
+		return err
+	}
+	return response.(deletePlayerResponse).Err
+}
+
+// RestorePlayer calls the restore player endpoint
+func (e *Endpoints) RestorePlayer(ctx context.Context, id int, version int) (*models.Player, error) {
+	response, err := e.restorePlayerEndpoint(ctx, restorePlayerRequest{
+		ID:      id,
+		Version: version,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := response.(restorePlayerResponse)
+	return resp.Player, resp.Err
+}
+
+// ImportPlayers calls the import players endpoint
+func (e *Endpoints) ImportPlayers(ctx context.Context, rows []models.ImportRow) ([]models.ImportResult, error) {
+	response, err := e.importPlayersEndpoint(ctx, importPlayersRequest{
+		Rows: rows,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := response.(importPlayersResponse)
+	return resp.Results, resp.Err
+}
+
+// GetPlayerHistory calls the get history endpoint
+func (e *Endpoints) GetPlayerHistory(ctx context.Context, id int) ([]models.HistoryEntry, error) {
+	response, err := e.getHistoryEndpoint(ctx, getHistoryRequest{
+		ID: id,
+	})
+	if err != nil {
+		return nil, err
 	}
+	resp := response.(getHistoryResponse)
+	return resp.History, resp.Err
 }
 
 func makeListPlayersEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(listPlayersRequest)
-		players, err := s.ListPlayers(ctx, req.Position)
+		players, next, err := s.ListPlayers(ctx, req.Filter, models.PageRequest{
+			Size:  req.PageSize,
+			Token: req.PageToken,
+		})
 		if err != nil {
 			return listPlayersResponse{
-				Err: err.Error(),
+				Err: err,
 			}, nil
 		}
 		return listPlayersResponse{
-			Players: players,
+			Players:       players,
+			NextPageToken: next,
+		}, nil
+	}
+}
+
+func makeExportPlayersEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(exportPlayersRequest)
+		return exportPlayersResponse{
+			Format: req.Format,
+			Export: func(fn func(*models.Player) error) error {
+				return s.ExportPlayers(ctx, req.Filter, fn)
+			},
+		}, nil
+	}
+}
+
+func makeSearchPlayersEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(searchPlayersRequest)
+		results, err := s.SearchPlayers(ctx, req.Query, req.Limit)
+		if err != nil {
+			return searchPlayersResponse{
+				Err: err,
+			}, nil
+		}
+		return searchPlayersResponse{
+			Results: results,
 		}, nil
 	}
 }
@@ -79,10 +331,10 @@ func makeListPlayersEndpoint(s Service) endpoint.Endpoint {
 func makeGetPlayerEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(getPlayerRequest)
-		player, err := s.GetPlayer(ctx, req.ID)
+		player, err := s.GetPlayer(ctx, req.ID, req.IncludeDeleted)
 		if err != nil {
 			return getPlayerResponse{
-				Err: err.Error(),
+				Err: err,
 			}, nil
 		}
 		return getPlayerResponse{
@@ -97,7 +349,7 @@ func makeSavePlayerEndpoint(s Service) endpoint.Endpoint {
 		player, created, err := s.SavePlayer(ctx, req.Player)
 		if err != nil {
 			return savePlayerResponse{
-				Err: err.Error(),
+				Err: err,
 			}, nil
 		}
 		return savePlayerResponse{
@@ -107,15 +359,76 @@ func makeSavePlayerEndpoint(s Service) endpoint.Endpoint {
 	}
 }
 
+func makePatchPlayerEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(patchPlayerRequest)
+		player, err := s.PatchPlayer(ctx, req.ID, req.Patch)
+		if err != nil {
+			return patchPlayerResponse{
+				Err: err,
+			}, nil
+		}
+		return patchPlayerResponse{
+			Player: player,
+		}, nil
+	}
+}
+
 func makeDeletePlayerEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(deletePlayerRequest)
-		err := s.DeletePlayer(ctx, req.ID)
+		err := s.DeletePlayer(ctx, req.ID, req.Version)
 		if err != nil {
 			return deletePlayerResponse{
-				Err: err.Error(),
+				Err: err,
 			}, nil
 		}
 		return deletePlayerResponse{}, nil
 	}
 }
+
+func makeRestorePlayerEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(restorePlayerRequest)
+		player, err := s.RestorePlayer(ctx, req.ID, req.Version)
+		if err != nil {
+			return restorePlayerResponse{
+				Err: err,
+			}, nil
+		}
+		return restorePlayerResponse{
+			Player: player,
+		}, nil
+	}
+}
+
+func makeImportPlayersEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(importPlayersRequest)
+		results, err := s.ImportPlayers(ctx, req.Rows)
+		if err != nil {
+			return importPlayersResponse{
+				Err: err,
+			}, nil
+		}
+		return importPlayersResponse{
+			Summary: models.SummarizeImport(results),
+			Results: results,
+		}, nil
+	}
+}
+
+func makeGetHistoryEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getHistoryRequest)
+		history, err := s.GetPlayerHistory(ctx, req.ID)
+		if err != nil {
+			return getHistoryResponse{
+				Err: err,
+			}, nil
+		}
+		return getHistoryResponse{
+			History: history,
+		}, nil
+	}
+}
@@ -13,11 +13,20 @@ var jr = models.Player{Name: "Jalen Ramsey"}
 
 type mockSuccessService struct{}
 
-func (m *mockSuccessService) ListPlayers(context.Context, string) ([]models.Player, error) {
-	return []models.Player{jr}, nil
+func (m *mockSuccessService) ListPlayers(context.Context, models.PlayerFilter, models.PageRequest) ([]models.Player, string, error) {
+	return []models.Player{jr}, "", nil
 }
 
-func (m *mockSuccessService) GetPlayer(context.Context, int) (*models.Player, error) {
+func (m *mockSuccessService) ExportPlayers(_ context.Context, _ models.PlayerFilter, fn func(*models.Player) error) error {
+	p := jr
+	return fn(&p)
+}
+
+func (m *mockSuccessService) SearchPlayers(context.Context, string, int) ([]models.SearchResult, error) {
+	return []models.SearchResult{{Player: jr, Rank: 1, Highlight: "<em>Jalen</em> Ramsey"}}, nil
+}
+
+func (m *mockSuccessService) GetPlayer(context.Context, int, bool) (*models.Player, error) {
 	return &jr, nil
 }
 
@@ -25,19 +34,43 @@ func (m *mockSuccessService) SavePlayer(context.Context, *models.Player) (*model
 	return &jr, false, nil
 }
 
-func (m *mockSuccessService) DeletePlayer(context.Context, int) error {
+func (m *mockSuccessService) PatchPlayer(context.Context, int, *models.PlayerPatch) (*models.Player, error) {
+	return &jr, nil
+}
+
+func (m *mockSuccessService) DeletePlayer(context.Context, int, int) error {
 	return nil
 }
 
+func (m *mockSuccessService) RestorePlayer(context.Context, int, int) (*models.Player, error) {
+	return &jr, nil
+}
+
+func (m *mockSuccessService) ImportPlayers(context.Context, []models.ImportRow) ([]models.ImportResult, error) {
+	return []models.ImportResult{{Row: 1, Status: models.ImportCreated, ID: 1, Name: "Jalen Ramsey"}}, nil
+}
+
+func (m *mockSuccessService) GetPlayerHistory(context.Context, int) ([]models.HistoryEntry, error) {
+	return []models.HistoryEntry{{ID: 1, PlayerID: 1, Version: 1, Operation: models.OperationCreate, Actor: models.Anonymous}}, nil
+}
+
 var successSvc = &mockSuccessService{}
 
 type mockFailService struct{}
 
-func (m *mockFailService) ListPlayers(context.Context, string) ([]models.Player, error) {
+func (m *mockFailService) ListPlayers(context.Context, models.PlayerFilter, models.PageRequest) ([]models.Player, string, error) {
+	return nil, "", errors.New("fail")
+}
+
+func (m *mockFailService) ExportPlayers(context.Context, models.PlayerFilter, func(*models.Player) error) error {
+	return errors.New("fail")
+}
+
+func (m *mockFailService) SearchPlayers(context.Context, string, int) ([]models.SearchResult, error) {
 	return nil, errors.New("fail")
 }
 
-func (m *mockFailService) GetPlayer(context.Context, int) (*models.Player, error) {
+func (m *mockFailService) GetPlayer(context.Context, int, bool) (*models.Player, error) {
 	return nil, errors.New("fail")
 }
 
@@ -45,10 +78,26 @@ func (m *mockFailService) SavePlayer(context.Context, *models.Player) (*models.P
 	return nil, false, errors.New("fail")
 }
 
-func (m *mockFailService) DeletePlayer(context.Context, int) error {
+func (m *mockFailService) PatchPlayer(context.Context, int, *models.PlayerPatch) (*models.Player, error) {
+	return nil, errors.New("fail")
+}
+
+func (m *mockFailService) DeletePlayer(context.Context, int, int) error {
 	return errors.New("fail")
 }
 
+func (m *mockFailService) RestorePlayer(context.Context, int, int) (*models.Player, error) {
+	return nil, errors.New("fail")
+}
+
+func (m *mockFailService) ImportPlayers(context.Context, []models.ImportRow) ([]models.ImportResult, error) {
+	return nil, errors.New("fail")
+}
+
+func (m *mockFailService) GetPlayerHistory(context.Context, int) ([]models.HistoryEntry, error) {
+	return nil, errors.New("fail")
+}
+
 var failSvc = &mockFailService{}
 
 func TestMakeListPlayersEndpointShouldReturnFuncThatReturnsListPlayersResponse(t *testing.T) {
@@ -61,6 +110,33 @@ func TestMakeListPlayersEndpointShouldReturnFuncThatReturnsListPlayersResponse(t
 	assert.Equal(t, "Jalen Ramsey", lpr.Players[0].Name)
 }
 
+func TestMakeExportPlayersEndpointShouldReturnFuncThatExportsPlayers(t *testing.T) {
+	ep := NewEndpoints(successSvc)
+	resp, err := ep.exportPlayersEndpoint(context.Background(), exportPlayersRequest{Format: models.ExportJSONL})
+	assert.Nil(t, err)
+	epr, ok := resp.(exportPlayersResponse)
+	assert.True(t, ok)
+	assert.Equal(t, models.ExportJSONL, epr.Format)
+
+	var names []string
+	err = epr.Export(func(p *models.Player) error {
+		names = append(names, p.Name)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Jalen Ramsey"}, names)
+}
+
+func TestMakeSearchPlayersEndpointShouldReturnFuncThatReturnsSearchPlayersResponse(t *testing.T) {
+	ep := NewEndpoints(successSvc)
+	resp, err := ep.searchPlayersEndpoint(context.Background(), searchPlayersRequest{Query: "jalen"})
+	assert.Nil(t, err)
+	spr, ok := resp.(searchPlayersResponse)
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(spr.Results))
+	assert.Equal(t, "<em>Jalen</em> Ramsey", spr.Results[0].Highlight)
+}
+
 func TestMakeGetPlayerEndpointShouldReturnFuncThatReturnsGetPlayerResponse(t *testing.T) {
 	ep := NewEndpoints(successSvc)
 	resp, err := ep.getPlayerEndpoint(context.Background(), getPlayerRequest{})
@@ -79,13 +155,53 @@ func TestMakeSavePlayerEndpointShouldReturnFuncThatReturnsSavePlayerResponse(t *
 	assert.Equal(t, "Jalen Ramsey", spr.Player.Name)
 }
 
+func TestMakePatchPlayerEndpointShouldReturnFuncThatReturnsPatchPlayerResponse(t *testing.T) {
+	ep := NewEndpoints(successSvc)
+	resp, err := ep.patchPlayerEndpoint(context.Background(), patchPlayerRequest{ID: 20, Patch: &models.PlayerPatch{}})
+	assert.Nil(t, err)
+	ppr, ok := resp.(patchPlayerResponse)
+	assert.True(t, ok)
+	assert.Equal(t, "Jalen Ramsey", ppr.Player.Name)
+}
+
 func TestMakeDeletePlayerEndpointShouldReturnFuncThatReturnsDeletePlayerResponse(t *testing.T) {
 	ep := NewEndpoints(successSvc)
 	resp, err := ep.deletePlayerEndpoint(context.Background(), deletePlayerRequest{ID: 20})
 	assert.Nil(t, err)
 	dpr, ok := resp.(deletePlayerResponse)
 	assert.True(t, ok)
-	assert.Equal(t, "", dpr.Err)
+	assert.Nil(t, dpr.Err)
+}
+
+func TestMakeRestorePlayerEndpointShouldReturnFuncThatReturnsRestorePlayerResponse(t *testing.T) {
+	ep := NewEndpoints(successSvc)
+	resp, err := ep.restorePlayerEndpoint(context.Background(), restorePlayerRequest{ID: 20})
+	assert.Nil(t, err)
+	rpr, ok := resp.(restorePlayerResponse)
+	assert.True(t, ok)
+	assert.Nil(t, rpr.Err)
+	assert.Equal(t, "Jalen Ramsey", rpr.Player.Name)
+}
+
+func TestMakeImportPlayersEndpointShouldReturnFuncThatReturnsImportPlayersResponse(t *testing.T) {
+	ep := NewEndpoints(successSvc)
+	resp, err := ep.importPlayersEndpoint(context.Background(), importPlayersRequest{})
+	assert.Nil(t, err)
+	ipr, ok := resp.(importPlayersResponse)
+	assert.True(t, ok)
+	assert.Nil(t, ipr.Err)
+	assert.Equal(t, 1, len(ipr.Results))
+	assert.Equal(t, 1, ipr.Summary.Created)
+}
+
+func TestMakeGetHistoryEndpointShouldReturnFuncThatReturnsGetHistoryResponse(t *testing.T) {
+	ep := NewEndpoints(successSvc)
+	resp, err := ep.getHistoryEndpoint(context.Background(), getHistoryRequest{ID: 1})
+	assert.Nil(t, err)
+	ghr, ok := resp.(getHistoryResponse)
+	assert.True(t, ok)
+	assert.Nil(t, ghr.Err)
+	assert.Equal(t, 1, len(ghr.History))
 }
 
 func TestMakeListPlayersEndpointShouldReturnFuncThatReturnsListPlayersResponseWithErrorWhenError(t *testing.T) {
@@ -94,7 +210,7 @@ func TestMakeListPlayersEndpointShouldReturnFuncThatReturnsListPlayersResponseWi
 	assert.Nil(t, err)
 	lpr, ok := resp.(listPlayersResponse)
 	assert.True(t, ok)
-	assert.Equal(t, "fail", lpr.Err)
+	assert.EqualError(t, lpr.Err, "fail")
 }
 
 func TestMakeGetPlayerEndpointShouldReturnFuncThatReturnsGetPlayerResponseWithErrorWhenError(t *testing.T) {
@@ -103,7 +219,7 @@ func TestMakeGetPlayerEndpointShouldReturnFuncThatReturnsGetPlayerResponseWithEr
 	assert.Nil(t, err)
 	gpr, ok := resp.(getPlayerResponse)
 	assert.True(t, ok)
-	assert.Equal(t, "fail", gpr.Err)
+	assert.EqualError(t, gpr.Err, "fail")
 }
 
 func TestMakeSavePlayerEndpointShouldReturnFuncThatReturnsSavePlayerResponseWithErrorWhenError(t *testing.T) {
@@ -112,7 +228,7 @@ func TestMakeSavePlayerEndpointShouldReturnFuncThatReturnsSavePlayerResponseWith
 	assert.Nil(t, err)
 	spr, ok := resp.(savePlayerResponse)
 	assert.True(t, ok)
-	assert.Equal(t, "fail", spr.Err)
+	assert.EqualError(t, spr.Err, "fail")
 }
 
 func TestMakeDeletePlayerEndpointShouldReturnFuncThatReturnsDeletePlayerResponseWithErrorWhenError(t *testing.T) {
@@ -121,5 +237,32 @@ func TestMakeDeletePlayerEndpointShouldReturnFuncThatReturnsDeletePlayerResponse
 	assert.Nil(t, err)
 	dpr, ok := resp.(deletePlayerResponse)
 	assert.True(t, ok)
-	assert.Equal(t, "fail", dpr.Err)
+	assert.EqualError(t, dpr.Err, "fail")
+}
+
+func TestMakeRestorePlayerEndpointShouldReturnFuncThatReturnsRestorePlayerResponseWithErrorWhenError(t *testing.T) {
+	ep := NewEndpoints(failSvc)
+	resp, err := ep.restorePlayerEndpoint(context.Background(), restorePlayerRequest{ID: 20})
+	assert.Nil(t, err)
+	rpr, ok := resp.(restorePlayerResponse)
+	assert.True(t, ok)
+	assert.EqualError(t, rpr.Err, "fail")
+}
+
+func TestMakeImportPlayersEndpointShouldReturnFuncThatReturnsImportPlayersResponseWithErrorWhenError(t *testing.T) {
+	ep := NewEndpoints(failSvc)
+	resp, err := ep.importPlayersEndpoint(context.Background(), importPlayersRequest{})
+	assert.Nil(t, err)
+	ipr, ok := resp.(importPlayersResponse)
+	assert.True(t, ok)
+	assert.EqualError(t, ipr.Err, "fail")
+}
+
+func TestMakeGetHistoryEndpointShouldReturnFuncThatReturnsGetHistoryResponseWithErrorWhenError(t *testing.T) {
+	ep := NewEndpoints(failSvc)
+	resp, err := ep.getHistoryEndpoint(context.Background(), getHistoryRequest{ID: 1})
+	assert.Nil(t, err)
+	ghr, ok := resp.(getHistoryResponse)
+	assert.True(t, ok)
+	assert.EqualError(t, ghr.Err, "fail")
 }
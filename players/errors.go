@@ -0,0 +1,146 @@
+package players
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Kind classifies the errors returned by the players service
+type Kind int
+
+// The kinds of error the players service returns
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindInvalidArgument
+	KindPreconditionFailed
+	KindUnauthorized
+	KindNotAcceptable
+	KindUnsupportedMediaType
+	KindInvalidState
+	KindTooLarge
+)
+
+// Error is a players service error of a known kind
+type Error struct {
+	Kind    Kind
+	Message string
+}
+
+// Error returns the error's message
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(kind Kind, message string) *Error {
+	return &Error{
+		Kind:    kind,
+		Message: message,
+	}
+}
+
+var errNotFound = newError(KindNotFound, "not found")
+var errInvalidPageToken = newError(KindInvalidArgument, "invalid page token")
+var errInvalidSort = newError(KindInvalidArgument, "invalid sort field")
+var errVersionMismatch = newError(KindPreconditionFailed, "version mismatch")
+var errNotDeleted = newError(KindInvalidState, "player isn't deleted")
+
+// KindOf returns the kind of the given error. Validation errors are invalid
+// arguments, and errors of unknown type are internal.
+func KindOf(err error) Kind {
+	switch e := err.(type) {
+	case *Error:
+		return e.Kind
+	case *ValidationError:
+		return KindInvalidArgument
+	}
+	return KindInternal
+}
+
+func httpStatusCode(err error) int {
+	if _, ok := err.(*ValidationError); ok {
+		return http.StatusUnprocessableEntity
+	}
+
+	switch KindOf(err) {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict, KindInvalidState:
+		return http.StatusConflict
+	case KindInvalidArgument:
+		return http.StatusBadRequest
+	case KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindNotAcceptable:
+		return http.StatusNotAcceptable
+	case KindUnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case KindTooLarge:
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
+
+// httpStatusKind returns the kind of error an HTTP status code reports
+func httpStatusKind(code int) Kind {
+	switch code {
+	case http.StatusNotFound:
+		return KindNotFound
+	case http.StatusConflict:
+		return KindConflict
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return KindInvalidArgument
+	case http.StatusPreconditionFailed:
+		return KindPreconditionFailed
+	case http.StatusUnauthorized:
+		return KindUnauthorized
+	case http.StatusNotAcceptable:
+		return KindNotAcceptable
+	case http.StatusUnsupportedMediaType:
+		return KindUnsupportedMediaType
+	case http.StatusRequestEntityTooLarge:
+		return KindTooLarge
+	}
+	return KindInternal
+}
+
+func grpcCode(err error) codes.Code {
+	switch KindOf(err) {
+	case KindNotFound:
+		return codes.NotFound
+	case KindConflict:
+		return codes.AlreadyExists
+	case KindInvalidArgument, KindNotAcceptable, KindUnsupportedMediaType:
+		return codes.InvalidArgument
+	case KindPreconditionFailed, KindInvalidState:
+		return codes.FailedPrecondition
+	case KindUnauthorized:
+		return codes.Unauthenticated
+	case KindTooLarge:
+		return codes.ResourceExhausted
+	}
+	return codes.Internal
+}
+
+// grpcCodeKind returns the kind of error a gRPC status code reports
+func grpcCodeKind(code codes.Code) Kind {
+	switch code {
+	case codes.NotFound:
+		return KindNotFound
+	case codes.AlreadyExists:
+		return KindConflict
+	case codes.InvalidArgument:
+		return KindInvalidArgument
+	case codes.FailedPrecondition:
+		return KindPreconditionFailed
+	case codes.Unauthenticated:
+		return KindUnauthorized
+	case codes.ResourceExhausted:
+		return KindTooLarge
+	}
+	return KindInternal
+}
@@ -0,0 +1,53 @@
+package players
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hoop33/roster/models"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestKindOfShouldClassifyErrors(t *testing.T) {
+	assert.Equal(t, KindNotFound, KindOf(errNotFound))
+	assert.Equal(t, KindInvalidArgument, KindOf(errBadRequest))
+	assert.Equal(t, KindInvalidArgument, KindOf(&ValidationError{}))
+	assert.Equal(t, KindInternal, KindOf(errors.New("database error")))
+}
+
+func TestErrorsShouldMapToHTTPStatusCodes(t *testing.T) {
+	assert.Equal(t, http.StatusNotFound, httpStatusCode(errNotFound))
+	assert.Equal(t, http.StatusConflict, httpStatusCode(newError(KindConflict, "conflict")))
+	assert.Equal(t, http.StatusConflict, httpStatusCode(errNotDeleted))
+	assert.Equal(t, http.StatusBadRequest, httpStatusCode(errBadRequest))
+	assert.Equal(t, http.StatusUnprocessableEntity, httpStatusCode(&ValidationError{}))
+	assert.Equal(t, http.StatusPreconditionFailed, httpStatusCode(newError(KindPreconditionFailed, "precondition failed")))
+	assert.Equal(t, http.StatusUnauthorized, httpStatusCode(newError(KindUnauthorized, "unauthorized")))
+	assert.Equal(t, http.StatusNotAcceptable, httpStatusCode(errNotAcceptable))
+	assert.Equal(t, http.StatusUnsupportedMediaType, httpStatusCode(errUnsupportedMediaType))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, httpStatusCode(errImportTooLarge))
+	assert.Equal(t, http.StatusInternalServerError, httpStatusCode(errors.New("database error")))
+}
+
+func TestErrorsShouldMapToGRPCCodes(t *testing.T) {
+	assert.Equal(t, codes.NotFound, grpcCode(errNotFound))
+	assert.Equal(t, codes.AlreadyExists, grpcCode(newError(KindConflict, "conflict")))
+	assert.Equal(t, codes.FailedPrecondition, grpcCode(errNotDeleted))
+	assert.Equal(t, codes.InvalidArgument, grpcCode(&ValidationError{}))
+	assert.Equal(t, codes.FailedPrecondition, grpcCode(newError(KindPreconditionFailed, "precondition failed")))
+	assert.Equal(t, codes.Unauthenticated, grpcCode(newError(KindUnauthorized, "unauthorized")))
+	assert.Equal(t, codes.ResourceExhausted, grpcCode(errImportTooLarge))
+	assert.Equal(t, codes.Internal, grpcCode(errors.New("database error")))
+}
+
+func TestErrorKindShouldSurviveEndpoints(t *testing.T) {
+	ep := NewEndpoints(NewService(models.NewMemoryRepository()))
+	resp, err := ep.getPlayerEndpoint(context.Background(), getPlayerRequest{ID: 1})
+	assert.Nil(t, err)
+	gpr, ok := resp.(getPlayerResponse)
+	assert.True(t, ok)
+	assert.Equal(t, KindNotFound, KindOf(gpr.Err))
+}
@@ -2,6 +2,7 @@ package players
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -21,18 +22,36 @@ func NewLoggingService(logger log.Logger, next Service) Service {
 	}
 }
 
-func (l *loggingService) ListPlayers(ctx context.Context, position string) (players []models.Player, err error) {
+func (l *loggingService) ListPlayers(ctx context.Context, filter models.PlayerFilter, page models.PageRequest) (players []models.Player, next string, err error) {
 	defer func(begin time.Time) {
-		l.logger.Log("msg", "listing players", "pos", position, "num", len(players), "err", err, "took", time.Since(begin))
+		l.logger.Log("msg", "listing players", "pos", strings.Join(filter.Positions, ","), "sort", models.FormatSort(filter.Sort), "deleted", filter.IncludeDeleted, "size", page.Size, "token", page.Token, "num", len(players), "next", next, "err", err, "took", time.Since(begin))
 	}(time.Now())
-	return l.next.ListPlayers(ctx, position)
+	return l.next.ListPlayers(ctx, filter, page)
 }
 
-func (l *loggingService) GetPlayer(ctx context.Context, id int) (player *models.Player, err error) {
+func (l *loggingService) ExportPlayers(ctx context.Context, filter models.PlayerFilter, fn func(*models.Player) error) (err error) {
+	num := 0
 	defer func(begin time.Time) {
-		l.logger.Log("msg", "getting a player", "id", id, "err", err, "took", time.Since(begin))
+		l.logger.Log("msg", "exporting players", "pos", strings.Join(filter.Positions, ","), "sort", models.FormatSort(filter.Sort), "deleted", filter.IncludeDeleted, "num", num, "err", err, "took", time.Since(begin))
 	}(time.Now())
-	return l.next.GetPlayer(ctx, id)
+	return l.next.ExportPlayers(ctx, filter, func(p *models.Player) error {
+		num++
+		return fn(p)
+	})
+}
+
+func (l *loggingService) SearchPlayers(ctx context.Context, query string, limit int) (results []models.SearchResult, err error) {
+	defer func(begin time.Time) {
+		l.logger.Log("msg", "searching players", "q", query, "limit", limit, "num", len(results), "err", err, "took", time.Since(begin))
+	}(time.Now())
+	return l.next.SearchPlayers(ctx, query, limit)
+}
+
+func (l *loggingService) GetPlayer(ctx context.Context, id int, includeDeleted bool) (player *models.Player, err error) {
+	defer func(begin time.Time) {
+		l.logger.Log("msg", "getting a player", "id", id, "deleted", includeDeleted, "err", err, "took", time.Since(begin))
+	}(time.Now())
+	return l.next.GetPlayer(ctx, id, includeDeleted)
 }
 
 func (l *loggingService) SavePlayer(ctx context.Context, player *models.Player) (p *models.Player, created bool, err error) {
@@ -42,9 +61,38 @@ func (l *loggingService) SavePlayer(ctx context.Context, player *models.Player)
 	return l.next.SavePlayer(ctx, player)
 }
 
-func (l *loggingService) DeletePlayer(ctx context.Context, id int) (err error) {
+func (l *loggingService) PatchPlayer(ctx context.Context, id int, patch *models.PlayerPatch) (player *models.Player, err error) {
+	defer func(begin time.Time) {
+		l.logger.Log("msg", "patching player", "id", id, "fields", strings.Join(patch.Fields(), ","), "err", err, "took", time.Since(begin))
+	}(time.Now())
+	return l.next.PatchPlayer(ctx, id, patch)
+}
+
+func (l *loggingService) DeletePlayer(ctx context.Context, id, version int) (err error) {
+	defer func(begin time.Time) {
+		l.logger.Log("msg", "deleting a player", "id", id, "version", version, "err", err, "took", time.Since(begin))
+	}(time.Now())
+	return l.next.DeletePlayer(ctx, id, version)
+}
+
+func (l *loggingService) RestorePlayer(ctx context.Context, id, version int) (player *models.Player, err error) {
+	defer func(begin time.Time) {
+		l.logger.Log("msg", "restoring a player", "id", id, "version", version, "err", err, "took", time.Since(begin))
+	}(time.Now())
+	return l.next.RestorePlayer(ctx, id, version)
+}
+
+func (l *loggingService) ImportPlayers(ctx context.Context, rows []models.ImportRow) (results []models.ImportResult, err error) {
+	defer func(begin time.Time) {
+		s := models.SummarizeImport(results)
+		l.logger.Log("msg", "importing players", "rows", len(rows), "created", s.Created, "updated", s.Updated, "unchanged", s.Unchanged, "rejected", s.Rejected, "err", err, "took", time.Since(begin))
+	}(time.Now())
+	return l.next.ImportPlayers(ctx, rows)
+}
+
+func (l *loggingService) GetPlayerHistory(ctx context.Context, id int) (history []models.HistoryEntry, err error) {
 	defer func(begin time.Time) {
-		l.logger.Log("msg", "deleting a player", "id", id, "err", err, "took", time.Since(begin))
+		l.logger.Log("msg", "getting player history", "id", id, "num", len(history), "err", err, "took", time.Since(begin))
 	}(time.Now())
-	return l.next.DeletePlayer(ctx, id)
+	return l.next.GetPlayerHistory(ctx, id)
 }
@@ -13,12 +13,22 @@ type mockNextService struct {
 	called bool
 }
 
-func (m *mockNextService) ListPlayers(_ context.Context, _ string) ([]models.Player, error) {
+func (m *mockNextService) ListPlayers(_ context.Context, _ models.PlayerFilter, _ models.PageRequest) ([]models.Player, string, error) {
+	m.called = true
+	return nil, "", nil
+}
+
+func (m *mockNextService) ExportPlayers(_ context.Context, _ models.PlayerFilter, fn func(*models.Player) error) error {
+	m.called = true
+	return fn(&models.Player{})
+}
+
+func (m *mockNextService) SearchPlayers(_ context.Context, _ string, _ int) ([]models.SearchResult, error) {
 	m.called = true
 	return nil, nil
 }
 
-func (m *mockNextService) GetPlayer(_ context.Context, _ int) (*models.Player, error) {
+func (m *mockNextService) GetPlayer(_ context.Context, _ int, _ bool) (*models.Player, error) {
 	m.called = true
 	return nil, nil
 }
@@ -28,16 +38,59 @@ func (m *mockNextService) SavePlayer(_ context.Context, _ *models.Player) (*mode
 	return nil, false, nil
 }
 
-func (m *mockNextService) DeletePlayer(_ context.Context, _ int) error {
+func (m *mockNextService) PatchPlayer(_ context.Context, _ int, _ *models.PlayerPatch) (*models.Player, error) {
+	m.called = true
+	return nil, nil
+}
+
+func (m *mockNextService) DeletePlayer(_ context.Context, _, _ int) error {
 	m.called = true
 	return nil
 }
 
+func (m *mockNextService) RestorePlayer(_ context.Context, _, _ int) (*models.Player, error) {
+	m.called = true
+	return nil, nil
+}
+
+func (m *mockNextService) ImportPlayers(_ context.Context, _ []models.ImportRow) ([]models.ImportResult, error) {
+	m.called = true
+	return nil, nil
+}
+
+func (m *mockNextService) GetPlayerHistory(_ context.Context, _ int) ([]models.HistoryEntry, error) {
+	m.called = true
+	return nil, nil
+}
+
 func TestListPlayersShouldCallNext(t *testing.T) {
 	m := &mockNextService{}
 	s := NewLoggingService(log.NewNopLogger(), m)
 	assert.False(t, m.called)
-	_, err := s.ListPlayers(context.Background(), "")
+	_, _, err := s.ListPlayers(context.Background(), models.PlayerFilter{}, models.PageRequest{})
+	assert.Nil(t, err)
+	assert.True(t, m.called)
+}
+
+func TestExportPlayersShouldCallNext(t *testing.T) {
+	m := &mockNextService{}
+	s := NewLoggingService(log.NewNopLogger(), m)
+	assert.False(t, m.called)
+	num := 0
+	err := s.ExportPlayers(context.Background(), models.PlayerFilter{}, func(*models.Player) error {
+		num++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, m.called)
+	assert.Equal(t, 1, num)
+}
+
+func TestSearchPlayersShouldCallNext(t *testing.T) {
+	m := &mockNextService{}
+	s := NewLoggingService(log.NewNopLogger(), m)
+	assert.False(t, m.called)
+	_, err := s.SearchPlayers(context.Background(), "ramsey", 0)
 	assert.Nil(t, err)
 	assert.True(t, m.called)
 }
@@ -46,7 +99,7 @@ func TestGetPlayerShouldCallNext(t *testing.T) {
 	m := &mockNextService{}
 	s := NewLoggingService(log.NewNopLogger(), m)
 	assert.False(t, m.called)
-	_, err := s.GetPlayer(context.Background(), 0)
+	_, err := s.GetPlayer(context.Background(), 0, false)
 	assert.Nil(t, err)
 	assert.True(t, m.called)
 }
@@ -64,7 +117,34 @@ func TestDeletePlayerShouldCallNext(t *testing.T) {
 	m := &mockNextService{}
 	s := NewLoggingService(log.NewNopLogger(), m)
 	assert.False(t, m.called)
-	err := s.DeletePlayer(context.Background(), 0)
+	err := s.DeletePlayer(context.Background(), 0, 0)
+	assert.Nil(t, err)
+	assert.True(t, m.called)
+}
+
+func TestRestorePlayerShouldCallNext(t *testing.T) {
+	m := &mockNextService{}
+	s := NewLoggingService(log.NewNopLogger(), m)
+	assert.False(t, m.called)
+	_, err := s.RestorePlayer(context.Background(), 0, 0)
+	assert.Nil(t, err)
+	assert.True(t, m.called)
+}
+
+func TestImportPlayersShouldCallNext(t *testing.T) {
+	m := &mockNextService{}
+	s := NewLoggingService(log.NewNopLogger(), m)
+	assert.False(t, m.called)
+	_, err := s.ImportPlayers(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.True(t, m.called)
+}
+
+func TestGetPlayerHistoryShouldCallNext(t *testing.T) {
+	m := &mockNextService{}
+	s := NewLoggingService(log.NewNopLogger(), m)
+	assert.False(t, m.called)
+	_, err := s.GetPlayerHistory(context.Background(), 0)
 	assert.Nil(t, err)
 	assert.True(t, m.called)
 }
@@ -0,0 +1,417 @@
+package players
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hoop33/roster/models"
+)
+
+// openAPIPath is where the HTTP transport serves its OpenAPI document
+const openAPIPath = "/v1/openapi.json"
+
+// apiOperation describes a route of the HTTP transport. Bodies are given
+// as zero values of the types the transport reads and writes, keyed by
+// media type, and their schemas are reflected from the types' JSON tags.
+type apiOperation struct {
+	Method    string
+	Path      string
+	ID        string
+	Summary   string
+	Params    []apiParam
+	Requests  map[string]interface{}
+	Status    int
+	Responses map[string]interface{}
+	// Errors are the error statuses worth documenting on their own;
+	// the rest fall under the default response
+	Errors []int
+	// Conditional responses carry an ETag, and Modified ones a
+	// Last-Modified date, for conditional GETs
+	Conditional bool
+	Modified    bool
+}
+
+// apiParam describes a path, query, or header parameter
+type apiParam struct {
+	Name        string
+	In          string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// jsonPatchOperation is an RFC 6902 operation, as read by models.ParseJSONPatch
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+var (
+	idParam             = apiParam{Name: "id", In: "path", Type: "integer", Description: "player ID", Required: true}
+	ifMatchParam        = apiParam{Name: "If-Match", In: "header", Type: "string", Description: "ETag of the version being changed"}
+	actorParam          = apiParam{Name: ActorHeader, In: "header", Type: "string", Description: "who is making the change, for the history"}
+	includeDeletedParam = apiParam{Name: "include_deleted", In: "query", Type: "boolean", Description: "include deleted players"}
+)
+
+// filterParams are the query parameters shared by lists and exports
+var filterParams = []apiParam{
+	{Name: "position", In: "query", Type: "string", Description: "comma-separated positions"},
+	{Name: "college", In: "query", Type: "string", Description: "college, ignoring case"},
+	{Name: "name", In: "query", Type: "string", Description: "name prefix, ignoring case"},
+	{Name: "min_experience", In: "query", Type: "integer", Description: "least years of experience"},
+	{Name: "max_experience", In: "query", Type: "integer", Description: "most years of experience"},
+	includeDeletedParam,
+	{Name: "sort", In: "query", Type: "string", Description: "comma-separated fields, each prefixed with - for descending"},
+}
+
+// playerBodies are the media types a player can be sent in
+func playerBodies() map[string]interface{} {
+	bodies := map[string]interface{}{}
+	for _, mt := range responseMediaTypes {
+		bodies[mt] = models.Player{}
+	}
+	return bodies
+}
+
+// responseBodies offers a response in each negotiable media type
+func responseBodies(v interface{}) map[string]interface{} {
+	bodies := map[string]interface{}{}
+	for _, mt := range responseMediaTypes {
+		bodies[mt] = v
+	}
+	return bodies
+}
+
+// apiOperations describes every route of the HTTP transport
+var apiOperations = []apiOperation{
+	{
+		Method:      "GET",
+		Path:        "/v1/players",
+		ID:          "listPlayers",
+		Summary:     "List players a page at a time",
+		Params:      append(append([]apiParam{}, filterParams...), apiParam{Name: "page_size", In: "query", Type: "integer", Description: "most players per page"}, apiParam{Name: "page_token", In: "query", Type: "string", Description: "token from the previous page"}),
+		Status:      http.StatusOK,
+		Responses:   responseBodies(listPlayersResponse{}),
+		Conditional: true,
+	},
+	{
+		Method:  "GET",
+		Path:    "/v1/players/search",
+		ID:      "searchPlayers",
+		Summary: "Search players by name",
+		Params: []apiParam{
+			{Name: "q", In: "query", Type: "string", Description: "words to search for", Required: true},
+			{Name: "limit", In: "query", Type: "integer", Description: "most results"},
+		},
+		Status:    http.StatusOK,
+		Responses: responseBodies(searchPlayersResponse{}),
+	},
+	{
+		Method:  "GET",
+		Path:    "/v1/players/export",
+		ID:      "exportPlayers",
+		Summary: "Export players as a stream",
+		Params:  append(append([]apiParam{}, filterParams...), apiParam{Name: "format", In: "query", Type: "string", Description: "csv, jsonl, or tsv; defaults to the Accept header"}),
+		Status:  http.StatusOK,
+		Responses: map[string]interface{}{
+			mediaCSV:   models.Player{},
+			mediaTSV:   models.Player{},
+			mediaJSONL: models.Player{},
+		},
+	},
+	{
+		Method:      "GET",
+		Path:        "/v1/players/{id}",
+		ID:          "getPlayer",
+		Summary:     "Get a player",
+		Params:      []apiParam{idParam, includeDeletedParam},
+		Status:      http.StatusOK,
+		Responses:   responseBodies(getPlayerResponse{}),
+		Conditional: true,
+		Modified:    true,
+		Errors:      []int{http.StatusNotFound},
+	},
+	{
+		Method:    "GET",
+		Path:      "/v1/players/{id}/history",
+		ID:        "getHistory",
+		Summary:   "Get a player's change history",
+		Params:    []apiParam{idParam},
+		Status:    http.StatusOK,
+		Responses: responseBodies(getHistoryResponse{}),
+		Errors:    []int{http.StatusNotFound},
+	},
+	{
+		Method:    "POST",
+		Path:      "/v1/players",
+		ID:        "createPlayer",
+		Summary:   "Create a player",
+		Params:    []apiParam{actorParam},
+		Requests:  playerBodies(),
+		Status:    http.StatusCreated,
+		Responses: responseBodies(savePlayerResponse{}),
+	},
+	{
+		Method:    "POST",
+		Path:      "/v1/players:import",
+		ID:        "importPlayers",
+		Summary:   "Create or update players from CSV, matching them by name",
+		Params:    []apiParam{actorParam},
+		Requests:  map[string]interface{}{mediaCSV: models.Player{}, mediaProtobuf: importPlayersRequest{}},
+		Status:    http.StatusOK,
+		Responses: responseBodies(importPlayersResponse{}),
+	},
+	{
+		Method:    "POST",
+		Path:      "/v1/players/{id}:restore",
+		ID:        "restorePlayer",
+		Summary:   "Restore a deleted player",
+		Params:    []apiParam{idParam, ifMatchParam, actorParam},
+		Status:    http.StatusOK,
+		Responses: responseBodies(restorePlayerResponse{}),
+		Errors:    []int{http.StatusNotFound, http.StatusPreconditionFailed},
+	},
+	{
+		Method:    "PUT",
+		Path:      "/v1/players/{id}",
+		ID:        "updatePlayer",
+		Summary:   "Replace a player",
+		Params:    []apiParam{idParam, ifMatchParam, actorParam},
+		Requests:  playerBodies(),
+		Status:    http.StatusOK,
+		Responses: responseBodies(savePlayerResponse{}),
+		Errors:    []int{http.StatusNotFound, http.StatusPreconditionFailed},
+	},
+	{
+		Method:  "PATCH",
+		Path:    "/v1/players/{id}",
+		ID:      "patchPlayer",
+		Summary: "Change some of a player's fields",
+		Params:  []apiParam{idParam, ifMatchParam, actorParam},
+		Requests: map[string]interface{}{
+			"application/merge-patch+json": models.Player{},
+			"application/json-patch+json":  []jsonPatchOperation{},
+		},
+		Status:    http.StatusOK,
+		Responses: responseBodies(patchPlayerResponse{}),
+		Errors:    []int{http.StatusNotFound, http.StatusPreconditionFailed},
+	},
+	{
+		Method:  "DELETE",
+		Path:    "/v1/players/{id}",
+		ID:      "deletePlayer",
+		Summary: "Delete a player, keeping it until it's purged",
+		Params:  []apiParam{idParam, ifMatchParam, actorParam},
+		Status:  http.StatusNoContent,
+		Errors:  []int{http.StatusNotFound, http.StatusPreconditionFailed},
+	},
+	{
+		Method:    "GET",
+		Path:      openAPIPath,
+		ID:        "getOpenAPI",
+		Summary:   "Get this document",
+		Status:    http.StatusOK,
+		Responses: map[string]interface{}{mediaJSON: map[string]interface{}{}},
+	},
+}
+
+// knownSchemas describes the types that encode themselves as JSON
+var knownSchemas = map[reflect.Type]map[string]interface{}{
+	reflect.TypeOf(models.Number(0)):  {"type": "string", "example": "5", "description": "jersey number, also read as an integer"},
+	reflect.TypeOf(models.Height(0)):  {"type": "string", "example": "6-4", "description": "feet-inches, also read as inches"},
+	reflect.TypeOf(models.Weight(0)):  {"type": "string", "example": "236", "description": "pounds, also read as an integer"},
+	reflect.TypeOf(models.Date{}):     {"type": "string", "format": "date"},
+	reflect.TypeOf(time.Time{}):       {"type": "string", "format": "date-time"},
+	reflect.TypeOf(json.RawMessage{}): {"description": "any JSON value"},
+}
+
+// extraProperties describes properties added by a type's MarshalJSON
+var extraProperties = map[reflect.Type]map[string]interface{}{
+	reflect.TypeOf(models.Player{}): {
+		"age": map[string]interface{}{"type": "string", "readOnly": true, "description": "whole years, or empty if the birth date is unknown"},
+	},
+}
+
+// schemaBuilder reflects types into schemas, collecting structs as
+// components
+type schemaBuilder struct {
+	components map[string]interface{}
+}
+
+// schemaFor returns the schema of a body in a media type. Delimited and
+// protobuf bodies aren't JSON, so they're described only by their format.
+func (b *schemaBuilder) schemaFor(mt string, v interface{}) map[string]interface{} {
+	switch mt {
+	case mediaCSV, mediaTSV:
+		return map[string]interface{}{"type": "string", "description": "a header row, then a row per player"}
+	case mediaProtobuf:
+		return map[string]interface{}{"type": "string", "format": "binary", "description": "the message of the same name in pb/players.proto"}
+	}
+	return b.schema(reflect.TypeOf(v))
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	if s, ok := knownSchemas[t]; ok {
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		return b.structSchema(t)
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema adds a struct to the components and returns a reference
+// to it. Nothing is marked required, as the same schemas describe partial
+// request bodies.
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	name := schemaName(t)
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	if _, ok := b.components[name]; ok {
+		return ref
+	}
+	b.components[name] = nil
+
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || f.PkgPath != "" {
+			continue
+		}
+		field := strings.Split(tag, ",")[0]
+		if field == "" {
+			field = f.Name
+		}
+		properties[field] = b.schema(f.Type)
+	}
+	for field, s := range extraProperties[t] {
+		properties[field] = s
+	}
+
+	b.components[name] = map[string]interface{}{"type": "object", "properties": properties}
+	return ref
+}
+
+// schemaName exports a type's name, e.g., getPlayerResponse becomes
+// GetPlayerResponse
+func schemaName(t reflect.Type) string {
+	r := []rune(t.Name())
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// newOpenAPIDocument describes the operations as an OpenAPI 3 document
+func newOpenAPIDocument(ops []apiOperation) map[string]interface{} {
+	b := &schemaBuilder{components: map[string]interface{}{}}
+	errorContent := map[string]interface{}{}
+	for _, mt := range []string{mediaJSON, mediaYAML, mediaXML, mediaProtobuf} {
+		errorContent[mt] = map[string]interface{}{"schema": b.schemaFor(mt, errorResponse{})}
+	}
+
+	paths := map[string]interface{}{}
+	for _, op := range ops {
+		params := []interface{}{}
+		for _, p := range op.Params {
+			params = append(params, map[string]interface{}{
+				"name":        p.Name,
+				"in":          p.In,
+				"description": p.Description,
+				"required":    p.Required,
+				"schema":      map[string]interface{}{"type": p.Type},
+			})
+		}
+
+		success := map[string]interface{}{"description": http.StatusText(op.Status)}
+		if len(op.Responses) > 0 {
+			success["content"] = b.content(op.Responses)
+		}
+		responses := map[string]interface{}{
+			strconv.Itoa(op.Status): success,
+			"default":               map[string]interface{}{"description": "Error", "content": errorContent},
+		}
+		for _, status := range op.Errors {
+			responses[strconv.Itoa(status)] = map[string]interface{}{"description": http.StatusText(status), "content": errorContent}
+		}
+		if op.Conditional {
+			params = append(params, map[string]interface{}{"name": "If-None-Match", "in": "header", "schema": map[string]interface{}{"type": "string"}})
+			if op.Modified {
+				params = append(params, map[string]interface{}{"name": "If-Modified-Since", "in": "header", "schema": map[string]interface{}{"type": "string"}})
+			}
+			responses[strconv.Itoa(http.StatusNotModified)] = map[string]interface{}{"description": http.StatusText(http.StatusNotModified)}
+		}
+
+		operation := map[string]interface{}{
+			"operationId": op.ID,
+			"summary":     op.Summary,
+			"parameters":  params,
+			"responses":   responses,
+		}
+		if len(op.Requests) > 0 {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  b.content(op.Requests),
+			}
+		}
+
+		item, ok := paths[op.Path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":   "Roster",
+			"version": "1",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": b.components},
+	}
+}
+
+func (b *schemaBuilder) content(bodies map[string]interface{}) map[string]interface{} {
+	content := map[string]interface{}{}
+	for mt, v := range bodies {
+		content[mt] = map[string]interface{}{"schema": b.schemaFor(mt, v)}
+	}
+	return content
+}
+
+// serveOpenAPI writes an OpenAPI document as JSON
+func serveOpenAPI(doc map[string]interface{}) http.Handler {
+	b, err := json.Marshal(doc)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			encodeHTTPError(r.Context(), err, w)
+			return
+		}
+		w.Header().Set("Content-Type", contentType(mediaJSON))
+		w.Write(b)
+	})
+}
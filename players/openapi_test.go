@@ -0,0 +1,121 @@
+package players
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/hoop33/roster/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPIShouldDescribeEveryRoute(t *testing.T) {
+	described := map[string]bool{}
+	for _, op := range apiOperations {
+		described[op.Method+" "+op.Path] = true
+	}
+
+	routed := map[string]bool{}
+	router := newHTTPRouter(NewEndpoints(NewService(models.NewMemoryRepository())), log.NewNopLogger())
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			routed[method+" "+path] = true
+			assert.True(t, described[method+" "+path], "%s %s isn't described in apiOperations", method, path)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+
+	for op := range described {
+		assert.True(t, routed[op], "%s is described but not routed", op)
+	}
+}
+
+func TestHTTPShouldServeOpenAPI(t *testing.T) {
+	handler := NewHTTPTransport(NewEndpoints(NewService(models.NewMemoryRepository())), log.NewNopLogger())
+
+	req := httptest.NewRequest("GET", "/v1/openapi.json", nil)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header().Get("Content-Type"))
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string                     `json:"operationId"`
+			Summary     string                     `json:"summary"`
+			Responses   map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.Nil(t, json.Unmarshal(resp.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.0", doc.OpenAPI)
+	assert.Equal(t, "getPlayer", doc.Paths["/v1/players/{id}"]["get"].OperationID)
+	assert.NotNil(t, doc.Paths["/v1/players/{id}"]["delete"].Responses["204"])
+	assert.NotNil(t, doc.Paths["/v1/players"]["get"].Responses["304"])
+	assert.Equal(t, "Replace a player", doc.Paths["/v1/players/{id}"]["put"].Summary)
+	assert.NotNil(t, doc.Paths["/v1/players/{id}"]["put"].Responses["404"])
+	assert.NotNil(t, doc.Paths["/v1/players/{id}"]["put"].Responses["412"])
+
+	player := doc.Components.Schemas["Player"]
+	assert.Equal(t, "string", player.Properties["number"]["type"])
+	assert.Equal(t, "date", player.Properties["birth_date"]["format"])
+	assert.Equal(t, true, player.Properties["age"]["readOnly"])
+
+	list := doc.Components.Schemas["ListPlayersResponse"]
+	assert.Equal(t, "#/components/schemas/Player", list.Properties["players"]["items"].(map[string]interface{})["$ref"])
+	_, ok := list.Properties["Err"]
+	assert.False(t, ok)
+
+	for _, ref := range findRefs(resp.Body.String()) {
+		_, ok := doc.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+		assert.True(t, ok, ref)
+	}
+}
+
+// findRefs returns the targets of the $refs in a JSON document
+func findRefs(doc string) []string {
+	var refs []string
+	for _, part := range strings.Split(doc, `"$ref":"`)[1:] {
+		refs = append(refs, part[:strings.Index(part, `"`)])
+	}
+	return refs
+}
+
+func TestHTTPShouldReturnDocumentedNotFound(t *testing.T) {
+	handler := NewHTTPTransport(NewEndpoints(NewService(models.NewMemoryRepository())), log.NewNopLogger())
+
+	for _, op := range apiOperations {
+		for _, status := range op.Errors {
+			if status != http.StatusNotFound {
+				continue
+			}
+			path := strings.Replace(op.Path, "{id}", "5", 1)
+			body := `{"id":5,"name":"Blake Bortles","number":5,"position":"QB"}`
+			if op.Method == "PATCH" {
+				body = `{"number":9}`
+			}
+			req := httptest.NewRequest(op.Method, path, strings.NewReader(body))
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+			assert.Equal(t, http.StatusNotFound, resp.Code, op.ID)
+		}
+	}
+}
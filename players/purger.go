@@ -0,0 +1,62 @@
+package players
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/hoop33/roster/models"
+)
+
+// PurgeActor is who purges are attributed to in the player history
+const PurgeActor = "purger"
+
+// ErrPurgeInterval is returned when running a purger without a positive
+// interval
+var ErrPurgeInterval = errors.New("purge interval must be positive")
+
+// Purger permanently removes players that have been deleted for longer
+// than a retention period
+type Purger struct {
+	repo      models.PlayerRepository
+	retention time.Duration
+	logger    log.Logger
+}
+
+// NewPurger returns a purger that removes players deleted more than the
+// retention period ago
+func NewPurger(repo models.PlayerRepository, retention time.Duration, logger log.Logger) *Purger {
+	return &Purger{
+		repo:      repo,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Purge removes the expired players once, returning how many it removed
+func (p *Purger) Purge(ctx context.Context) (int, error) {
+	before := time.Now().UTC().Add(-p.retention)
+	return p.repo.PurgePlayers(models.WithActor(ctx, PurgeActor), before)
+}
+
+// Run purges now and then every interval, until the context is done
+func (p *Purger) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return ErrPurgeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		begin := time.Now()
+		n, err := p.Purge(ctx)
+		p.logger.Log("msg", "purging deleted players", "retention", p.retention, "num", n, "err", err, "took", time.Since(begin))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package players
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/hoop33/roster/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgerShouldKeepPlayersWithinRetention(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey"})
+	assert.Nil(t, err)
+	assert.Nil(t, repo.DeletePlayer(context.Background(), 1, 0))
+
+	n, err := NewPurger(repo, time.Hour, log.NewNopLogger()).Purge(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = repo.GetPlayer(context.Background(), 1, true)
+	assert.Nil(t, err)
+}
+
+func TestPurgerRunShouldPurgeUntilDone(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey"})
+	assert.Nil(t, err)
+	assert.Nil(t, repo.DeletePlayer(context.Background(), 1, 0))
+	time.Sleep(time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Nil(t, NewPurger(repo, 0, log.NewNopLogger()).Run(ctx, time.Hour))
+
+	_, err = repo.GetPlayer(context.Background(), 1, true)
+	assert.NotNil(t, err)
+
+	history, err := repo.GetPlayerHistory(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, models.OperationPurge, history[len(history)-1].Operation)
+	assert.Equal(t, PurgeActor, history[len(history)-1].Actor)
+}
+
+func TestPurgerRunShouldRejectNonPositiveInterval(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey"})
+	assert.Nil(t, err)
+	assert.Nil(t, repo.DeletePlayer(context.Background(), 1, 0))
+
+	for _, interval := range []time.Duration{0, -time.Hour} {
+		assert.Equal(t, ErrPurgeInterval, NewPurger(repo, 0, log.NewNopLogger()).Run(context.Background(), interval))
+	}
+
+	_, err = repo.GetPlayer(context.Background(), 1, true)
+	assert.Nil(t, err)
+}
@@ -3,65 +3,122 @@ package players
 import (
 	"context"
 	"database/sql"
-	"errors"
 
 	"github.com/hoop33/roster/models"
-	"github.com/jmoiron/sqlx"
 )
 
 // Service defines the functions for a players service
 type Service interface {
-	ListPlayers(context.Context, string) ([]models.Player, error)
-	GetPlayer(context.Context, int) (*models.Player, error)
+	ListPlayers(context.Context, models.PlayerFilter, models.PageRequest) ([]models.Player, string, error)
+	ExportPlayers(context.Context, models.PlayerFilter, func(*models.Player) error) error
+	SearchPlayers(context.Context, string, int) ([]models.SearchResult, error)
+	GetPlayer(context.Context, int, bool) (*models.Player, error)
 	SavePlayer(context.Context, *models.Player) (*models.Player, bool, error)
-	DeletePlayer(context.Context, int) error
+	PatchPlayer(context.Context, int, *models.PlayerPatch) (*models.Player, error)
+	DeletePlayer(context.Context, int, int) error
+	RestorePlayer(context.Context, int, int) (*models.Player, error)
+	ImportPlayers(context.Context, []models.ImportRow) ([]models.ImportResult, error)
+	GetPlayerHistory(context.Context, int) ([]models.HistoryEntry, error)
 }
 
 type service struct {
-	db *sqlx.DB
+	repo models.PlayerRepository
 }
 
-var errNotFound = errors.New("not found")
-
 // NewService returns a new service for interacting with players
-func NewService(db *sqlx.DB) Service {
+func NewService(repo models.PlayerRepository) Service {
 	return &service{
-		db: db,
+		repo: repo,
 	}
 }
 
-func (p *service) ListPlayers(_ context.Context, position string) ([]models.Player, error) {
-	players, err := models.ListPlayers(p.db, position)
-	if err == sql.ErrNoRows {
-		return nil, errNotFound
+func (p *service) ListPlayers(ctx context.Context, filter models.PlayerFilter, page models.PageRequest) ([]models.Player, string, error) {
+	players, next, err := p.repo.ListPlayers(ctx, filter, page)
+	switch err {
+	case sql.ErrNoRows:
+		return nil, "", errNotFound
+	case models.ErrInvalidPageToken:
+		return nil, "", errInvalidPageToken
+	case models.ErrInvalidSort:
+		return nil, "", errInvalidSort
 	}
-	return players, err
+	return players, next, err
+}
+
+func (p *service) ExportPlayers(ctx context.Context, filter models.PlayerFilter, fn func(*models.Player) error) error {
+	err := p.repo.ExportPlayers(ctx, filter, fn)
+	if err == models.ErrInvalidSort {
+		return errInvalidSort
+	}
+	return err
 }
 
-func (p *service) GetPlayer(_ context.Context, id int) (*models.Player, error) {
-	player, err := models.GetPlayer(p.db, id)
+func (p *service) SearchPlayers(ctx context.Context, query string, limit int) ([]models.SearchResult, error) {
+	return p.repo.SearchPlayers(ctx, query, limit)
+}
+
+func (p *service) GetPlayer(ctx context.Context, id int, includeDeleted bool) (*models.Player, error) {
+	player, err := p.repo.GetPlayer(ctx, id, includeDeleted)
 	if err == sql.ErrNoRows {
 		return nil, errNotFound
 	}
 	return player, err
 }
 
-func (p *service) SavePlayer(_ context.Context, player *models.Player) (*models.Player, bool, error) {
-	player, created, err := player.Save(p.db)
-	if err == sql.ErrNoRows {
+func (p *service) SavePlayer(ctx context.Context, player *models.Player) (*models.Player, bool, error) {
+	player, created, err := p.repo.SavePlayer(ctx, player)
+	switch err {
+	case sql.ErrNoRows:
 		return nil, false, errNotFound
+	case models.ErrVersionMismatch:
+		return nil, false, errVersionMismatch
 	}
 	return player, created, err
 }
 
-func (p *service) DeletePlayer(_ context.Context, id int) error {
-	player := &models.Player{
-		ID: id,
+func (p *service) PatchPlayer(ctx context.Context, id int, patch *models.PlayerPatch) (*models.Player, error) {
+	player, err := p.repo.PatchPlayer(ctx, id, patch)
+	switch err {
+	case sql.ErrNoRows:
+		return nil, errNotFound
+	case models.ErrVersionMismatch:
+		return nil, errVersionMismatch
 	}
+	return player, err
+}
 
-	err := player.Delete(p.db)
-	if err == sql.ErrNoRows {
+func (p *service) DeletePlayer(ctx context.Context, id, version int) error {
+	err := p.repo.DeletePlayer(ctx, id, version)
+	switch err {
+	case sql.ErrNoRows:
 		return errNotFound
+	case models.ErrVersionMismatch:
+		return errVersionMismatch
 	}
 	return err
 }
+
+func (p *service) RestorePlayer(ctx context.Context, id, version int) (*models.Player, error) {
+	player, err := p.repo.RestorePlayer(ctx, id, version)
+	switch err {
+	case sql.ErrNoRows:
+		return nil, errNotFound
+	case models.ErrNotDeleted:
+		return nil, errNotDeleted
+	case models.ErrVersionMismatch:
+		return nil, errVersionMismatch
+	}
+	return player, err
+}
+
+func (p *service) ImportPlayers(ctx context.Context, rows []models.ImportRow) ([]models.ImportResult, error) {
+	return p.repo.ImportPlayers(ctx, rows)
+}
+
+func (p *service) GetPlayerHistory(ctx context.Context, id int) ([]models.HistoryEntry, error) {
+	history, err := p.repo.GetPlayerHistory(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, errNotFound
+	}
+	return history, err
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/hoop33/roster/models"
 	"github.com/jmoiron/sqlx"
@@ -21,10 +22,10 @@ func TestListPlayersShouldReturnAllPlayers(t *testing.T) {
 		AddRow(1, "Blake Bortles", "5").
 		AddRow(2, "Jalen Ramsey", "20")
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC, id ASC LIMIT \$1$`).
 		WillReturnRows(rows)
 
-	players, err := NewService(db).ListPlayers(context.Background(), "")
+	players, _, err := NewService(models.NewPostgresRepository(db)).ListPlayers(context.Background(), models.PlayerFilter{}, models.PageRequest{})
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(players))
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -35,10 +36,10 @@ func TestListPlayersShouldReturnNotFoundWhenNoRows(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC, id ASC LIMIT \$1$`).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "number"}))
 
-	players, err := NewService(db).ListPlayers(context.Background(), "")
+	players, _, err := NewService(models.NewPostgresRepository(db)).ListPlayers(context.Background(), models.PlayerFilter{}, models.PageRequest{})
 	assert.Error(t, errNotFound, err)
 	assert.Equal(t, 0, len(players))
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -53,11 +54,11 @@ func TestListPlayersShouldUsePositionWhenSpecified(t *testing.T) {
 		AddRow(1, "Blake Bortles", "5", "QB").
 		AddRow(2, "Cody Kessler", "6", "QB")
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE position = \$1 ORDER BY number ASC$`).
-		WithArgs("QB").
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL AND position IN \(\$1\) ORDER BY number ASC, id ASC LIMIT \$2$`).
+		WithArgs("QB", models.DefaultPageSize+1).
 		WillReturnRows(rows)
 
-	players, err := NewService(db).ListPlayers(context.Background(), "QB")
+	players, _, err := NewService(models.NewPostgresRepository(db)).ListPlayers(context.Background(), models.PlayerFilter{Positions: []string{"QB"}}, models.PageRequest{})
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(players))
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -68,10 +69,10 @@ func TestListPlayersShouldReturnErrorWhenDatabaseError(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC`).
 		WillReturnError(errors.New("database error"))
 
-	players, err := NewService(db).ListPlayers(context.Background(), "")
+	players, _, err := NewService(models.NewPostgresRepository(db)).ListPlayers(context.Background(), models.PlayerFilter{}, models.PageRequest{})
 	assert.NotNil(t, err)
 	assert.Equal(t, 0, len(players))
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -85,11 +86,11 @@ func TestGetPlayerShouldReturnPlayerWhenExists(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "name", "number"}).
 		AddRow(1, "Blake Bortles", "5")
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnRows(rows)
 
-	player, err := NewService(db).GetPlayer(context.Background(), 1)
+	player, err := NewService(models.NewPostgresRepository(db)).GetPlayer(context.Background(), 1, false)
 	assert.Nil(t, err)
 	assert.Equal(t, "Blake Bortles", player.Name)
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -100,11 +101,11 @@ func TestGetPlayerShouldReturnErrorWhenNotFound(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnError(sql.ErrNoRows)
 
-	player, err := NewService(db).GetPlayer(context.Background(), 1)
+	player, err := NewService(models.NewPostgresRepository(db)).GetPlayer(context.Background(), 1, false)
 	assert.Error(t, errNotFound, err)
 	assert.Nil(t, player)
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -115,11 +116,11 @@ func TestGetPlayerShouldReturnErrorWhenDatabaseError(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnError(errors.New("database error"))
 
-	player, err := NewService(db).GetPlayer(context.Background(), 1)
+	player, err := NewService(models.NewPostgresRepository(db)).GetPlayer(context.Background(), 1, false)
 	assert.NotNil(t, err)
 	assert.Nil(t, player)
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -132,22 +133,25 @@ func TestSavePlayerShouldCreatePlayerWhenNoID(t *testing.T) {
 
 	p := &models.Player{
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
+	mock.ExpectBegin()
 	mock.ExpectQuery(`^INSERT INTO players
-		\(name, number, position, height, weight, age, experience, college\)
-		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)
+		\(name, number, position, height, weight, birth_date, experience, college, updated_at\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\)
 		RETURNING id$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectHistory(mock, models.OperationCreate)
+	mock.ExpectCommit()
 
-	player, created, err := NewService(db).SavePlayer(context.Background(), p)
+	player, created, err := NewService(models.NewPostgresRepository(db)).SavePlayer(context.Background(), p)
 	assert.Nil(t, err)
 	assert.True(t, created)
 	assert.Equal(t, 1, player.ID)
@@ -161,22 +165,24 @@ func TestSavePlayerShouldReturnErrorWhenNoIDAndDatabaseError(t *testing.T) {
 
 	p := &models.Player{
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
+	mock.ExpectBegin()
 	mock.ExpectQuery(`^INSERT INTO players
-		\(name, number, position, height, weight, age, experience, college\)
-		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)
+		\(name, number, position, height, weight, birth_date, experience, college, updated_at\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\)
 		RETURNING id$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg()).
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
-	player, created, err := NewService(db).SavePlayer(context.Background(), p)
+	player, created, err := NewService(models.NewPostgresRepository(db)).SavePlayer(context.Background(), p)
 	assert.NotNil(t, err)
 	assert.False(t, created)
 	assert.Equal(t, 0, player.ID)
@@ -191,21 +197,26 @@ func TestSavePlayerShouldUpdatePlayerWhenHasID(t *testing.T) {
 	p := &models.Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
-	mock.ExpectExec(`^UPDATE players
-		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, age=\$6, experience=\$7, college=\$8
-		WHERE id=\$9$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	player, created, err := NewService(db).SavePlayer(context.Background(), p)
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, birth_date=\$6, experience=\$7, college=\$8, updated_at=\$9, version=version\+1
+		WHERE id=\$10 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg(), p.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	expectHistory(mock, models.OperationUpdate)
+	mock.ExpectCommit()
+
+	player, created, err := NewService(models.NewPostgresRepository(db)).SavePlayer(context.Background(), p)
 	assert.Nil(t, err)
 	assert.False(t, created)
 	assert.Equal(t, 1, player.ID)
@@ -220,21 +231,19 @@ func TestSavePlayerShouldReturnErrWhenUpdatePlayerNotFound(t *testing.T) {
 	p := &models.Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
-	mock.ExpectExec(`^UPDATE players
-		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, age=\$6, experience=\$7, college=\$8
-		WHERE id=\$9$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID).
-		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	expectLock(mock, 1, false)
+	mock.ExpectRollback()
 
-	player, created, err := NewService(db).SavePlayer(context.Background(), p)
+	player, created, err := NewService(models.NewPostgresRepository(db)).SavePlayer(context.Background(), p)
 	assert.Error(t, errNotFound, err)
 	assert.False(t, created)
 	assert.Nil(t, player)
@@ -249,21 +258,25 @@ func TestSavePlayerShouldReturnErrorWhenHasIDAndDatabaseError(t *testing.T) {
 	p := &models.Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
-	mock.ExpectExec(`^UPDATE players
-		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, age=\$6, experience=\$7, college=\$8
-		WHERE id=\$9$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID).
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, birth_date=\$6, experience=\$7, college=\$8, updated_at=\$9, version=version\+1
+		WHERE id=\$10 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg(), p.ID).
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
-	player, created, err := NewService(db).SavePlayer(context.Background(), p)
+	player, created, err := NewService(models.NewPostgresRepository(db)).SavePlayer(context.Background(), p)
 	assert.NotNil(t, err)
 	assert.False(t, created)
 	assert.Equal(t, 1, player.ID)
@@ -275,12 +288,18 @@ func TestDeleteShouldDeletePlayer(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 1))
-
-	err = NewService(db).DeletePlayer(context.Background(), 1)
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	expectHistory(mock, models.OperationDelete)
+	mock.ExpectCommit()
+
+	err = NewService(models.NewPostgresRepository(db)).DeletePlayer(context.Background(), 1, 0)
 	assert.Nil(t, err)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
@@ -290,12 +309,11 @@ func TestDeleteShouldReturnErrorWhenNotFound(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	expectLock(mock, 1, false)
+	mock.ExpectRollback()
 
-	err = NewService(db).DeletePlayer(context.Background(), 1)
+	err = NewService(models.NewPostgresRepository(db)).DeletePlayer(context.Background(), 1, 0)
 	assert.Error(t, errNotFound, err)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
@@ -305,22 +323,224 @@ func TestDeleteShouldReturnErrorWhenError(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
-	err = NewService(db).DeletePlayer(context.Background(), 1)
+	err = NewService(models.NewPostgresRepository(db)).DeletePlayer(context.Background(), 1, 0)
 	assert.NotNil(t, err)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
+type mockRepository struct {
+	players []models.Player
+	err     error
+}
+
+func (m *mockRepository) ListPlayers(context.Context, models.PlayerFilter, models.PageRequest) ([]models.Player, string, error) {
+	return m.players, "", m.err
+}
+
+func (m *mockRepository) ExportPlayers(_ context.Context, _ models.PlayerFilter, fn func(*models.Player) error) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i := range m.players {
+		if err := fn(&m.players[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockRepository) SearchPlayers(context.Context, string, int) ([]models.SearchResult, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	results := make([]models.SearchResult, len(m.players))
+	for i, p := range m.players {
+		results[i] = models.SearchResult{Player: p, Rank: 1, Highlight: p.Name}
+	}
+	return results, nil
+}
+
+func (m *mockRepository) GetPlayer(context.Context, int, bool) (*models.Player, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &m.players[0], nil
+}
+
+func (m *mockRepository) SavePlayer(_ context.Context, player *models.Player) (*models.Player, bool, error) {
+	return player, player.ID <= 0 && m.err == nil, m.err
+}
+
+func (m *mockRepository) PatchPlayer(_ context.Context, _ int, patch *models.PlayerPatch) (*models.Player, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	player := m.players[0]
+	patch.Apply(&player)
+	return &player, nil
+}
+
+func (m *mockRepository) DeletePlayer(context.Context, int, int) error {
+	return m.err
+}
+
+func (m *mockRepository) RestorePlayer(context.Context, int, int) (*models.Player, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &m.players[0], nil
+}
+
+func (m *mockRepository) PurgePlayers(context.Context, time.Time) (int, error) {
+	return 0, m.err
+}
+
+func (m *mockRepository) ImportPlayers(context.Context, []models.ImportRow) ([]models.ImportResult, error) {
+	return nil, m.err
+}
+
+func (m *mockRepository) GetPlayerHistory(context.Context, int) ([]models.HistoryEntry, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []models.HistoryEntry{}, nil
+}
+
+func TestServiceShouldReturnPlayersFromRepository(t *testing.T) {
+	repo := &mockRepository{
+		players: []models.Player{jr},
+	}
+	players, _, err := NewService(repo).ListPlayers(context.Background(), models.PlayerFilter{}, models.PageRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(players))
+	assert.Equal(t, "Jalen Ramsey", players[0].Name)
+}
+
+func TestServiceShouldExportPlayersFromRepository(t *testing.T) {
+	repo := &mockRepository{
+		players: []models.Player{jr},
+	}
+	var names []string
+	err := NewService(repo).ExportPlayers(context.Background(), models.PlayerFilter{}, func(p *models.Player) error {
+		names = append(names, p.Name)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Jalen Ramsey"}, names)
+
+	repo.err = models.ErrInvalidSort
+	err = NewService(repo).ExportPlayers(context.Background(), models.PlayerFilter{}, nil)
+	assert.Equal(t, errInvalidSort, err)
+}
+
+func TestServiceShouldMapNoRowsFromRepositoryToNotFound(t *testing.T) {
+	repo := &mockRepository{
+		err: sql.ErrNoRows,
+	}
+	s := NewService(repo)
+
+	_, _, err := s.ListPlayers(context.Background(), models.PlayerFilter{}, models.PageRequest{})
+	assert.Equal(t, errNotFound, err)
+
+	_, err = s.GetPlayer(context.Background(), 1, false)
+	assert.Equal(t, errNotFound, err)
+
+	_, _, err = s.SavePlayer(context.Background(), &models.Player{ID: 1})
+	assert.Equal(t, errNotFound, err)
+
+	_, err = s.PatchPlayer(context.Background(), 1, &models.PlayerPatch{})
+	assert.Equal(t, errNotFound, err)
+
+	err = s.DeletePlayer(context.Background(), 1, 0)
+	assert.Equal(t, errNotFound, err)
+}
+
+func TestServiceShouldMapVersionMismatchToPreconditionFailed(t *testing.T) {
+	repo := &mockRepository{
+		err: models.ErrVersionMismatch,
+	}
+	s := NewService(repo)
+
+	_, _, err := s.SavePlayer(context.Background(), &models.Player{ID: 1, Version: 1})
+	assert.Equal(t, errVersionMismatch, err)
+
+	_, err = s.PatchPlayer(context.Background(), 1, &models.PlayerPatch{Version: 1})
+	assert.Equal(t, errVersionMismatch, err)
+
+	err = s.DeletePlayer(context.Background(), 1, 1)
+	assert.Equal(t, errVersionMismatch, err)
+	assert.Equal(t, KindPreconditionFailed, KindOf(err))
+}
+
+func TestServiceShouldMapRestoreErrors(t *testing.T) {
+	for _, tc := range []struct {
+		repoErr error
+		want    error
+		kind    Kind
+	}{
+		{sql.ErrNoRows, errNotFound, KindNotFound},
+		{models.ErrNotDeleted, errNotDeleted, KindInvalidState},
+		{models.ErrVersionMismatch, errVersionMismatch, KindPreconditionFailed},
+	} {
+		_, err := NewService(&mockRepository{err: tc.repoErr}).RestorePlayer(context.Background(), 1, 0)
+		assert.Equal(t, tc.want, err)
+		assert.Equal(t, tc.kind, KindOf(err))
+	}
+}
+
+func TestServiceShouldPatchPlayerFromRepository(t *testing.T) {
+	repo := &mockRepository{
+		players: []models.Player{jr},
+	}
+	number := models.Number(33)
+	player, err := NewService(repo).PatchPlayer(context.Background(), 1, &models.PlayerPatch{Number: &number})
+	assert.Nil(t, err)
+	assert.Equal(t, "Jalen Ramsey", player.Name)
+	assert.Equal(t, models.Number(33), player.Number)
+}
+
+func TestServiceShouldPassThroughRepositoryErrors(t *testing.T) {
+	repo := &mockRepository{
+		err: errors.New("storage error"),
+	}
+	_, err := NewService(repo).GetPlayer(context.Background(), 1, false)
+	assert.EqualError(t, err, "storage error")
+}
+
 func createDB() (*sqlx.DB, sqlmock.Sqlmock, error) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	xdb := sqlx.NewDb(db, "sqlmock")
+	xdb := sqlx.NewDb(db, "postgres")
 	return xdb, mock, nil
 }
+
+// expectLock expects the row lock taken before a player changes
+func expectLock(mock sqlmock.Sqlmock, id int, found bool) {
+	rows := sqlmock.NewRows([]string{"id", "name", "version"})
+	if found {
+		rows.AddRow(id, "Blake Bortles", 1)
+	}
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 FOR UPDATE$`).
+		WithArgs(id).
+		WillReturnRows(rows)
+}
+
+// expectHistory expects a change to be recorded in the player history
+func expectHistory(mock sqlmock.Sqlmock, operation string) {
+	mock.ExpectExec(`^INSERT INTO player_history`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), operation, models.Anonymous, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
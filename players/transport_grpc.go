@@ -2,33 +2,69 @@ package players
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/transport/grpc"
+	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/hoop33/roster/models"
 	"github.com/hoop33/roster/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type grpcTransport struct {
-	listPlayers  grpc.Handler
-	getPlayer    grpc.Handler
-	savePlayer   grpc.Handler
-	deletePlayer grpc.Handler
+	listPlayers   grpc.Handler
+	searchPlayers grpc.Handler
+	getPlayer     grpc.Handler
+	savePlayer    grpc.Handler
+	updatePlayer  grpc.Handler
+	deletePlayer  grpc.Handler
+	restorePlayer grpc.Handler
+	getHistory    grpc.Handler
+	importPlayers grpc.Handler
+	legacyErrors  bool
 }
 
+// GRPCOption sets an optional parameter for the GRPC transport
+type GRPCOption func(*grpcTransport)
+
+// LegacyErrors makes the GRPC transport report failures in the deprecated
+// err response fields with an OK status, for clients that predate status errors
+func LegacyErrors() GRPCOption {
+	return func(t *grpcTransport) {
+		t.legacyErrors = true
+	}
+}
+
+// ActorMetadataKey names the gRPC metadata identifying who is making a
+// change, for the player history. Like ActorHeader, it's taken on trust.
+const ActorMetadataKey = "x-user"
+
 // NewGRPCTransport returns a handler for GRPC transport
-func NewGRPCTransport(ep *Endpoints, logger log.Logger) pb.PlayersServer {
+func NewGRPCTransport(ep *Endpoints, logger log.Logger, options ...GRPCOption) pb.PlayersServer {
 	opts := []grpc.ServerOption{
 		grpc.ServerErrorLogger(log.With(logger, "tag", "grpc")),
+		grpc.ServerBefore(populateGRPCActor),
 	}
 
-	return &grpcTransport{
+	t := &grpcTransport{
 		listPlayers: grpc.NewServer(
 			ep.listPlayersEndpoint,
 			decodeGRPCListPlayersRequest,
 			encodeGRPCListPlayersResponse,
 			opts...,
 		),
+		searchPlayers: grpc.NewServer(
+			ep.searchPlayersEndpoint,
+			decodeGRPCSearchPlayersRequest,
+			encodeGRPCSearchPlayersResponse,
+			opts...,
+		),
 		getPlayer: grpc.NewServer(
 			ep.getPlayerEndpoint,
 			decodeGRPCGetPlayerRequest,
@@ -41,26 +77,74 @@ func NewGRPCTransport(ep *Endpoints, logger log.Logger) pb.PlayersServer {
 			encodeGRPCSavePlayerResponse,
 			opts...,
 		),
+		updatePlayer: grpc.NewServer(
+			ep.patchPlayerEndpoint,
+			decodeGRPCUpdatePlayerRequest,
+			encodeGRPCUpdatePlayerResponse,
+			opts...,
+		),
 		deletePlayer: grpc.NewServer(
 			ep.deletePlayerEndpoint,
 			decodeGRPCDeletePlayerRequest,
 			encodeGRPCDeletePlayerResponse,
 			opts...,
 		),
+		restorePlayer: grpc.NewServer(
+			ep.restorePlayerEndpoint,
+			decodeGRPCRestorePlayerRequest,
+			encodeGRPCRestorePlayerResponse,
+			opts...,
+		),
+		getHistory: grpc.NewServer(
+			ep.getHistoryEndpoint,
+			decodeGRPCGetPlayerHistoryRequest,
+			encodeGRPCGetPlayerHistoryResponse,
+			opts...,
+		),
+		importPlayers: grpc.NewServer(
+			ep.importPlayersEndpoint,
+			decodeGRPCImportPlayersRequest,
+			encodeGRPCImportPlayersResponse,
+			opts...,
+		),
 	}
+	for _, option := range options {
+		option(t)
+	}
+	return t
 }
 
 func (s *grpcTransport) ListPlayers(ctx context.Context, r *pb.ListPlayersRequest) (*pb.ListPlayersResponse, error) {
 	_, resp, err := s.listPlayers.ServeGRPC(ctx, r)
 	if err != nil {
+		if s.legacyErrors {
+			return &pb.ListPlayersResponse{
+				Err: status.Convert(err).Message(),
+			}, nil
+		}
 		return nil, err
 	}
 	return resp.(*pb.ListPlayersResponse), nil
 }
 
+// SearchPlayers has no legacy err field, so it always reports failures
+// with a status
+func (s *grpcTransport) SearchPlayers(ctx context.Context, r *pb.SearchPlayersRequest) (*pb.SearchPlayersResponse, error) {
+	_, resp, err := s.searchPlayers.ServeGRPC(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.SearchPlayersResponse), nil
+}
+
 func (s *grpcTransport) GetPlayer(ctx context.Context, r *pb.GetPlayerRequest) (*pb.GetPlayerResponse, error) {
 	_, resp, err := s.getPlayer.ServeGRPC(ctx, r)
 	if err != nil {
+		if s.legacyErrors {
+			return &pb.GetPlayerResponse{
+				Err: status.Convert(err).Message(),
+			}, nil
+		}
 		return nil, err
 	}
 	return resp.(*pb.GetPlayerResponse), nil
@@ -69,28 +153,110 @@ func (s *grpcTransport) GetPlayer(ctx context.Context, r *pb.GetPlayerRequest) (
 func (s *grpcTransport) SavePlayer(ctx context.Context, r *pb.SavePlayerRequest) (*pb.SavePlayerResponse, error) {
 	_, resp, err := s.savePlayer.ServeGRPC(ctx, r)
 	if err != nil {
+		if s.legacyErrors {
+			return &pb.SavePlayerResponse{
+				Err: status.Convert(err).Message(),
+			}, nil
+		}
 		return nil, err
 	}
 	return resp.(*pb.SavePlayerResponse), nil
 }
 
+// UpdatePlayer has no legacy err field, so it always reports failures
+// with a status
+func (s *grpcTransport) UpdatePlayer(ctx context.Context, r *pb.UpdatePlayerRequest) (*pb.UpdatePlayerResponse, error) {
+	_, resp, err := s.updatePlayer.ServeGRPC(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.UpdatePlayerResponse), nil
+}
+
 func (s *grpcTransport) DeletePlayer(ctx context.Context, r *pb.DeletePlayerRequest) (*pb.DeletePlayerResponse, error) {
 	_, resp, err := s.deletePlayer.ServeGRPC(ctx, r)
 	if err != nil {
+		if s.legacyErrors {
+			return &pb.DeletePlayerResponse{
+				Err: status.Convert(err).Message(),
+			}, nil
+		}
 		return nil, err
 	}
 	return resp.(*pb.DeletePlayerResponse), nil
 }
 
+// RestorePlayer has no legacy err field, so it always reports failures
+// with a status
+func (s *grpcTransport) RestorePlayer(ctx context.Context, r *pb.RestorePlayerRequest) (*pb.RestorePlayerResponse, error) {
+	_, resp, err := s.restorePlayer.ServeGRPC(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.RestorePlayerResponse), nil
+}
+
+// GetPlayerHistory has no legacy err field, so it always reports
+// failures with a status
+func (s *grpcTransport) GetPlayerHistory(ctx context.Context, r *pb.GetPlayerHistoryRequest) (*pb.GetPlayerHistoryResponse, error) {
+	_, resp, err := s.getHistory.ServeGRPC(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.GetPlayerHistoryResponse), nil
+}
+
+func (s *grpcTransport) ImportPlayers(ctx context.Context, r *pb.ImportPlayersRequest) (*pb.ImportPlayersResponse, error) {
+	_, resp, err := s.importPlayers.ServeGRPC(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*pb.ImportPlayersResponse), nil
+}
+
+func populateGRPCActor(ctx context.Context, md metadata.MD) context.Context {
+	if actors := md.Get(ActorMetadataKey); len(actors) > 0 && actors[0] != "" {
+		return models.WithActor(ctx, actors[0])
+	}
+	return ctx
+}
+
 func decodeGRPCListPlayersRequest(_ context.Context, r interface{}) (interface{}, error) {
 	req := r.(*pb.ListPlayersRequest)
+
+	positions := req.Positions
+	if req.Position != "" {
+		positions = append(positions, req.Position)
+	}
+
 	return listPlayersRequest{
-		Position: req.Position,
+		Filter: models.PlayerFilter{
+			Positions:      positions,
+			College:        req.College,
+			NamePrefix:     req.NamePrefix,
+			MinExperience:  protoInt(req.MinExperience),
+			MaxExperience:  protoInt(req.MaxExperience),
+			IncludeDeleted: req.IncludeDeleted,
+			Sort:           models.ParseSort(req.Sort),
+		},
+		PageSize:  int(req.PageSize),
+		PageToken: req.PageToken,
 	}, nil
 }
 
+func protoInt(v *wrappers.Int32Value) *int {
+	if v == nil {
+		return nil
+	}
+	n := int(v.Value)
+	return &n
+}
+
 func encodeGRPCListPlayersResponse(_ context.Context, r interface{}) (interface{}, error) {
 	resp := r.(listPlayersResponse)
+	if resp.Err != nil {
+		return nil, grpcError(resp.Err)
+	}
 
 	players := make([]*pb.Player, len(resp.Players))
 	for i, p := range resp.Players {
@@ -99,37 +265,74 @@ func encodeGRPCListPlayersResponse(_ context.Context, r interface{}) (interface{
 	}
 
 	return &pb.ListPlayersResponse{
-		Players: players,
-		Err:     resp.Err,
+		Players:       players,
+		NextPageToken: resp.NextPageToken,
+	}, nil
+}
+
+func decodeGRPCSearchPlayersRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(*pb.SearchPlayersRequest)
+	return searchPlayersRequest{
+		Query: req.Query,
+		Limit: int(req.Limit),
+	}, nil
+}
+
+func encodeGRPCSearchPlayersResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(searchPlayersResponse)
+	if resp.Err != nil {
+		return nil, grpcError(resp.Err)
+	}
+
+	results := make([]*pb.SearchResult, len(resp.Results))
+	for i, r := range resp.Results {
+		player := modelsPlayerToProtoPlayer(r.Player)
+		results[i] = &pb.SearchResult{
+			Player:    &player,
+			Rank:      r.Rank,
+			Highlight: r.Highlight,
+		}
+	}
+
+	return &pb.SearchPlayersResponse{
+		Results: results,
 	}, nil
 }
 
 func decodeGRPCGetPlayerRequest(_ context.Context, r interface{}) (interface{}, error) {
 	req := r.(*pb.GetPlayerRequest)
 	return getPlayerRequest{
-		ID: int(req.Id),
+		ID:             int(req.Id),
+		IncludeDeleted: req.IncludeDeleted,
 	}, nil
 }
 
 func encodeGRPCGetPlayerResponse(_ context.Context, r interface{}) (interface{}, error) {
 	resp := r.(getPlayerResponse)
+	if resp.Err != nil {
+		return nil, grpcError(resp.Err)
+	}
 
 	if resp.Player == nil {
-		return &pb.GetPlayerResponse{
-			Err: resp.Err,
-		}, nil
+		return &pb.GetPlayerResponse{}, nil
 	}
 
 	player := modelsPlayerToProtoPlayer(*resp.Player)
 	return &pb.GetPlayerResponse{
 		Player: &player,
-		Err:    resp.Err,
 	}, nil
 }
 
 func decodeGRPCSavePlayerRequest(_ context.Context, r interface{}) (interface{}, error) {
 	req := r.(*pb.SavePlayerRequest)
-	player := protoPlayerToModelsPlayer(*req.Player)
+	if req.Player == nil {
+		return nil, grpcError(newError(KindInvalidArgument, "player is required"))
+	}
+	player, err := protoPlayerToModelsPlayer(*req.Player)
+	if err != nil {
+		return nil, grpcError(newError(KindInvalidArgument, err.Error()))
+	}
+	player.Version = int(req.ExpectedVersion)
 	return savePlayerRequest{
 		Player: &player,
 	}, nil
@@ -137,11 +340,13 @@ func decodeGRPCSavePlayerRequest(_ context.Context, r interface{}) (interface{},
 
 func encodeGRPCSavePlayerResponse(_ context.Context, r interface{}) (interface{}, error) {
 	resp := r.(savePlayerResponse)
+	if resp.Err != nil {
+		return nil, grpcError(resp.Err)
+	}
 
 	if resp.Player == nil {
 		return &pb.SavePlayerResponse{
 			Created: resp.Created,
-			Err:     resp.Err,
 		}, nil
 	}
 
@@ -149,49 +354,316 @@ func encodeGRPCSavePlayerResponse(_ context.Context, r interface{}) (interface{}
 	return &pb.SavePlayerResponse{
 		Player:  &player,
 		Created: resp.Created,
-		Err:     resp.Err,
+	}, nil
+}
+
+// maskFields maps field mask paths, which name proto Player fields, to
+// model fields
+var maskFields = map[string]string{
+	"name":          "name",
+	"jersey_number": "number",
+	"number":        "number",
+	"position":      "position",
+	"height_inches": "height",
+	"height":        "height",
+	"weight_pounds": "weight",
+	"weight":        "weight",
+	"birth_date":    "birth_date",
+	"experience":    "experience",
+	"college":       "college",
+}
+
+func decodeGRPCUpdatePlayerRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(*pb.UpdatePlayerRequest)
+	if req.Player == nil || req.Player.Id <= 0 {
+		return nil, grpcError(newError(KindInvalidArgument, "player with an id is required"))
+	}
+	if req.UpdateMask == nil || len(req.UpdateMask.Paths) == 0 {
+		return nil, grpcError(newError(KindInvalidArgument, "update_mask is required"))
+	}
+
+	fields := make([]string, len(req.UpdateMask.Paths))
+	for i, path := range req.UpdateMask.Paths {
+		field, ok := maskFields[path]
+		if !ok {
+			return nil, grpcError(newError(KindInvalidArgument, fmt.Sprintf("unknown update_mask path %q", path)))
+		}
+		fields[i] = field
+	}
+
+	player, err := protoPlayerToModelsPlayer(*req.Player)
+	if err != nil {
+		return nil, grpcError(newError(KindInvalidArgument, err.Error()))
+	}
+
+	patch, err := models.NewPlayerPatch(player, fields)
+	if err != nil {
+		return nil, grpcError(newError(KindInvalidArgument, err.Error()))
+	}
+	patch.Version = int(req.ExpectedVersion)
+
+	return patchPlayerRequest{
+		ID:    player.ID,
+		Patch: patch,
+	}, nil
+}
+
+func encodeGRPCUpdatePlayerResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(patchPlayerResponse)
+	if resp.Err != nil {
+		return nil, grpcError(resp.Err)
+	}
+
+	player := modelsPlayerToProtoPlayer(*resp.Player)
+	return &pb.UpdatePlayerResponse{
+		Player: &player,
 	}, nil
 }
 
 func decodeGRPCDeletePlayerRequest(_ context.Context, r interface{}) (interface{}, error) {
 	req := r.(*pb.DeletePlayerRequest)
 	return deletePlayerRequest{
-		ID: int(req.Id),
+		ID:      int(req.Id),
+		Version: int(req.ExpectedVersion),
 	}, nil
 }
 
 func encodeGRPCDeletePlayerResponse(_ context.Context, r interface{}) (interface{}, error) {
 	resp := r.(deletePlayerResponse)
+	if resp.Err != nil {
+		return nil, grpcError(resp.Err)
+	}
+
+	return &pb.DeletePlayerResponse{}, nil
+}
+
+func decodeGRPCRestorePlayerRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(*pb.RestorePlayerRequest)
+	return restorePlayerRequest{
+		ID:      int(req.Id),
+		Version: int(req.ExpectedVersion),
+	}, nil
+}
+
+func encodeGRPCRestorePlayerResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(restorePlayerResponse)
+	if resp.Err != nil {
+		return nil, grpcError(resp.Err)
+	}
+
+	player := modelsPlayerToProtoPlayer(*resp.Player)
+	return &pb.RestorePlayerResponse{
+		Player: &player,
+	}, nil
+}
+
+func decodeGRPCGetPlayerHistoryRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(*pb.GetPlayerHistoryRequest)
+	return getHistoryRequest{
+		ID: int(req.Id),
+	}, nil
+}
+
+func encodeGRPCGetPlayerHistoryResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(getHistoryResponse)
+	if resp.Err != nil {
+		return nil, grpcError(resp.Err)
+	}
+
+	entries := make([]*pb.HistoryEntry, len(resp.History))
+	for i, e := range resp.History {
+		changes := make([]*pb.FieldChange, len(e.Changes))
+		for j, c := range e.Changes {
+			changes[j] = &pb.FieldChange{
+				Field:  c.Field,
+				Before: jsonValue(c.Before),
+				After:  jsonValue(c.After),
+			}
+		}
+		entries[i] = &pb.HistoryEntry{
+			Id:        int32(e.ID),
+			PlayerId:  int32(e.PlayerID),
+			Version:   int32(e.Version),
+			Operation: e.Operation,
+			Actor:     e.Actor,
+			ChangedAt: formatTimestamp(e.ChangedAt),
+			Changes:   changes,
+		}
+	}
+	return &pb.GetPlayerHistoryResponse{
+		Entries: entries,
+	}, nil
+}
 
-	return &pb.DeletePlayerResponse{
-		Err: resp.Err,
+// decodeGRPCImportPlayersRequest reads the rows of an import. A player
+// that can't be read rejects its row rather than the import.
+func decodeGRPCImportPlayersRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(*pb.ImportPlayersRequest)
+	rows := make([]models.ImportRow, len(req.Rows))
+	for i, row := range req.Rows {
+		rows[i] = models.ImportRow{
+			Row:    int(row.Row),
+			Errors: row.Errors,
+		}
+		if len(row.Fields) > 0 {
+			rows[i].Fields = row.Fields
+		}
+		if row.Player == nil {
+			continue
+		}
+		player, err := protoPlayerToModelsPlayer(*row.Player)
+		if err != nil {
+			rows[i].Errors = append(rows[i].Errors, err.Error())
+			player = models.Player{Name: row.Player.Name}
+		}
+		rows[i].Player = player
+	}
+	return importPlayersRequest{
+		Rows: rows,
+	}, nil
+}
+
+func encodeGRPCImportPlayersResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(importPlayersResponse)
+	if resp.Err != nil {
+		return nil, grpcError(resp.Err)
+	}
+	results := make([]*pb.ImportResult, len(resp.Results))
+	for i, result := range resp.Results {
+		results[i] = &pb.ImportResult{
+			Row:    int32(result.Row),
+			Status: result.Status,
+			Id:     int32(result.ID),
+			Name:   result.Name,
+			Errors: result.Errors,
+		}
+	}
+	return &pb.ImportPlayersResponse{
+		Summary: &pb.ImportSummary{
+			Created:   int32(resp.Summary.Created),
+			Updated:   int32(resp.Summary.Updated),
+			Unchanged: int32(resp.Summary.Unchanged),
+			Rejected:  int32(resp.Summary.Rejected),
+		},
+		Results: results,
 	}, nil
 }
 
+// jsonValue returns a JSON value as text, with null for a missing value
+func jsonValue(v json.RawMessage) string {
+	if v == nil {
+		return "null"
+	}
+	return string(v)
+}
+
+// grpcError converts a service error to a gRPC status error, attaching
+// the field violations for validation errors
+func grpcError(err error) error {
+	st := status.New(grpcCode(err), err.Error())
+
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		return st.Err()
+	}
+
+	br := &errdetails.BadRequest{}
+	for _, f := range ve.Fields {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       f.Field,
+			Description: f.Message,
+		})
+	}
+
+	detailed, e := st.WithDetails(br)
+	if e != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
 func modelsPlayerToProtoPlayer(p models.Player) pb.Player {
+	age := ""
+	if !p.BirthDate.IsZero() {
+		age = strconv.Itoa(p.Age())
+	}
 	return pb.Player{
-		Id:         int32(p.ID),
-		Name:       p.Name,
-		Number:     p.Number,
-		Position:   p.Position,
-		Height:     p.Height,
-		Weight:     p.Weight,
-		Age:        p.Age,
-		Experience: int32(p.Experience),
-		College:    p.College,
+		Id:           int32(p.ID),
+		Name:         p.Name,
+		Number:       p.Number.String(),
+		Position:     p.Position,
+		Height:       p.Height.String(),
+		Weight:       p.Weight.String(),
+		Age:          age,
+		Experience:   int32(p.Experience),
+		College:      p.College,
+		JerseyNumber: int32(p.Number),
+		HeightInches: int32(p.Height),
+		WeightPounds: int32(p.Weight),
+		BirthDate:    p.BirthDate.String(),
+		Version:      int32(p.Version),
+		UpdatedAt:    formatTimestamp(p.UpdatedAt),
+		DeletedAt:    formatDeletedAt(p.DeletedAt),
+	}
+}
+
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
+	return t.UTC().Format(time.RFC3339Nano)
 }
 
-func protoPlayerToModelsPlayer(p pb.Player) models.Player {
+func formatDeletedAt(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTimestamp(*t)
+}
+
+func protoPlayerToModelsPlayer(p pb.Player) (models.Player, error) {
+	// Older clients only send the string fields, so fall back to parsing them
+	number := models.Number(p.JerseyNumber)
+	if number == 0 && p.Number != "" {
+		n, err := models.ParseNumber(p.Number)
+		if err != nil {
+			return models.Player{}, err
+		}
+		number = n
+	}
+
+	height := models.Height(p.HeightInches)
+	if height == 0 && p.Height != "" {
+		h, err := models.ParseHeight(p.Height)
+		if err != nil {
+			return models.Player{}, err
+		}
+		height = h
+	}
+
+	weight := models.Weight(p.WeightPounds)
+	if weight == 0 && p.Weight != "" {
+		w, err := models.ParseWeight(p.Weight)
+		if err != nil {
+			return models.Player{}, err
+		}
+		weight = w
+	}
+
+	birthDate, err := models.ParseDate(p.BirthDate)
+	if err != nil {
+		return models.Player{}, err
+	}
+
 	return models.Player{
 		ID:         int(p.Id),
 		Name:       p.Name,
-		Number:     p.Number,
+		Number:     number,
 		Position:   p.Position,
-		Height:     p.Height,
-		Weight:     p.Weight,
-		Age:        p.Age,
+		Height:     height,
+		Weight:     weight,
+		BirthDate:  birthDate,
 		Experience: int(p.Experience),
 		College:    p.College,
-	}
+	}, nil
 }
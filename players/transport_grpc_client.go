@@ -0,0 +1,454 @@
+package players
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServiceName is the full name of the service in pb/players.proto
+const grpcServiceName = "pb.Players"
+
+// NewGRPCClient returns endpoints that call the gRPC transport over conn.
+// Failures the remote service reports are returned as the errors it
+// returned, so KindOf reports them the same; others, such as an
+// unreachable service, are returned as gRPC status errors. There's no
+// export RPC, so exports list the players a page at a time.
+func NewGRPCClient(conn *grpc.ClientConn, options ...kitgrpc.ClientOption) *Endpoints {
+	options = append([]kitgrpc.ClientOption{
+		kitgrpc.ClientBefore(populateGRPCClientActor),
+	}, options...)
+
+	newClient := func(method string, enc kitgrpc.EncodeRequestFunc, dec kitgrpc.DecodeResponseFunc, reply interface{}) endpoint.Endpoint {
+		return serviceErrorFromGRPC(kitgrpc.NewClient(conn, grpcServiceName, method, enc, dec, reply, options...).Endpoint())
+	}
+
+	listPlayers := newClient("ListPlayers", encodeGRPCListPlayersClientRequest, decodeGRPCListPlayersClientResponse, pb.ListPlayersResponse{})
+	return &Endpoints{
+		listPlayersEndpoint:   listPlayers,
+		exportPlayersEndpoint: makeGRPCExportPlayersClientEndpoint(listPlayers),
+		searchPlayersEndpoint: newClient("SearchPlayers", encodeGRPCSearchPlayersClientRequest, decodeGRPCSearchPlayersClientResponse, pb.SearchPlayersResponse{}),
+		getPlayerEndpoint:     newClient("GetPlayer", encodeGRPCGetPlayerClientRequest, decodeGRPCGetPlayerClientResponse, pb.GetPlayerResponse{}),
+		savePlayerEndpoint:    newClient("SavePlayer", encodeGRPCSavePlayerClientRequest, decodeGRPCSavePlayerClientResponse, pb.SavePlayerResponse{}),
+		patchPlayerEndpoint:   newClient("UpdatePlayer", encodeGRPCUpdatePlayerClientRequest, decodeGRPCUpdatePlayerClientResponse, pb.UpdatePlayerResponse{}),
+		deletePlayerEndpoint:  newClient("DeletePlayer", encodeGRPCDeletePlayerClientRequest, decodeGRPCDeletePlayerClientResponse, pb.DeletePlayerResponse{}),
+		restorePlayerEndpoint: newClient("RestorePlayer", encodeGRPCRestorePlayerClientRequest, decodeGRPCRestorePlayerClientResponse, pb.RestorePlayerResponse{}),
+		importPlayersEndpoint: newClient("ImportPlayers", encodeGRPCImportPlayersClientRequest, decodeGRPCImportPlayersClientResponse, pb.ImportPlayersResponse{}),
+		getHistoryEndpoint:    newClient("GetPlayerHistory", encodeGRPCGetPlayerHistoryClientRequest, decodeGRPCGetPlayerHistoryClientResponse, pb.GetPlayerHistoryResponse{}),
+	}
+}
+
+// populateGRPCClientActor passes the actor on from the context
+func populateGRPCClientActor(ctx context.Context, md *metadata.MD) context.Context {
+	if actor := models.ActorFrom(ctx); actor != models.Anonymous {
+		(*md)[ActorMetadataKey] = []string{actor}
+	}
+	return ctx
+}
+
+// serviceErrorFromGRPC turns the status errors a remote service reports
+// back into the errors it returned
+func serviceErrorFromGRPC(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		response, err := next(ctx, request)
+		if err != nil {
+			return nil, grpcServiceError(err)
+		}
+		return response, nil
+	}
+}
+
+// grpcServiceError reverses grpcError, leaving the statuses the service
+// doesn't report as they are
+func grpcServiceError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return err
+	}
+
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			ve := &ValidationError{}
+			for _, v := range br.FieldViolations {
+				ve.Fields = append(ve.Fields, FieldError{
+					Field:   v.Field,
+					Message: v.Description,
+				})
+			}
+			return ve
+		}
+	}
+	return newError(grpcCodeKind(st.Code()), st.Message())
+}
+
+// legacyGRPCError returns the failure a service reported in a deprecated
+// err field, whose kind is unknown
+func legacyGRPCError(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	return newError(KindInternal, msg)
+}
+
+// protoInt32 is the reverse of protoInt
+func protoInt32(n *int) *wrappers.Int32Value {
+	if n == nil {
+		return nil
+	}
+	return &wrappers.Int32Value{Value: int32(*n)}
+}
+
+// protoPlayerToSavedPlayer reads a player returned by the service,
+// including the fields only the service sets
+func protoPlayerToSavedPlayer(p *pb.Player) (*models.Player, error) {
+	if p == nil {
+		return nil, nil
+	}
+	player, err := protoPlayerToModelsPlayer(*p)
+	if err != nil {
+		return nil, err
+	}
+	player.Version = int(p.Version)
+	if p.UpdatedAt != "" {
+		if player.UpdatedAt, err = time.Parse(time.RFC3339Nano, p.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+	if p.DeletedAt != "" {
+		deletedAt, err := time.Parse(time.RFC3339Nano, p.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		player.DeletedAt = &deletedAt
+	}
+	return &player, nil
+}
+
+func encodeGRPCListPlayersClientRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(listPlayersRequest)
+	return &pb.ListPlayersRequest{
+		PageSize:       int32(req.PageSize),
+		PageToken:      req.PageToken,
+		Positions:      req.Filter.Positions,
+		College:        req.Filter.College,
+		NamePrefix:     req.Filter.NamePrefix,
+		MinExperience:  protoInt32(req.Filter.MinExperience),
+		MaxExperience:  protoInt32(req.Filter.MaxExperience),
+		Sort:           models.FormatSort(req.Filter.Sort),
+		IncludeDeleted: req.Filter.IncludeDeleted,
+	}, nil
+}
+
+func decodeGRPCListPlayersClientResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(*pb.ListPlayersResponse)
+	players := make([]models.Player, len(resp.Players))
+	for i, p := range resp.Players {
+		player, err := protoPlayerToSavedPlayer(p)
+		if err != nil {
+			return nil, err
+		}
+		players[i] = *player
+	}
+	return listPlayersResponse{
+		Players:       players,
+		NextPageToken: resp.NextPageToken,
+		Err:           legacyGRPCError(resp.Err),
+	}, nil
+}
+
+// makeGRPCExportPlayersClientEndpoint exports by listing the largest
+// pages it can. An empty page is reported as not found, which ends the
+// export.
+func makeGRPCExportPlayersClientEndpoint(listPlayers endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(exportPlayersRequest)
+		return exportPlayersResponse{
+			Format: req.Format,
+			Export: func(fn func(*models.Player) error) error {
+				page := listPlayersRequest{
+					Filter:   req.Filter,
+					PageSize: models.MaxPageSize,
+				}
+				for {
+					response, err := listPlayers(ctx, page)
+					if KindOf(err) == KindNotFound {
+						return nil
+					} else if err != nil {
+						return err
+					}
+					resp := response.(listPlayersResponse)
+					if resp.Err != nil {
+						return resp.Err
+					}
+					for i := range resp.Players {
+						if err := fn(&resp.Players[i]); err != nil {
+							return err
+						}
+					}
+					if resp.NextPageToken == "" {
+						return nil
+					}
+					page.PageToken = resp.NextPageToken
+				}
+			},
+		}, nil
+	}
+}
+
+func encodeGRPCSearchPlayersClientRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(searchPlayersRequest)
+	return &pb.SearchPlayersRequest{
+		Query: req.Query,
+		Limit: int32(req.Limit),
+	}, nil
+}
+
+func decodeGRPCSearchPlayersClientResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(*pb.SearchPlayersResponse)
+	results := make([]models.SearchResult, len(resp.Results))
+	for i, result := range resp.Results {
+		player, err := protoPlayerToSavedPlayer(result.Player)
+		if err != nil {
+			return nil, err
+		}
+		if player != nil {
+			results[i].Player = *player
+		}
+		results[i].Rank = result.Rank
+		results[i].Highlight = result.Highlight
+	}
+	return searchPlayersResponse{
+		Results: results,
+	}, nil
+}
+
+func encodeGRPCGetPlayerClientRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(getPlayerRequest)
+	return &pb.GetPlayerRequest{
+		Id:             int32(req.ID),
+		IncludeDeleted: req.IncludeDeleted,
+	}, nil
+}
+
+func decodeGRPCGetPlayerClientResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(*pb.GetPlayerResponse)
+	player, err := protoPlayerToSavedPlayer(resp.Player)
+	if err != nil {
+		return nil, err
+	}
+	return getPlayerResponse{
+		Player: player,
+		Err:    legacyGRPCError(resp.Err),
+	}, nil
+}
+
+func encodeGRPCSavePlayerClientRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(savePlayerRequest)
+	player := modelsPlayerToProtoPlayer(*req.Player)
+	return &pb.SavePlayerRequest{
+		Player:          &player,
+		ExpectedVersion: int32(req.Player.Version),
+	}, nil
+}
+
+func decodeGRPCSavePlayerClientResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(*pb.SavePlayerResponse)
+	player, err := protoPlayerToSavedPlayer(resp.Player)
+	if err != nil {
+		return nil, err
+	}
+	return savePlayerResponse{
+		Player:  player,
+		Created: resp.Created,
+		Err:     legacyGRPCError(resp.Err),
+	}, nil
+}
+
+// encodeGRPCUpdatePlayerClientRequest sends the fields a patch sets, and
+// masks the rest
+func encodeGRPCUpdatePlayerClientRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(patchPlayerRequest)
+	player := pb.Player{
+		Id: int32(req.ID),
+	}
+	mask := &field_mask.FieldMask{}
+	if req.Patch.Name != nil {
+		player.Name = *req.Patch.Name
+		mask.Paths = append(mask.Paths, "name")
+	}
+	if req.Patch.Number != nil {
+		player.JerseyNumber = int32(*req.Patch.Number)
+		mask.Paths = append(mask.Paths, "jersey_number")
+	}
+	if req.Patch.Position != nil {
+		player.Position = *req.Patch.Position
+		mask.Paths = append(mask.Paths, "position")
+	}
+	if req.Patch.Height != nil {
+		player.HeightInches = int32(*req.Patch.Height)
+		mask.Paths = append(mask.Paths, "height_inches")
+	}
+	if req.Patch.Weight != nil {
+		player.WeightPounds = int32(*req.Patch.Weight)
+		mask.Paths = append(mask.Paths, "weight_pounds")
+	}
+	if req.Patch.BirthDate != nil {
+		player.BirthDate = req.Patch.BirthDate.String()
+		mask.Paths = append(mask.Paths, "birth_date")
+	}
+	if req.Patch.Experience != nil {
+		player.Experience = int32(*req.Patch.Experience)
+		mask.Paths = append(mask.Paths, "experience")
+	}
+	if req.Patch.College != nil {
+		player.College = *req.Patch.College
+		mask.Paths = append(mask.Paths, "college")
+	}
+	return &pb.UpdatePlayerRequest{
+		Player:          &player,
+		UpdateMask:      mask,
+		ExpectedVersion: int32(req.Patch.Version),
+	}, nil
+}
+
+func decodeGRPCUpdatePlayerClientResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(*pb.UpdatePlayerResponse)
+	player, err := protoPlayerToSavedPlayer(resp.Player)
+	if err != nil {
+		return nil, err
+	}
+	return patchPlayerResponse{
+		Player: player,
+	}, nil
+}
+
+func encodeGRPCDeletePlayerClientRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(deletePlayerRequest)
+	return &pb.DeletePlayerRequest{
+		Id:              int32(req.ID),
+		ExpectedVersion: int32(req.Version),
+	}, nil
+}
+
+func decodeGRPCDeletePlayerClientResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(*pb.DeletePlayerResponse)
+	return deletePlayerResponse{
+		Err: legacyGRPCError(resp.Err),
+	}, nil
+}
+
+func encodeGRPCRestorePlayerClientRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(restorePlayerRequest)
+	return &pb.RestorePlayerRequest{
+		Id:              int32(req.ID),
+		ExpectedVersion: int32(req.Version),
+	}, nil
+}
+
+func decodeGRPCRestorePlayerClientResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(*pb.RestorePlayerResponse)
+	player, err := protoPlayerToSavedPlayer(resp.Player)
+	if err != nil {
+		return nil, err
+	}
+	return restorePlayerResponse{
+		Player: player,
+	}, nil
+}
+
+func encodeGRPCImportPlayersClientRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(importPlayersRequest)
+	rows := make([]*pb.ImportRow, len(req.Rows))
+	for i, row := range req.Rows {
+		player := modelsPlayerToProtoPlayer(row.Player)
+		rows[i] = &pb.ImportRow{
+			Row:    int32(row.Row),
+			Player: &player,
+			Errors: row.Errors,
+			Fields: row.Fields,
+		}
+	}
+	return &pb.ImportPlayersRequest{
+		Rows: rows,
+	}, nil
+}
+
+func decodeGRPCImportPlayersClientResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(*pb.ImportPlayersResponse)
+	results := make([]models.ImportResult, len(resp.Results))
+	for i, result := range resp.Results {
+		results[i] = models.ImportResult{
+			Row:    int(result.Row),
+			Status: result.Status,
+			ID:     int(result.Id),
+			Name:   result.Name,
+			Errors: result.Errors,
+		}
+	}
+	return importPlayersResponse{
+		Summary: models.SummarizeImport(results),
+		Results: results,
+	}, nil
+}
+
+func encodeGRPCGetPlayerHistoryClientRequest(_ context.Context, r interface{}) (interface{}, error) {
+	req := r.(getHistoryRequest)
+	return &pb.GetPlayerHistoryRequest{
+		Id: int32(req.ID),
+	}, nil
+}
+
+func decodeGRPCGetPlayerHistoryClientResponse(_ context.Context, r interface{}) (interface{}, error) {
+	resp := r.(*pb.GetPlayerHistoryResponse)
+	history := make([]models.HistoryEntry, len(resp.Entries))
+	for i, e := range resp.Entries {
+		changedAt, err := time.Parse(time.RFC3339Nano, e.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes := make(models.Changes, len(e.Changes))
+		for j, c := range e.Changes {
+			changes[j] = models.FieldChange{
+				Field:  c.Field,
+				Before: rawJSONValue(c.Before),
+				After:  rawJSONValue(c.After),
+			}
+		}
+		history[i] = models.HistoryEntry{
+			ID:        int(e.Id),
+			PlayerID:  int(e.PlayerId),
+			Version:   int(e.Version),
+			Operation: e.Operation,
+			Actor:     e.Actor,
+			ChangedAt: changedAt,
+			Changes:   changes,
+		}
+	}
+	return getHistoryResponse{
+		History: history,
+	}, nil
+}
+
+// rawJSONValue is the reverse of jsonValue
+func rawJSONValue(s string) json.RawMessage {
+	if s == "null" {
+		return nil
+	}
+	return json.RawMessage(s)
+}
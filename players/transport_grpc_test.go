@@ -5,11 +5,18 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/hoop33/roster/models"
 	"github.com/hoop33/roster/pb"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 )
 
@@ -22,10 +29,10 @@ func TestGRPCListPlayersShouldReturnPlayersWhenDatabaseReturnsPlayers(t *testing
 		AddRow(1, "Blake Bortles", "5").
 		AddRow(2, "Jalen Ramsey", "20")
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC, id ASC LIMIT \$1$`).
 		WillReturnRows(rows)
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	tr := NewGRPCTransport(es, log.NewNopLogger())
 	req := &pb.ListPlayersRequest{}
@@ -36,15 +43,77 @@ func TestGRPCListPlayersShouldReturnPlayersWhenDatabaseReturnsPlayers(t *testing
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
+func TestGRPCListPlayersShouldReturnNextPageToken(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	for _, p := range []models.Player{
+		{Name: "Blake Bortles", Number: 5},
+		{Name: "Jalen Ramsey", Number: 20},
+	} {
+		p := p
+		_, _, err := repo.SavePlayer(context.Background(), &p)
+		assert.Nil(t, err)
+	}
+
+	tr := NewGRPCTransport(NewEndpoints(NewService(repo)), log.NewNopLogger())
+	resp, err := tr.ListPlayers(context.Background(), &pb.ListPlayersRequest{PageSize: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(resp.GetPlayers()))
+	assert.NotEqual(t, "", resp.GetNextPageToken())
+
+	resp, err = tr.ListPlayers(context.Background(), &pb.ListPlayersRequest{PageSize: 1, PageToken: resp.GetNextPageToken()})
+	assert.Nil(t, err)
+	assert.Equal(t, "Jalen Ramsey", resp.GetPlayers()[0].GetName())
+	assert.Equal(t, "", resp.GetNextPageToken())
+}
+
+func TestDecodeGRPCListPlayersRequestShouldReadFilter(t *testing.T) {
+	r, err := decodeGRPCListPlayersRequest(context.Background(), &pb.ListPlayersRequest{
+		Position:      "QB",
+		Positions:     []string{"RB"},
+		College:       "LSU",
+		NamePrefix:    "leo",
+		MaxExperience: &wrappers.Int32Value{Value: 0},
+		Sort:          "-experience",
+	})
+	assert.Nil(t, err)
+
+	f := r.(listPlayersRequest).Filter
+	assert.Equal(t, []string{"RB", "QB"}, f.Positions)
+	assert.Equal(t, "LSU", f.College)
+	assert.Equal(t, "leo", f.NamePrefix)
+	assert.Nil(t, f.MinExperience)
+	assert.Equal(t, 0, *f.MaxExperience)
+	assert.Equal(t, "-experience", models.FormatSort(f.Sort))
+}
+
+func TestGRPCSearchPlayersShouldReturnResults(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Leonard Fournette", Number: 27})
+	assert.Nil(t, err)
+
+	tr := NewGRPCTransport(NewEndpoints(NewService(repo)), log.NewNopLogger())
+	resp, err := tr.SearchPlayers(context.Background(), &pb.SearchPlayersRequest{Query: "leo"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(resp.GetResults()))
+	assert.Equal(t, "Leonard Fournette", resp.GetResults()[0].GetPlayer().GetName())
+	assert.Equal(t, "<em>Leonard</em> Fournette", resp.GetResults()[0].GetHighlight())
+}
+
+func TestGRPCListPlayersShouldReturnInvalidArgumentWhenPageTokenInvalid(t *testing.T) {
+	tr := NewGRPCTransport(NewEndpoints(NewService(models.NewMemoryRepository())), log.NewNopLogger())
+	_, err := tr.ListPlayers(context.Background(), &pb.ListPlayersRequest{PageToken: "!"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
 func TestGRPCListPlayersShouldReturnErrorWhenDatabaseReturnsNoPlayers(t *testing.T) {
 	db, mock, err := createDB()
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC, id ASC LIMIT \$1$`).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "number"}))
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	tr := NewGRPCTransport(es, log.NewNopLogger())
 	req := &pb.ListPlayersRequest{}
@@ -52,7 +121,7 @@ func TestGRPCListPlayersShouldReturnErrorWhenDatabaseReturnsNoPlayers(t *testing
 	assert.Error(t, errNotFound, err)
 	players := resp.GetPlayers()
 	assert.Equal(t, 0, len(players))
-	assert.Equal(t, "not found", resp.GetErr())
+	assert.Equal(t, codes.NotFound, status.Code(err))
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
@@ -61,10 +130,10 @@ func TestGRPCListPlayersShouldReturnErrorWhenDatabaseReturnsError(t *testing.T)
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC, id ASC LIMIT \$1$`).
 		WillReturnError(errors.New("database error"))
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	tr := NewGRPCTransport(es, log.NewNopLogger())
 	req := &pb.ListPlayersRequest{}
@@ -72,7 +141,7 @@ func TestGRPCListPlayersShouldReturnErrorWhenDatabaseReturnsError(t *testing.T)
 	assert.Error(t, errNotFound, err)
 	players := resp.GetPlayers()
 	assert.Equal(t, 0, len(players))
-	assert.Equal(t, "database error", resp.GetErr())
+	assert.Equal(t, codes.Internal, status.Code(err))
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
@@ -84,11 +153,11 @@ func TestGRPCGetPlayerShouldReturnPlayerWhenDatabaseReturnsPlayer(t *testing.T)
 	rows := sqlmock.NewRows([]string{"id", "name", "number"}).
 		AddRow(1, "Blake Bortles", "5")
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnRows(rows)
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	tr := NewGRPCTransport(es, log.NewNopLogger())
 	req := &pb.GetPlayerRequest{
@@ -105,19 +174,19 @@ func TestGRPCGetPlayerShouldReturnErrorWhenDatabaseReturnsNoRows(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnError(sql.ErrNoRows)
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	tr := NewGRPCTransport(es, log.NewNopLogger())
 	req := &pb.GetPlayerRequest{
 		Id: 1,
 	}
 	resp, err := tr.GetPlayer(context.Background(), req)
-	assert.Nil(t, err)
-	assert.Equal(t, "not found", resp.GetErr())
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Nil(t, resp)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
@@ -126,19 +195,20 @@ func TestGRPCGetPlayerShouldReturnErrorWhenDatabaseReturnsError(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnError(errors.New("database error"))
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	tr := NewGRPCTransport(es, log.NewNopLogger())
 	req := &pb.GetPlayerRequest{
 		Id: 1,
 	}
 	resp, err := tr.GetPlayer(context.Background(), req)
-	assert.Nil(t, err)
-	assert.Equal(t, "database error", resp.GetErr())
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "database error", status.Convert(err).Message())
+	assert.Nil(t, resp)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
@@ -149,22 +219,25 @@ func TestGRPCSavePlayerShouldCreatePlayerWhenNoID(t *testing.T) {
 
 	p := &models.Player{
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
+	mock.ExpectBegin()
 	mock.ExpectQuery(`^INSERT INTO players
-		\(name, number, position, height, weight, age, experience, college\)
-		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)
+		\(name, number, position, height, weight, birth_date, experience, college, updated_at\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\)
 		RETURNING id$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectHistory(mock, models.OperationCreate)
+	mock.ExpectCommit()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	player := modelsPlayerToProtoPlayer(*p)
 	tr := NewGRPCTransport(es, log.NewNopLogger())
@@ -186,21 +259,26 @@ func TestGRPCSavePlayerShouldUpdatePlayerWhenHasID(t *testing.T) {
 	p := &models.Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
-	mock.ExpectExec(`^UPDATE players
-		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, age=\$6, experience=\$7, college=\$8
-		WHERE id=\$9$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	es := NewEndpoints(NewService(db))
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, birth_date=\$6, experience=\$7, college=\$8, updated_at=\$9, version=version\+1
+		WHERE id=\$10 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg(), p.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	expectHistory(mock, models.OperationUpdate)
+	mock.ExpectCommit()
+
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	player := modelsPlayerToProtoPlayer(*p)
 	tr := NewGRPCTransport(es, log.NewNopLogger())
@@ -221,22 +299,24 @@ func TestGRPCSavePlayerShouldReturnErrorWhenCreateFails(t *testing.T) {
 
 	p := &models.Player{
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
+	mock.ExpectBegin()
 	mock.ExpectQuery(`^INSERT INTO players
-		\(name, number, position, height, weight, age, experience, college\)
-		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)
+		\(name, number, position, height, weight, birth_date, experience, college, updated_at\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\)
 		RETURNING id$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg()).
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	player := modelsPlayerToProtoPlayer(*p)
 	tr := NewGRPCTransport(es, log.NewNopLogger())
@@ -244,8 +324,9 @@ func TestGRPCSavePlayerShouldReturnErrorWhenCreateFails(t *testing.T) {
 		Player: &player,
 	}
 	resp, err := tr.SavePlayer(context.Background(), req)
-	assert.Nil(t, err)
-	assert.Equal(t, "database error", resp.GetErr())
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "database error", status.Convert(err).Message())
+	assert.Nil(t, resp)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
@@ -257,21 +338,19 @@ func TestGRPCSavePlayerShouldReturnErrorWhenNotFound(t *testing.T) {
 	p := &models.Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
-	mock.ExpectExec(`^UPDATE players
-		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, age=\$6, experience=\$7, college=\$8
-		WHERE id=\$9$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID).
-		WillReturnError(sql.ErrNoRows)
+	mock.ExpectBegin()
+	expectLock(mock, 1, false)
+	mock.ExpectRollback()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	player := modelsPlayerToProtoPlayer(*p)
 	tr := NewGRPCTransport(es, log.NewNopLogger())
@@ -279,8 +358,8 @@ func TestGRPCSavePlayerShouldReturnErrorWhenNotFound(t *testing.T) {
 		Player: &player,
 	}
 	resp, err := tr.SavePlayer(context.Background(), req)
-	assert.Nil(t, err)
-	assert.Equal(t, "not found", resp.GetErr())
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Nil(t, resp)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
@@ -292,21 +371,25 @@ func TestGRPCSavePlayerShouldReturnErrorWhenDatabaseReturnsError(t *testing.T) {
 	p := &models.Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
-	mock.ExpectExec(`^UPDATE players
-		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, age=\$6, experience=\$7, college=\$8
-		WHERE id=\$9$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID).
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, birth_date=\$6, experience=\$7, college=\$8, updated_at=\$9, version=version\+1
+		WHERE id=\$10 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg(), p.ID).
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	player := modelsPlayerToProtoPlayer(*p)
 	tr := NewGRPCTransport(es, log.NewNopLogger())
@@ -314,8 +397,9 @@ func TestGRPCSavePlayerShouldReturnErrorWhenDatabaseReturnsError(t *testing.T) {
 		Player: &player,
 	}
 	resp, err := tr.SavePlayer(context.Background(), req)
-	assert.Nil(t, err)
-	assert.Equal(t, "database error", resp.GetErr())
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "database error", status.Convert(err).Message())
+	assert.Nil(t, resp)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
@@ -324,12 +408,18 @@ func TestGRPCDeletePlayerShouldReturnNoErrorWhenDeleteSucceeds(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	expectHistory(mock, models.OperationDelete)
+	mock.ExpectCommit()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	tr := NewGRPCTransport(es, log.NewNopLogger())
 	req := &pb.DeletePlayerRequest{
@@ -346,20 +436,19 @@ func TestGRPCDeletePlayerShouldReturnErrorWhenNotFound(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
-		WillReturnError(sql.ErrNoRows)
+	mock.ExpectBegin()
+	expectLock(mock, 1, false)
+	mock.ExpectRollback()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	tr := NewGRPCTransport(es, log.NewNopLogger())
 	req := &pb.DeletePlayerRequest{
 		Id: 1,
 	}
 	resp, err := tr.DeletePlayer(context.Background(), req)
-	assert.Nil(t, err)
-	assert.Equal(t, "not found", resp.GetErr())
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Nil(t, resp)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
@@ -368,19 +457,249 @@ func TestGRPCDeletePlayerShouldReturnErrorWhenDatabaseReturnsError(t *testing.T)
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	tr := NewGRPCTransport(es, log.NewNopLogger())
 	req := &pb.DeletePlayerRequest{
 		Id: 1,
 	}
 	resp, err := tr.DeletePlayer(context.Background(), req)
-	assert.Nil(t, err)
-	assert.Equal(t, "database error", resp.GetErr())
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "database error", status.Convert(err).Message())
+	assert.Nil(t, resp)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
+
+func TestProtoPlayerToModelsPlayerShouldParseLegacyStringFields(t *testing.T) {
+	p, err := protoPlayerToModelsPlayer(pb.Player{
+		Name:      "Blake Bortles",
+		Number:    "5",
+		Height:    "6-5",
+		Weight:    "236",
+		BirthDate: "1992-04-29",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, models.Number(5), p.Number)
+	assert.Equal(t, models.Height(77), p.Height)
+	assert.Equal(t, models.Weight(236), p.Weight)
+	assert.Equal(t, models.NewDate(1992, time.April, 29), p.BirthDate)
+}
+
+func TestProtoPlayerToModelsPlayerShouldPreferTypedFields(t *testing.T) {
+	p, err := protoPlayerToModelsPlayer(pb.Player{
+		Number:       "5",
+		JerseyNumber: 6,
+		HeightInches: 76,
+		WeightPounds: 220,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, models.Number(6), p.Number)
+	assert.Equal(t, models.Height(76), p.Height)
+	assert.Equal(t, models.Weight(220), p.Weight)
+}
+
+func TestProtoPlayerToModelsPlayerShouldReturnErrorWhenLegacyFieldInvalid(t *testing.T) {
+	_, err := protoPlayerToModelsPlayer(pb.Player{
+		Height: "tall",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestGRPCSavePlayerShouldReturnInvalidArgumentWhenInvalid(t *testing.T) {
+	es := NewEndpoints(NewValidatingService(NewService(models.NewMemoryRepository())))
+
+	tr := NewGRPCTransport(es, log.NewNopLogger())
+	req := &pb.SavePlayerRequest{
+		Player: &pb.Player{
+			Position: "QB",
+		},
+	}
+	resp, err := tr.SavePlayer(context.Background(), req)
+	assert.Nil(t, resp)
+
+	st, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+
+	details := st.Details()
+	assert.Equal(t, 1, len(details))
+	br, ok := details[0].(*errdetails.BadRequest)
+	assert.True(t, ok)
+	assert.Equal(t, "name", br.GetFieldViolations()[0].GetField())
+}
+
+func TestGRPCSavePlayerShouldReturnInvalidArgumentWhenPlayerMissing(t *testing.T) {
+	tr := NewGRPCTransport(NewEndpoints(NewValidatingService(NewService(models.NewMemoryRepository()))), log.NewNopLogger())
+	resp, err := tr.SavePlayer(context.Background(), &pb.SavePlayerRequest{})
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "player is required", status.Convert(err).Message())
+}
+
+func TestGRPCShouldReturnErrFieldWithOKStatusWhenLegacyErrors(t *testing.T) {
+	es := NewEndpoints(NewService(models.NewMemoryRepository()))
+
+	tr := NewGRPCTransport(es, log.NewNopLogger(), LegacyErrors())
+	resp, err := tr.GetPlayer(context.Background(), &pb.GetPlayerRequest{Id: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, "not found", resp.GetErr())
+
+	dresp, err := tr.DeletePlayer(context.Background(), &pb.DeletePlayerRequest{Id: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, "not found", dresp.GetErr())
+}
+
+func TestGRPCShouldReturnValidationErrorInErrFieldWhenLegacyErrors(t *testing.T) {
+	es := NewEndpoints(NewValidatingService(NewService(models.NewMemoryRepository())))
+
+	tr := NewGRPCTransport(es, log.NewNopLogger(), LegacyErrors())
+	resp, err := tr.SavePlayer(context.Background(), &pb.SavePlayerRequest{
+		Player: &pb.Player{
+			Position: "QB",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "invalid player: name is required", resp.GetErr())
+	assert.False(t, resp.GetCreated())
+}
+
+func TestGRPCUpdatePlayerShouldChangeOnlyMaskedFields(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB", College: "Florida State"})
+	assert.Nil(t, err)
+
+	tr := NewGRPCTransport(NewEndpoints(NewValidatingService(NewService(repo))), log.NewNopLogger())
+	resp, err := tr.UpdatePlayer(context.Background(), &pb.UpdatePlayerRequest{
+		Player:     &pb.Player{Id: 1, JerseyNumber: 33},
+		UpdateMask: &field_mask.FieldMask{Paths: []string{"jersey_number", "college"}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "Jalen Ramsey", resp.GetPlayer().GetName())
+	assert.Equal(t, int32(33), resp.GetPlayer().GetJerseyNumber())
+	assert.Equal(t, "CB", resp.GetPlayer().GetPosition())
+	assert.Equal(t, "", resp.GetPlayer().GetCollege())
+}
+
+func TestGRPCUpdatePlayerShouldRejectInvalidRequests(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+	tr := NewGRPCTransport(NewEndpoints(NewValidatingService(NewService(repo))), log.NewNopLogger())
+
+	for _, tc := range []struct {
+		req  *pb.UpdatePlayerRequest
+		code codes.Code
+	}{
+		{&pb.UpdatePlayerRequest{Player: &pb.Player{Id: 1}}, codes.InvalidArgument},
+		{&pb.UpdatePlayerRequest{UpdateMask: &field_mask.FieldMask{Paths: []string{"name"}}}, codes.InvalidArgument},
+		{&pb.UpdatePlayerRequest{Player: &pb.Player{Id: 1}, UpdateMask: &field_mask.FieldMask{Paths: []string{"salary"}}}, codes.InvalidArgument},
+		{&pb.UpdatePlayerRequest{Player: &pb.Player{Id: 1}, UpdateMask: &field_mask.FieldMask{Paths: []string{"id"}}}, codes.InvalidArgument},
+		{&pb.UpdatePlayerRequest{Player: &pb.Player{Id: 1, JerseyNumber: 100}, UpdateMask: &field_mask.FieldMask{Paths: []string{"jersey_number"}}}, codes.InvalidArgument},
+		{&pb.UpdatePlayerRequest{Player: &pb.Player{Id: 2, Name: "Cody Kessler"}, UpdateMask: &field_mask.FieldMask{Paths: []string{"name"}}}, codes.NotFound},
+	} {
+		_, err := tr.UpdatePlayer(context.Background(), tc.req)
+		assert.Equal(t, tc.code, status.Code(err), tc.req.String())
+	}
+}
+
+func TestGRPCShouldEnforceExpectedVersion(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+	tr := NewGRPCTransport(NewEndpoints(NewValidatingService(NewService(repo))), log.NewNopLogger())
+
+	sresp, err := tr.SavePlayer(context.Background(), &pb.SavePlayerRequest{
+		Player:          &pb.Player{Id: 1, Name: "Jalen Ramsey", JerseyNumber: 5, Position: "CB"},
+		ExpectedVersion: 1,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), sresp.GetPlayer().GetVersion())
+
+	_, err = tr.SavePlayer(context.Background(), &pb.SavePlayerRequest{
+		Player:          &pb.Player{Id: 1, Name: "Jalen Ramsey", JerseyNumber: 20, Position: "CB"},
+		ExpectedVersion: 1,
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = tr.UpdatePlayer(context.Background(), &pb.UpdatePlayerRequest{
+		Player:          &pb.Player{Id: 1, JerseyNumber: 20},
+		UpdateMask:      &field_mask.FieldMask{Paths: []string{"jersey_number"}},
+		ExpectedVersion: 1,
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = tr.DeletePlayer(context.Background(), &pb.DeletePlayerRequest{Id: 1, ExpectedVersion: 1})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = tr.DeletePlayer(context.Background(), &pb.DeletePlayerRequest{Id: 1, ExpectedVersion: 2})
+	assert.Nil(t, err)
+}
+
+func TestGRPCGetPlayerHistoryShouldReturnChangesByActor(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	tr := NewGRPCTransport(NewEndpoints(NewService(repo)), log.NewNopLogger())
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ActorMetadataKey, "jaguars"))
+	_, err := tr.SavePlayer(ctx, &pb.SavePlayerRequest{
+		Player: &pb.Player{Name: "Jalen Ramsey", JerseyNumber: 20, Position: "CB"},
+	})
+	assert.Nil(t, err)
+	_, err = tr.DeletePlayer(ctx, &pb.DeletePlayerRequest{Id: 1})
+	assert.Nil(t, err)
+
+	resp, err := tr.GetPlayerHistory(context.Background(), &pb.GetPlayerHistoryRequest{Id: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(resp.GetEntries()))
+
+	created := resp.GetEntries()[0]
+	assert.Equal(t, "create", created.GetOperation())
+	assert.Equal(t, "jaguars", created.GetActor())
+	assert.Equal(t, int32(1), created.GetVersion())
+	assert.Equal(t, "name", created.GetChanges()[0].GetField())
+	assert.Equal(t, "null", created.GetChanges()[0].GetBefore())
+	assert.Equal(t, `"Jalen Ramsey"`, created.GetChanges()[0].GetAfter())
+	assert.Equal(t, "delete", resp.GetEntries()[1].GetOperation())
+
+	_, err = tr.GetPlayerHistory(context.Background(), &pb.GetPlayerHistoryRequest{Id: 2})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCRestorePlayerShouldUndeletePlayer(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	tr := NewGRPCTransport(NewEndpoints(NewService(repo)), log.NewNopLogger())
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+	_, err = tr.DeletePlayer(context.Background(), &pb.DeletePlayerRequest{Id: 1})
+	assert.Nil(t, err)
+
+	_, err = tr.GetPlayer(context.Background(), &pb.GetPlayerRequest{Id: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	gresp, err := tr.GetPlayer(context.Background(), &pb.GetPlayerRequest{Id: 1, IncludeDeleted: true})
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", gresp.GetPlayer().GetDeletedAt())
+	lresp, err := tr.ListPlayers(context.Background(), &pb.ListPlayersRequest{IncludeDeleted: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(lresp.GetPlayers()))
+
+	_, err = tr.RestorePlayer(context.Background(), &pb.RestorePlayerRequest{Id: 1, ExpectedVersion: 1})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	resp, err := tr.RestorePlayer(context.Background(), &pb.RestorePlayerRequest{Id: 1, ExpectedVersion: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, "Jalen Ramsey", resp.GetPlayer().GetName())
+	assert.Equal(t, int32(3), resp.GetPlayer().GetVersion())
+	assert.Equal(t, "", resp.GetPlayer().GetDeletedAt())
+
+	_, err = tr.RestorePlayer(context.Background(), &pb.RestorePlayerRequest{Id: 1})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
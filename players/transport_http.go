@@ -2,25 +2,75 @@ package players
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/gorilla/mux"
 	"github.com/hoop33/roster/models"
 )
 
-var errBadRoute = errors.New("bad route")
-var errBadRequest = errors.New("bad request")
+var errBadRoute = newError(KindInternal, "bad route")
+var errBadRequest = newError(KindInvalidArgument, "bad request")
+var errIfMatch = newError(KindPreconditionFailed, "If-Match doesn't match any version")
+var errImportTooLarge = newError(KindTooLarge, fmt.Sprintf("import is larger than %d bytes", MaxImportBytes))
+
+// ActorHeader names the HTTP header identifying who is making a change,
+// for the player history. The service doesn't authenticate callers, so
+// it's taken on trust.
+const ActorHeader = "X-User"
+
+// MaxImportBytes limits the size of an import's CSV body; larger bodies
+// get 413 Request Entity Too Large
+const MaxImportBytes = 10 << 20
+
+// DefaultCacheControl makes clients revalidate cached players on every
+// use, which is cheap with conditional requests
+const DefaultCacheControl = "no-cache"
+
+type httpTransport struct {
+	cacheControl string
+}
+
+// HTTPOption sets an optional parameter for the HTTP transport
+type HTTPOption func(*httpTransport)
+
+// CacheControl sets the Cache-Control header sent with players, e.g.,
+// "public, max-age=60"; an empty value sends none
+func CacheControl(value string) HTTPOption {
+	return func(t *httpTransport) {
+		t.cacheControl = value
+	}
+}
 
 // NewHTTPTransport returns a handler for HTTP transport
-func NewHTTPTransport(ep *Endpoints, logger log.Logger) http.Handler {
+func NewHTTPTransport(ep *Endpoints, logger log.Logger, options ...HTTPOption) http.Handler {
+	return accessControl(newHTTPRouter(ep, logger, options...))
+}
+
+// newHTTPRouter routes requests to the endpoints. Each route must be
+// described in apiOperations.
+func newHTTPRouter(ep *Endpoints, logger log.Logger, options ...HTTPOption) *mux.Router {
+	t := &httpTransport{
+		cacheControl: DefaultCacheControl,
+	}
+	for _, option := range options {
+		option(t)
+	}
+
 	opts := []kithttp.ServerOption{
 		kithttp.ServerErrorLogger(log.With(logger, "tag", "http")),
 		kithttp.ServerErrorEncoder(encodeHTTPError),
+		kithttp.ServerBefore(kithttp.PopulateRequestContext),
+		kithttp.ServerBefore(populateCacheContext(t.cacheControl)),
+		kithttp.ServerBefore(populateHTTPActor),
 	}
 
 	listPlayersHandler := kithttp.NewServer(
@@ -30,6 +80,20 @@ func NewHTTPTransport(ep *Endpoints, logger log.Logger) http.Handler {
 		opts...,
 	)
 
+	exportPlayersHandler := kithttp.NewServer(
+		ep.exportPlayersEndpoint,
+		decodeHTTPExportPlayersRequest,
+		encodeHTTPExportPlayersResponse,
+		opts...,
+	)
+
+	searchPlayersHandler := kithttp.NewServer(
+		ep.searchPlayersEndpoint,
+		decodeHTTPSearchPlayersRequest,
+		encodeHTTPSearchPlayersResponse,
+		opts...,
+	)
+
 	getPlayerHandler := kithttp.NewServer(
 		ep.getPlayerEndpoint,
 		decodeHTTPGetPlayerRequest,
@@ -51,6 +115,13 @@ func NewHTTPTransport(ep *Endpoints, logger log.Logger) http.Handler {
 		opts...,
 	)
 
+	patchPlayerHandler := kithttp.NewServer(
+		ep.patchPlayerEndpoint,
+		decodeHTTPPatchPlayerRequest,
+		encodeHTTPPatchPlayerResponse,
+		opts...,
+	)
+
 	deletePlayerHandler := kithttp.NewServer(
 		ep.deletePlayerEndpoint,
 		decodeHTTPDeletePlayerRequest,
@@ -58,21 +129,53 @@ func NewHTTPTransport(ep *Endpoints, logger log.Logger) http.Handler {
 		opts...,
 	)
 
-	r := mux.NewRouter()
-	r.Handle("/v1/players", listPlayersHandler).Methods("GET")
-	r.Handle("/v1/players/{id}", getPlayerHandler).Methods("GET")
-	r.Handle("/v1/players", createPlayerHandler).Methods("POST")
-	r.Handle("/v1/players/{id}", updatePlayerHandler).Methods("PUT")
-	r.Handle("/v1/players/{id}", deletePlayerHandler).Methods("DELETE")
+	restorePlayerHandler := kithttp.NewServer(
+		ep.restorePlayerEndpoint,
+		decodeHTTPRestorePlayerRequest,
+		encodeHTTPRestorePlayerResponse,
+		opts...,
+	)
 
-	return accessControl(r)
+	importPlayersHandler := kithttp.NewServer(
+		ep.importPlayersEndpoint,
+		decodeHTTPImportPlayersRequest,
+		encodeHTTPImportPlayersResponse,
+		opts...,
+	)
+
+	getHistoryHandler := kithttp.NewServer(
+		ep.getHistoryEndpoint,
+		decodeHTTPGetHistoryRequest,
+		encodeHTTPGetHistoryResponse,
+		opts...,
+	)
+
+	negotiated := negotiate(responseMediaTypes...)
+	matched := matchAnyTag(ep.getPlayerEndpoint)
+
+	r := mux.NewRouter()
+	r.Handle("/v1/players", negotiated(listPlayersHandler)).Methods("GET")
+	r.Handle("/v1/players/search", negotiated(searchPlayersHandler)).Methods("GET")
+	r.Handle("/v1/players/export", negotiateExport(exportPlayersHandler)).Methods("GET")
+	r.Handle("/v1/players/{id}", negotiated(getPlayerHandler)).Methods("GET")
+	r.Handle("/v1/players/{id}/history", negotiated(getHistoryHandler)).Methods("GET")
+	r.Handle("/v1/players", negotiated(createPlayerHandler)).Methods("POST")
+	r.Handle("/v1/players:import", negotiated(importPlayersHandler)).Methods("POST")
+	r.Handle("/v1/players/{id}:restore", negotiated(matched(restorePlayerHandler))).Methods("POST")
+	r.Handle("/v1/players/{id}", negotiated(matched(updatePlayerHandler))).Methods("PUT")
+	r.Handle("/v1/players/{id}", negotiated(matched(patchPlayerHandler))).Methods("PATCH")
+	r.Handle("/v1/players/{id}", negotiated(matched(deletePlayerHandler))).Methods("DELETE")
+	r.Handle(openAPIPath, negotiate(mediaJSON)(serveOpenAPI(newOpenAPIDocument(apiOperations)))).Methods("GET")
+
+	return r
 }
 
 func accessControl(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, If-Match, If-None-Match, If-Modified-Since, "+ActorHeader)
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified, Link")
 
 		if r.Method == "OPTIONS" {
 			return
@@ -82,18 +185,246 @@ func accessControl(h http.Handler) http.Handler {
 	})
 }
 
+func populateHTTPActor(ctx context.Context, r *http.Request) context.Context {
+	if actor := strings.TrimSpace(r.Header.Get(ActorHeader)); actor != "" {
+		return models.WithActor(ctx, actor)
+	}
+	return ctx
+}
+
 func decodeHTTPListPlayersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+
+	var size int
+	if ps := q.Get("page_size"); ps != "" {
+		var err error
+		if size, err = strconv.Atoi(ps); err != nil || size < 0 {
+			return nil, errBadRequest
+		}
+	}
+
+	filter, err := decodeHTTPPlayerFilter(q)
+	if err != nil {
+		return nil, err
+	}
+
 	return listPlayersRequest{
-		Position: r.URL.Query().Get("position"),
+		Filter:    filter,
+		PageSize:  size,
+		PageToken: q.Get("page_token"),
+	}, nil
+}
+
+// decodeHTTPPlayerFilter reads the filter and sort shared by lists and exports
+func decodeHTTPPlayerFilter(q url.Values) (models.PlayerFilter, error) {
+	minExp, err := queryInt(q, "min_experience")
+	if err != nil {
+		return models.PlayerFilter{}, errBadRequest
+	}
+	maxExp, err := queryInt(q, "max_experience")
+	if err != nil {
+		return models.PlayerFilter{}, errBadRequest
+	}
+	includeDeleted, err := queryBool(q, "include_deleted")
+	if err != nil {
+		return models.PlayerFilter{}, errBadRequest
+	}
+
+	var positions []string
+	for _, p := range q["position"] {
+		positions = append(positions, splitList(p)...)
+	}
+
+	return models.PlayerFilter{
+		Positions:      positions,
+		College:        q.Get("college"),
+		NamePrefix:     q.Get("name"),
+		MinExperience:  minExp,
+		MaxExperience:  maxExp,
+		IncludeDeleted: includeDeleted,
+		Sort:           models.ParseSort(q.Get("sort")),
 	}, nil
 }
 
+// queryInt returns the integer value of a query parameter, or nil if it's missing
+func queryInt(q url.Values, key string) (*int, error) {
+	v := q.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// queryBool returns the boolean value of a query parameter, or false if it's missing
+func queryBool(q url.Values, key string) (bool, error) {
+	v := q.Get(key)
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// splitList splits a comma-separated list, dropping empty values
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 func encodeHTTPListPlayersResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	lpr := response.(listPlayersResponse)
-	if lpr.Err == "" {
+	if lpr.Err == nil {
+		if lpr.NextPageToken != "" {
+			if link, ok := nextPageLink(ctx, lpr.NextPageToken); ok {
+				w.Header().Set("Link", link)
+			}
+		}
+		if notModified(ctx, w, listETag(lpr.Players, lpr.NextPageToken), time.Time{}) {
+			return encodeHTTPResponse(ctx, http.StatusNotModified, w, nil)
+		}
 		return encodeHTTPResponse(ctx, http.StatusOK, w, response)
 	}
-	encodeHTTPError(ctx, getHTTPError(lpr.Err), w)
+	encodeHTTPError(ctx, lpr.Err, w)
+	return nil
+}
+
+// exportMediaTypes maps export formats to their media types
+var exportMediaTypes = map[string]string{
+	models.ExportCSV:   mediaCSV,
+	models.ExportJSONL: mediaJSONL,
+	models.ExportTSV:   mediaTSV,
+}
+
+// negotiateExport negotiates the export format from Accept, unless the
+// query names one, which wins whatever Accept says
+func negotiateExport(h http.Handler) http.Handler {
+	negotiated := negotiate(mediaCSV, mediaTSV, mediaJSONL)(h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		negotiated.ServeHTTP(w, r)
+	})
+}
+
+// decodeHTTPExportPlayersRequest takes the format from the query, or
+// failing that from the negotiated media type
+func decodeHTTPExportPlayersRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+
+	format := q.Get("format")
+	if format == "" {
+		mt := responseMediaType(ctx)
+		for f, m := range exportMediaTypes {
+			if m == mt {
+				format = f
+			}
+		}
+	}
+	if _, ok := exportMediaTypes[format]; !ok {
+		return nil, errBadRequest
+	}
+
+	filter, err := decodeHTTPPlayerFilter(q)
+	if err != nil {
+		return nil, err
+	}
+
+	return exportPlayersRequest{
+		Filter: filter,
+		Format: format,
+	}, nil
+}
+
+// encodeHTTPExportPlayersResponse streams players as they're read. The
+// headers wait for the first player, so errors found before then, such
+// as an invalid sort, still get an error status; later errors can only
+// cut the body short.
+func encodeHTTPExportPlayersResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	epr := response.(exportPlayersResponse)
+	pw, err := models.NewPlayerWriter(w, epr.Format)
+	if err != nil {
+		return err
+	}
+
+	started := false
+	start := func() {
+		started = true
+		w.Header().Set("Content-Type", contentType(exportMediaTypes[epr.Format]))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="players.%s"`, epr.Format))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	err = epr.Export(func(p *models.Player) error {
+		if !started {
+			start()
+		}
+		return pw.Write(p)
+	})
+	if err != nil {
+		if !started {
+			encodeHTTPError(ctx, err, w)
+			return nil
+		}
+		return err
+	}
+
+	if !started {
+		start()
+	}
+	return pw.Flush()
+}
+
+// nextPageLink builds an RFC 5988 Link header value pointing at the next
+// page, keeping the rest of the original query intact
+func nextPageLink(ctx context.Context, token string) (string, bool) {
+	uri, ok := ctx.Value(kithttp.ContextKeyRequestURI).(string)
+	if !ok || uri == "" {
+		return "", false
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", false
+	}
+
+	q := u.Query()
+	q.Set("page_token", token)
+	u.RawQuery = q.Encode()
+	return fmt.Sprintf(`<%s>; rel="next"`, u.String()), true
+}
+
+func decodeHTTPSearchPlayersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	limit, err := queryInt(q, "limit")
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	req := searchPlayersRequest{
+		Query: q.Get("q"),
+	}
+	if limit != nil {
+		req.Limit = *limit
+	}
+	return req, nil
+}
+
+func encodeHTTPSearchPlayersResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	spr := response.(searchPlayersResponse)
+	if spr.Err == nil {
+		return encodeHTTPResponse(ctx, http.StatusOK, w, response)
+	}
+	encodeHTTPError(ctx, spr.Err, w)
 	return nil
 }
 
@@ -109,24 +440,33 @@ func decodeHTTPGetPlayerRequest(_ context.Context, r *http.Request) (interface{}
 		return nil, errBadRequest
 	}
 
+	includeDeleted, err := queryBool(r.URL.Query(), "include_deleted")
+	if err != nil {
+		return nil, errBadRequest
+	}
+
 	return getPlayerRequest{
-		ID: ID,
+		ID:             ID,
+		IncludeDeleted: includeDeleted,
 	}, nil
 }
 
 func encodeHTTPGetPlayerResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	gpr := response.(getPlayerResponse)
-	if gpr.Err == "" {
+	if gpr.Err == nil {
+		if notModified(ctx, w, playerETag(ctx, gpr.Player), gpr.Player.UpdatedAt) {
+			return encodeHTTPResponse(ctx, http.StatusNotModified, w, nil)
+		}
 		return encodeHTTPResponse(ctx, http.StatusOK, w, response)
 	}
-	encodeHTTPError(ctx, getHTTPError(gpr.Err), w)
+	encodeHTTPError(ctx, gpr.Err, w)
 	return nil
 }
 
 func decodeHTTPCreatePlayerRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	var player models.Player
-	if err := json.NewDecoder(r.Body).Decode(&player); err != nil {
-		return nil, errBadRequest
+	player, err := decodeHTTPPlayer(r)
+	if err != nil {
+		return nil, err
 	}
 
 	if player.ID > 0 {
@@ -150,15 +490,20 @@ func decodeHTTPUpdatePlayerRequest(_ context.Context, r *http.Request) (interfac
 		return nil, errBadRequest
 	}
 
-	var player models.Player
-	if err := json.NewDecoder(r.Body).Decode(&player); err != nil {
-		return nil, errBadRequest
+	player, err := decodeHTTPPlayer(r)
+	if err != nil {
+		return nil, err
 	}
 
 	if ID != player.ID {
 		return nil, errBadRequest
 	}
 
+	// Only If-Match is checked, so a stale version in the body is ignored
+	if player.Version, _, err = ifMatch(r); err != nil {
+		return nil, err
+	}
+
 	return savePlayerRequest{
 		Player: &player,
 	}, nil
@@ -166,14 +511,68 @@ func decodeHTTPUpdatePlayerRequest(_ context.Context, r *http.Request) (interfac
 
 func encodeHTTPSavePlayerResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	spr := response.(savePlayerResponse)
-	if spr.Err == "" {
+	if spr.Err == nil {
 		sc := http.StatusOK
 		if spr.Created {
 			sc = http.StatusCreated
 		}
+		setETag(ctx, w, spr.Player)
 		return encodeHTTPResponse(ctx, sc, w, response)
 	}
-	encodeHTTPError(ctx, getHTTPError(spr.Err), w)
+	encodeHTTPError(ctx, spr.Err, w)
+	return nil
+}
+
+// decodeHTTPPatchPlayerRequest reads an RFC 7396 merge patch, or an RFC
+// 6902 JSON patch when the content type asks for one
+func decodeHTTPPatchPlayerRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	ID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	parse := models.ParseMergePatch
+	switch mediaType(r.Header.Get("Content-Type")) {
+	case "", "application/json", "application/merge-patch+json":
+	case "application/json-patch+json":
+		parse = models.ParseJSONPatch
+	default:
+		return nil, errUnsupportedMediaType
+	}
+
+	patch, err := parse(body)
+	if err != nil {
+		return nil, newError(KindInvalidArgument, err.Error())
+	}
+
+	if patch.Version, _, err = ifMatch(r); err != nil {
+		return nil, err
+	}
+
+	return patchPlayerRequest{
+		ID:    ID,
+		Patch: patch,
+	}, nil
+}
+
+func encodeHTTPPatchPlayerResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	ppr := response.(patchPlayerResponse)
+	if ppr.Err == nil {
+		setETag(ctx, w, ppr.Player)
+		return encodeHTTPResponse(ctx, http.StatusOK, w, response)
+	}
+	encodeHTTPError(ctx, ppr.Err, w)
 	return nil
 }
 
@@ -189,50 +588,257 @@ func decodeHTTPDeletePlayerRequest(_ context.Context, r *http.Request) (interfac
 		return nil, errBadRequest
 	}
 
+	version, _, err := ifMatch(r)
+	if err != nil {
+		return nil, err
+	}
+
 	return deletePlayerRequest{
-		ID: ID,
+		ID:      ID,
+		Version: version,
 	}, nil
 }
 
 func encodeHTTPDeletePlayerResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
 	dpr := response.(deletePlayerResponse)
-	if dpr.Err == "" {
+	if dpr.Err == nil {
 		return encodeHTTPResponse(ctx, http.StatusNoContent, w, nil)
 	}
-	encodeHTTPError(ctx, getHTTPError(dpr.Err), w)
+	encodeHTTPError(ctx, dpr.Err, w)
 	return nil
 }
 
-func encodeHTTPResponse(_ context.Context, statusCode int, w http.ResponseWriter, response interface{}) error {
-	w.WriteHeader(statusCode)
-	if response == nil {
-		return nil
+func decodeHTTPRestorePlayerRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	ID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errBadRequest
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	return json.NewEncoder(w).Encode(response)
+
+	version, _, err := ifMatch(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return restorePlayerRequest{
+		ID:      ID,
+		Version: version,
+	}, nil
 }
 
-func encodeHTTPError(_ context.Context, err error, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	switch err {
-	case errBadRequest:
-		w.WriteHeader(http.StatusBadRequest)
-	case errNotFound:
-		w.WriteHeader(http.StatusNotFound)
-	default:
-		w.WriteHeader(http.StatusInternalServerError)
+func encodeHTTPRestorePlayerResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	rpr := response.(restorePlayerResponse)
+	if rpr.Err == nil {
+		setETag(ctx, w, rpr.Player)
+		return encodeHTTPResponse(ctx, http.StatusOK, w, response)
 	}
-	e := json.NewEncoder(w).Encode(map[string]interface{}{
-		"error": err.Error(),
-	})
-	if e != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	encodeHTTPError(ctx, rpr.Err, w)
+	return nil
+}
+
+// decodeHTTPImportPlayersRequest reads players from a CSV body
+// decodeHTTPImportPlayersRequest reads an import as CSV, or as a
+// pb.ImportPlayersRequest, which can give each row its own fields
+func decodeHTTPImportPlayersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	mt := requestMediaType(r)
+	if mt != mediaCSV && mt != mediaProtobuf && r.Header.Get("Content-Type") != "" {
+		return nil, errUnsupportedMediaType
+	}
+
+	body := http.MaxBytesReader(nil, r.Body, MaxImportBytes)
+	var rows []models.ImportRow
+	var err error
+	if mt == mediaProtobuf {
+		rows, err = decodeProtoImport(body)
+	} else {
+		rows, err = models.ParseImport(body, time.Now())
 	}
+	if err != nil {
+		// The parser may report running out of body as a bad header, but
+		// the limit's error sticks to later reads
+		if _, err := body.Read(nil); err != nil {
+			if _, ok := err.(*http.MaxBytesError); ok {
+				return nil, errImportTooLarge
+			}
+		}
+		return nil, newError(KindInvalidArgument, err.Error())
+	}
+
+	return importPlayersRequest{
+		Rows: rows,
+	}, nil
 }
 
-func getHTTPError(str string) error {
-	if str == "not found" {
-		return errNotFound
+func encodeHTTPImportPlayersResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	ipr := response.(importPlayersResponse)
+	if ipr.Err == nil {
+		return encodeHTTPResponse(ctx, http.StatusOK, w, response)
+	}
+	encodeHTTPError(ctx, ipr.Err, w)
+	return nil
+}
+
+func decodeHTTPGetHistoryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	vars := mux.Vars(r)
+	id, ok := vars["id"]
+	if !ok {
+		return nil, errBadRoute
+	}
+
+	ID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	return getHistoryRequest{
+		ID: ID,
+	}, nil
+}
+
+func encodeHTTPGetHistoryResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	ghr := response.(getHistoryResponse)
+	if ghr.Err == nil {
+		return encodeHTTPResponse(ctx, http.StatusOK, w, response)
+	}
+	encodeHTTPError(ctx, ghr.Err, w)
+	return nil
+}
+
+// etag returns the entity tag for a version of a player
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// playerETag returns the entity tag for a player in the negotiated media
+// type, or "" if it has no version. The tag is the version for JSON, and
+// the version and a suffix naming the media type for the others, e.g.
+// "3-yaml", so each representation's strong tag is different.
+func playerETag(ctx context.Context, player *models.Player) string {
+	if player == nil || player.Version <= 0 {
+		return ""
+	}
+	mt := responseMediaType(ctx)
+	if mt == mediaJSON {
+		return etag(player.Version)
+	}
+	suffix := strings.TrimPrefix(mt[strings.Index(mt, "/")+1:], "x-")
+	return strconv.Quote(fmt.Sprintf("%d-%s", player.Version, suffix))
+}
+
+func setETag(ctx context.Context, w http.ResponseWriter, player *models.Player) {
+	if tag := playerETag(ctx, player); tag != "" {
+		w.Header().Set("ETag", tag)
+	}
+}
+
+// ifMatch returns the version a request's If-Match header expects, and
+// whether it expects one; "*" matches any version. A tag from any media
+// type names its version, since they're all the same player. Tags that
+// can't be a version, including weak ones, never match.
+func ifMatch(r *http.Request) (int, bool, error) {
+	tag := strings.TrimSpace(r.Header.Get("If-Match"))
+	if tag == "" || tag == "*" {
+		return 0, false, nil
+	}
+
+	version, ok := tagVersion(tag)
+	if !ok {
+		return 0, false, errIfMatch
+	}
+	return version, true, nil
+}
+
+// tagVersion returns the version an entity tag names
+func tagVersion(tag string) (int, bool) {
+	s, err := strconv.Unquote(tag)
+	if err != nil || !strings.HasPrefix(tag, `"`) {
+		return 0, false
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		s = s[:i]
+	}
+	version, err := strconv.Atoi(s)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// matchAnyTag narrows an If-Match list to the tag naming the player's
+// current version, so ifMatch sees a single tag. When none names it, the
+// first tag is kept and fails the version check as it should.
+func matchAnyTag(get endpoint.Endpoint) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tags := strings.Split(r.Header.Get("If-Match"), ",")
+			if len(tags) > 1 {
+				r.Header.Set("If-Match", currentTag(r, get, tags))
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// currentTag returns the tag in a list that names the current version of
+// the requested player, or the first tag if none does
+func currentTag(r *http.Request, get endpoint.Endpoint, tags []string) string {
+	for i := range tags {
+		tags[i] = strings.TrimSpace(tags[i])
+		if tags[i] == "*" {
+			return "*"
+		}
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return tags[0]
+	}
+	response, err := get(r.Context(), getPlayerRequest{ID: id, IncludeDeleted: true})
+	if err != nil {
+		return tags[0]
+	}
+	gpr := response.(getPlayerResponse)
+	if gpr.Err != nil {
+		return tags[0]
+	}
+	for _, tag := range tags {
+		if version, ok := tagVersion(tag); ok && version == gpr.Player.Version {
+			return tag
+		}
+	}
+	return tags[0]
+}
+
+// encodeHTTPResponse writes a response in the negotiated media type
+func encodeHTTPResponse(ctx context.Context, statusCode int, w http.ResponseWriter, response interface{}) error {
+	if response == nil {
+		w.WriteHeader(statusCode)
+		return nil
+	}
+
+	mt := responseMediaType(ctx)
+	body, err := marshalResponse(ctx, mt, response)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", contentType(mt))
+	w.WriteHeader(statusCode)
+	_, err = w.Write(body)
+	return err
+}
+
+func encodeHTTPError(ctx context.Context, err error, w http.ResponseWriter) {
+	mt, body, e := marshalError(responseMediaType(ctx), err)
+	if e != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
-	return errors.New(str)
+	w.Header().Set("Content-Type", contentType(mt))
+	w.WriteHeader(httpStatusCode(err))
+	w.Write(body)
 }
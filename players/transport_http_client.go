@@ -0,0 +1,417 @@
+package players
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/kit/endpoint"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/golang/protobuf/proto"
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/pb"
+)
+
+// NewHTTPClient returns endpoints that call the HTTP transport at base,
+// e.g., http://localhost:9090, using client to make requests. API errors
+// are returned as the errors the remote service returned, so KindOf
+// reports them the same; gateway failures are returned by the endpoints
+// themselves, so a middleware can retry them.
+func NewHTTPClient(base *url.URL, client *http.Client, options ...kithttp.ClientOption) *Endpoints {
+	options = append([]kithttp.ClientOption{
+		kithttp.SetClient(client),
+		kithttp.ClientBefore(populateHTTPClientHeaders),
+	}, options...)
+
+	players := clientURL(base, "/v1/players")
+	newClient := func(method string, tgt *url.URL, enc kithttp.EncodeRequestFunc, dec kithttp.DecodeResponseFunc) endpoint.Endpoint {
+		return kithttp.NewClient(method, tgt, enc, dec, options...).Endpoint()
+	}
+
+	return &Endpoints{
+		listPlayersEndpoint:   newClient("GET", players, encodeHTTPListPlayersClientRequest, decodeHTTPListPlayersClientResponse),
+		exportPlayersEndpoint: makeHTTPExportPlayersClientEndpoint(clientURL(base, "/v1/players/export"), client),
+		searchPlayersEndpoint: newClient("GET", clientURL(base, "/v1/players/search"), encodeHTTPSearchPlayersClientRequest, decodeHTTPSearchPlayersClientResponse),
+		getPlayerEndpoint:     newClient("GET", players, encodeHTTPGetPlayerClientRequest, decodeHTTPGetPlayerClientResponse),
+		savePlayerEndpoint:    newClient("POST", players, encodeHTTPSavePlayerClientRequest, decodeHTTPSavePlayerClientResponse),
+		patchPlayerEndpoint:   newClient("PATCH", players, encodeHTTPPatchPlayerClientRequest, decodeHTTPPatchPlayerClientResponse),
+		deletePlayerEndpoint:  newClient("DELETE", players, encodeHTTPDeletePlayerClientRequest, decodeHTTPDeletePlayerClientResponse),
+		restorePlayerEndpoint: newClient("POST", players, encodeHTTPRestorePlayerClientRequest, decodeHTTPRestorePlayerClientResponse),
+		importPlayersEndpoint: newClient("POST", clientURL(base, "/v1/players:import"), encodeHTTPImportPlayersClientRequest, decodeHTTPImportPlayersClientResponse),
+		getHistoryEndpoint:    newClient("GET", players, encodeHTTPGetHistoryClientRequest, decodeHTTPGetHistoryClientResponse),
+	}
+}
+
+// clientURL returns a copy of base with path appended to its path
+func clientURL(base *url.URL, path string) *url.URL {
+	u := *base
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	return &u
+}
+
+// appendPath adds to the path of a request, e.g., the ID of a player
+func appendPath(r *http.Request, format string, args ...interface{}) {
+	r.URL.Path += fmt.Sprintf(format, args...)
+}
+
+// populateHTTPClientHeaders asks for JSON unless an encoder asked for
+// something else, and passes the actor on from the context
+func populateHTTPClientHeaders(ctx context.Context, r *http.Request) context.Context {
+	if r.Header.Get("Accept") == "" {
+		r.Header.Set("Accept", mediaJSON)
+	}
+	if actor := models.ActorFrom(ctx); actor != models.Anonymous {
+		r.Header.Set(ActorHeader, actor)
+	}
+	return ctx
+}
+
+// setIfMatch sends the version a change expects, if there is one
+func setIfMatch(r *http.Request, version int) {
+	if version > 0 {
+		r.Header.Set("If-Match", etag(version))
+	}
+}
+
+// setJSONBody sends v as the body of a request
+func setJSONBody(r *http.Request, contentType string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", contentType)
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	r.ContentLength = int64(len(b))
+	return nil
+}
+
+// encodeHTTPPlayerFilter writes a filter as the query parameters that
+// decodeHTTPPlayerFilter reads
+func encodeHTTPPlayerFilter(q url.Values, filter models.PlayerFilter) {
+	if len(filter.Positions) > 0 {
+		q.Set("position", strings.Join(filter.Positions, ","))
+	}
+	if filter.College != "" {
+		q.Set("college", filter.College)
+	}
+	if filter.NamePrefix != "" {
+		q.Set("name", filter.NamePrefix)
+	}
+	if filter.MinExperience != nil {
+		q.Set("min_experience", strconv.Itoa(*filter.MinExperience))
+	}
+	if filter.MaxExperience != nil {
+		q.Set("max_experience", strconv.Itoa(*filter.MaxExperience))
+	}
+	if filter.IncludeDeleted {
+		q.Set("include_deleted", "true")
+	}
+	if len(filter.Sort) > 0 {
+		q.Set("sort", models.FormatSort(filter.Sort))
+	}
+}
+
+// decodeHTTPClientResponse reads a JSON response into response. An error
+// response is read into the error the remote service returned, unless it
+// came from a gateway, in which case err is set instead.
+func decodeHTTPClientResponse(resp *http.Response, response interface{}) (serviceErr error, err error) {
+	switch {
+	case resp.StatusCode == http.StatusBadGateway, resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+		return nil, fmt.Errorf("players service unavailable: %s", resp.Status)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return decodeHTTPClientError(resp), nil
+	case resp.StatusCode == http.StatusNoContent || response == nil:
+		return nil, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// decodeHTTPClientError reads an error response written by encodeHTTPError
+func decodeHTTPClientError(resp *http.Response) error {
+	var body errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		return newError(httpStatusKind(resp.StatusCode), strings.ToLower(http.StatusText(resp.StatusCode)))
+	}
+	if resp.StatusCode == http.StatusUnprocessableEntity && len(body.Fields) > 0 {
+		return &ValidationError{
+			Fields: body.Fields,
+		}
+	}
+	return newError(httpStatusKind(resp.StatusCode), body.Error)
+}
+
+func encodeHTTPListPlayersClientRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(listPlayersRequest)
+	q := r.URL.Query()
+	encodeHTTPPlayerFilter(q, req.Filter)
+	if req.PageSize > 0 {
+		q.Set("page_size", strconv.Itoa(req.PageSize))
+	}
+	if req.PageToken != "" {
+		q.Set("page_token", req.PageToken)
+	}
+	r.URL.RawQuery = q.Encode()
+	return nil
+}
+
+func decodeHTTPListPlayersClientResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var lpr listPlayersResponse
+	serviceErr, err := decodeHTTPClientResponse(resp, &lpr)
+	if err != nil {
+		return nil, err
+	}
+	lpr.Err = serviceErr
+	return lpr, nil
+}
+
+// makeHTTPExportPlayersClientEndpoint streams an export as JSON Lines. Go
+// kit's client cancels its request when the endpoint returns, before the
+// players have been read, so this endpoint makes its own.
+func makeHTTPExportPlayersClientEndpoint(tgt *url.URL, client *http.Client) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(exportPlayersRequest)
+		r, err := http.NewRequest("GET", tgt.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		q := r.URL.Query()
+		encodeHTTPPlayerFilter(q, req.Filter)
+		q.Set("format", models.ExportJSONL)
+		r.URL.RawQuery = q.Encode()
+		r.Header.Set("Accept", mediaJSONL)
+		populateHTTPClientHeaders(ctx, r)
+
+		resp, err := client.Do(r.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			serviceErr, err := decodeHTTPClientResponse(resp, nil)
+			if err != nil {
+				return nil, err
+			}
+			return exportPlayersResponse{
+				Format: models.ExportJSONL,
+				Export: func(func(*models.Player) error) error {
+					return serviceErr
+				},
+			}, nil
+		}
+
+		return exportPlayersResponse{
+			Format: models.ExportJSONL,
+			Export: func(fn func(*models.Player) error) error {
+				defer resp.Body.Close()
+				dec := json.NewDecoder(resp.Body)
+				for {
+					var p models.Player
+					if err := dec.Decode(&p); err == io.EOF {
+						return nil
+					} else if err != nil {
+						return err
+					}
+					if err := fn(&p); err != nil {
+						return err
+					}
+				}
+			},
+		}, nil
+	}
+}
+
+func encodeHTTPSearchPlayersClientRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(searchPlayersRequest)
+	q := r.URL.Query()
+	q.Set("q", req.Query)
+	if req.Limit > 0 {
+		q.Set("limit", strconv.Itoa(req.Limit))
+	}
+	r.URL.RawQuery = q.Encode()
+	return nil
+}
+
+func decodeHTTPSearchPlayersClientResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var spr searchPlayersResponse
+	serviceErr, err := decodeHTTPClientResponse(resp, &spr)
+	if err != nil {
+		return nil, err
+	}
+	spr.Err = serviceErr
+	return spr, nil
+}
+
+func encodeHTTPGetPlayerClientRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(getPlayerRequest)
+	appendPath(r, "/%d", req.ID)
+	if req.IncludeDeleted {
+		r.URL.RawQuery = "include_deleted=true"
+	}
+	return nil
+}
+
+func decodeHTTPGetPlayerClientResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var gpr getPlayerResponse
+	serviceErr, err := decodeHTTPClientResponse(resp, &gpr)
+	if err != nil {
+		return nil, err
+	}
+	gpr.Err = serviceErr
+	return gpr, nil
+}
+
+// encodeHTTPSavePlayerClientRequest creates players without an ID, and
+// replaces the others
+func encodeHTTPSavePlayerClientRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(savePlayerRequest)
+	if req.Player.ID > 0 {
+		r.Method = "PUT"
+		appendPath(r, "/%d", req.Player.ID)
+		setIfMatch(r, req.Player.Version)
+	}
+	return setJSONBody(r, mediaJSON, req.Player)
+}
+
+func decodeHTTPSavePlayerClientResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var spr savePlayerResponse
+	serviceErr, err := decodeHTTPClientResponse(resp, &spr)
+	if err != nil {
+		return nil, err
+	}
+	spr.Err = serviceErr
+	spr.Created = resp.StatusCode == http.StatusCreated
+	return spr, nil
+}
+
+// encodeHTTPPatchPlayerClientRequest sends a patch as a JSON merge patch
+func encodeHTTPPatchPlayerClientRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(patchPlayerRequest)
+	appendPath(r, "/%d", req.ID)
+	setIfMatch(r, req.Patch.Version)
+
+	members := map[string]interface{}{}
+	if req.Patch.Name != nil {
+		members["name"] = req.Patch.Name
+	}
+	if req.Patch.Number != nil {
+		members["number"] = req.Patch.Number
+	}
+	if req.Patch.Position != nil {
+		members["position"] = req.Patch.Position
+	}
+	if req.Patch.Height != nil {
+		members["height"] = req.Patch.Height
+	}
+	if req.Patch.Weight != nil {
+		members["weight"] = req.Patch.Weight
+	}
+	if req.Patch.BirthDate != nil {
+		members["birth_date"] = req.Patch.BirthDate
+	}
+	if req.Patch.Experience != nil {
+		members["experience"] = req.Patch.Experience
+	}
+	if req.Patch.College != nil {
+		members["college"] = req.Patch.College
+	}
+	return setJSONBody(r, "application/merge-patch+json", members)
+}
+
+func decodeHTTPPatchPlayerClientResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var ppr patchPlayerResponse
+	serviceErr, err := decodeHTTPClientResponse(resp, &ppr)
+	if err != nil {
+		return nil, err
+	}
+	ppr.Err = serviceErr
+	return ppr, nil
+}
+
+func encodeHTTPDeletePlayerClientRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(deletePlayerRequest)
+	appendPath(r, "/%d", req.ID)
+	setIfMatch(r, req.Version)
+	return nil
+}
+
+func decodeHTTPDeletePlayerClientResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	serviceErr, err := decodeHTTPClientResponse(resp, nil)
+	if err != nil {
+		return nil, err
+	}
+	return deletePlayerResponse{
+		Err: serviceErr,
+	}, nil
+}
+
+func encodeHTTPRestorePlayerClientRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(restorePlayerRequest)
+	appendPath(r, "/%d:restore", req.ID)
+	setIfMatch(r, req.Version)
+	return nil
+}
+
+func decodeHTTPRestorePlayerClientResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var rpr restorePlayerResponse
+	serviceErr, err := decodeHTTPClientResponse(resp, &rpr)
+	if err != nil {
+		return nil, err
+	}
+	rpr.Err = serviceErr
+	return rpr, nil
+}
+
+// encodeHTTPImportPlayersClientRequest sends rows as a
+// pb.ImportPlayersRequest rather than CSV, which can carry neither the
+// errors of rows that failed to parse nor rows setting different fields
+func encodeHTTPImportPlayersClientRequest(ctx context.Context, r *http.Request, request interface{}) error {
+	m, err := encodeGRPCImportPlayersClientRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+	b, err := proto.Marshal(m.(*pb.ImportPlayersRequest))
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set("Content-Type", mediaProtobuf)
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	r.ContentLength = int64(len(b))
+	return nil
+}
+
+func decodeHTTPImportPlayersClientResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var ipr importPlayersResponse
+	serviceErr, err := decodeHTTPClientResponse(resp, &ipr)
+	if err != nil {
+		return nil, err
+	}
+	ipr.Err = serviceErr
+	return ipr, nil
+}
+
+func encodeHTTPGetHistoryClientRequest(_ context.Context, r *http.Request, request interface{}) error {
+	req := request.(getHistoryRequest)
+	appendPath(r, "/%d/history", req.ID)
+	return nil
+}
+
+func decodeHTTPGetHistoryClientResponse(_ context.Context, resp *http.Response) (interface{}, error) {
+	var ghr getHistoryResponse
+	serviceErr, err := decodeHTTPClientResponse(resp, &ghr)
+	if err != nil {
+		return nil, err
+	}
+	ghr.Err = serviceErr
+	return ghr, nil
+}
@@ -2,14 +2,17 @@ package players
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/hoop33/roster/models"
@@ -26,10 +29,10 @@ func TestHTTPListPlayersShouldReturnPlayersWhenDatabaseReturnsPlayers(t *testing
 		AddRow(1, "Blake Bortles", "5").
 		AddRow(2, "Jalen Ramsey", "20")
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC, id ASC LIMIT \$1$`).
 		WillReturnRows(rows)
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("GET", "/v1/players", nil)
 	resp := httptest.NewRecorder()
@@ -41,15 +44,113 @@ func TestHTTPListPlayersShouldReturnPlayersWhenDatabaseReturnsPlayers(t *testing
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
+func TestHTTPListPlayersShouldSetLinkHeaderWhenMorePlayers(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	for _, p := range []models.Player{
+		{Name: "Blake Bortles", Number: 5, Position: "QB"},
+		{Name: "Cody Kessler", Number: 6, Position: "QB"},
+	} {
+		p := p
+		_, _, err := repo.SavePlayer(context.Background(), &p)
+		assert.Nil(t, err)
+	}
+
+	es := NewEndpoints(NewService(repo))
+
+	req := httptest.NewRequest("GET", "/v1/players?position=QB&page_size=1", nil)
+	resp := httptest.NewRecorder()
+	NewHTTPTransport(es, log.NewNopLogger()).ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Players       []models.Player `json:"players"`
+		NextPageToken string          `json:"next_page_token"`
+	}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, 1, len(body.Players))
+	assert.NotEqual(t, "", body.NextPageToken)
+
+	link := resp.Header().Get("Link")
+	assert.True(t, strings.HasPrefix(link, "</v1/players?"))
+	assert.True(t, strings.Contains(link, "position=QB"))
+	assert.True(t, strings.Contains(link, "page_token="+body.NextPageToken))
+	assert.True(t, strings.HasSuffix(link, `>; rel="next"`))
+}
+
+func TestHTTPListPlayersShouldReturnBadRequestWhenPageSizeInvalid(t *testing.T) {
+	es := NewEndpoints(NewService(models.NewMemoryRepository()))
+
+	req := httptest.NewRequest("GET", "/v1/players?page_size=lots", nil)
+	resp := httptest.NewRecorder()
+	NewHTTPTransport(es, log.NewNopLogger()).ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestDecodeHTTPListPlayersRequestShouldReadFilter(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v1/players?position=QB,RB&position=TE&college=LSU&name=leo&min_experience=1&max_experience=4&sort=-experience,name", nil)
+	r, err := decodeHTTPListPlayersRequest(context.Background(), req)
+	assert.Nil(t, err)
+
+	f := r.(listPlayersRequest).Filter
+	assert.Equal(t, []string{"QB", "RB", "TE"}, f.Positions)
+	assert.Equal(t, "LSU", f.College)
+	assert.Equal(t, "leo", f.NamePrefix)
+	assert.Equal(t, 1, *f.MinExperience)
+	assert.Equal(t, 4, *f.MaxExperience)
+	assert.Equal(t, "-experience,name", models.FormatSort(f.Sort))
+}
+
+func TestDecodeHTTPListPlayersRequestShouldRejectInvalidExperience(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v1/players?min_experience=lots", nil)
+	_, err := decodeHTTPListPlayersRequest(context.Background(), req)
+	assert.Equal(t, errBadRequest, err)
+}
+
+func TestHTTPSearchPlayersShouldReturnRankedResults(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	for _, p := range []models.Player{
+		{Name: "Jalen Ramsey", Number: 20},
+		{Name: "Blake Bortles", Number: 5},
+	} {
+		p := p
+		_, _, err := repo.SavePlayer(context.Background(), &p)
+		assert.Nil(t, err)
+	}
+
+	es := NewEndpoints(NewService(repo))
+
+	req := httptest.NewRequest("GET", "/v1/players/search?q=ramsy", nil)
+	resp := httptest.NewRecorder()
+	NewHTTPTransport(es, log.NewNopLogger()).ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		Results []models.SearchResult `json:"results"`
+	}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, 1, len(body.Results))
+	assert.Equal(t, "Jalen <em>Ramsey</em>", body.Results[0].Highlight)
+}
+
+func TestHTTPSearchPlayersShouldReturnEmptyResultsWhenNoMatches(t *testing.T) {
+	es := NewEndpoints(NewService(models.NewMemoryRepository()))
+
+	req := httptest.NewRequest("GET", "/v1/players/search?q=nobody", nil)
+	resp := httptest.NewRecorder()
+	NewHTTPTransport(es, log.NewNopLogger()).ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "{\"results\":[]}\n", resp.Body.String())
+}
+
 func TestHTTPListPlayersShouldReturnErrorWhenDatabaseReturnsNoRows(t *testing.T) {
 	db, mock, err := createDB()
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC, id ASC LIMIT \$1$`).
 		WillReturnError(sql.ErrNoRows)
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("GET", "/v1/players", nil)
 	resp := httptest.NewRecorder()
@@ -66,10 +167,10 @@ func TestHTTPListPlayersShouldReturnErrorWhenDatabaseReturnsError(t *testing.T)
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players ORDER BY number ASC$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL ORDER BY number ASC, id ASC LIMIT \$1$`).
 		WillReturnError(errors.New("database error"))
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("GET", "/v1/players", nil)
 	resp := httptest.NewRecorder()
@@ -89,11 +190,11 @@ func TestHTTPGetPlayerShouldReturnPlayerWhenExists(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "name", "number"}).
 		AddRow(1, "Blake Bortles", "5")
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnRows(rows)
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("GET", "/v1/players/1", nil)
 	resp := httptest.NewRecorder()
@@ -110,11 +211,11 @@ func TestHTTPGetPlayerShouldReturnErrorWhenNotExists(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnError(sql.ErrNoRows)
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("GET", "/v1/players/1", nil)
 	resp := httptest.NewRecorder()
@@ -131,11 +232,11 @@ func TestHTTPGetPlayerShouldReturnErrorWhenDatabaseReturnsError(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1$`).
+	mock.ExpectQuery(`^SELECT \* FROM players WHERE id = \$1 AND deleted_at IS NULL$`).
 		WithArgs(1).
 		WillReturnError(errors.New("database error"))
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("GET", "/v1/players/1", nil)
 	resp := httptest.NewRecorder()
@@ -152,7 +253,7 @@ func TestHTTPGetPlayerShouldReturnErrorWhenIDIsNonNumeric(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("GET", "/v1/players/a", nil)
 	resp := httptest.NewRecorder()
@@ -171,22 +272,25 @@ func TestHTTPSavePlayerShouldSavePlayerWhenPost(t *testing.T) {
 
 	p := &models.Player{
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
+	mock.ExpectBegin()
 	mock.ExpectQuery(`^INSERT INTO players
-		\(name, number, position, height, weight, age, experience, college\)
-		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)
+		\(name, number, position, height, weight, birth_date, experience, college, updated_at\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\)
 		RETURNING id$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectHistory(mock, models.OperationCreate)
+	mock.ExpectCommit()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	b, err := json.Marshal(p)
 	assert.Nil(t, err)
@@ -208,22 +312,24 @@ func TestHTTPSavePlayerShouldReturnErrorWhenPostAndDatabaseError(t *testing.T) {
 
 	p := &models.Player{
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
+	mock.ExpectBegin()
 	mock.ExpectQuery(`^INSERT INTO players
-		\(name, number, position, height, weight, age, experience, college\)
-		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8\)
+		\(name, number, position, height, weight, birth_date, experience, college, updated_at\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\)
 		RETURNING id$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg()).
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	b, err := json.Marshal(p)
 	assert.Nil(t, err)
@@ -246,16 +352,16 @@ func TestHTTPSavePlayerShouldReturnErrorWhenPostContainsID(t *testing.T) {
 	p := &models.Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	b, err := json.Marshal(p)
 	assert.Nil(t, err)
@@ -275,7 +381,7 @@ func TestHTTPSavePlayerShouldReturnErrorWhenPostBodyIsMalformatted(t *testing.T)
 	assert.Nil(t, err)
 	defer db.Close()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("POST", "/v1/players", strings.NewReader("bad format"))
 	resp := httptest.NewRecorder()
@@ -295,21 +401,26 @@ func TestHTTPSavePlayerShouldSavePlayerWhenPut(t *testing.T) {
 	p := &models.Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
-	mock.ExpectExec(`^UPDATE players
-		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, age=\$6, experience=\$7, college=\$8
-		WHERE id=\$9$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	es := NewEndpoints(NewService(db))
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, birth_date=\$6, experience=\$7, college=\$8, updated_at=\$9, version=version\+1
+		WHERE id=\$10 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg(), p.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	expectHistory(mock, models.OperationUpdate)
+	mock.ExpectCommit()
+
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	b, err := json.Marshal(p)
 	assert.Nil(t, err)
@@ -332,21 +443,25 @@ func TestHTTPSavePlayerShouldReturnErrorWhenPutAndDatabaseError(t *testing.T) {
 	p := &models.Player{
 		ID:         1,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
-	mock.ExpectExec(`^UPDATE players
-		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, age=\$6, experience=\$7, college=\$8
-		WHERE id=\$9$`).
-		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.Age, p.Experience, p.College, p.ID).
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET name=\$1, number=\$2, position=\$3, height=\$4, weight=\$5, birth_date=\$6, experience=\$7, college=\$8, updated_at=\$9, version=version\+1
+		WHERE id=\$10 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(p.Name, p.Number, p.Position, p.Height, p.Weight, p.BirthDate, p.Experience, p.College, sqlmock.AnyArg(), p.ID).
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	b, err := json.Marshal(p)
 	assert.Nil(t, err)
@@ -368,16 +483,16 @@ func TestHTTPSavePlayerShouldReturnErrorWhenPutDoesNotContainID(t *testing.T) {
 
 	p := &models.Player{
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	b, err := json.Marshal(p)
 	assert.Nil(t, err)
@@ -400,16 +515,16 @@ func TestHTTPSavePlayerShouldReturnErrorWhenPutIDsDoNotMatch(t *testing.T) {
 	p := &models.Player{
 		ID:         2,
 		Name:       "Blake Bortles",
-		Number:     "5",
+		Number:     5,
 		Position:   "QB",
-		Height:     "6-5",
-		Weight:     "236",
-		Age:        "26",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
 		Experience: 5,
 		College:    "Central Florida",
 	}
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	b, err := json.Marshal(p)
 	assert.Nil(t, err)
@@ -429,7 +544,7 @@ func TestHTTPSavePlayerShouldReturnErrorWhenPutBodyIsMalformatted(t *testing.T)
 	assert.Nil(t, err)
 	defer db.Close()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("PUT", "/v1/players/1", strings.NewReader("bad format"))
 	resp := httptest.NewRecorder()
@@ -446,7 +561,7 @@ func TestHTTPSavePlayerShouldReturnErrorWhenPutIDIsNonNumeric(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("PUT", "/v1/players/a", nil)
 	resp := httptest.NewRecorder()
@@ -463,7 +578,7 @@ func TestHTTPSavePlayerShouldReturnErrorWhenPutIDIsMissing(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("PUT", "/v1/players", nil)
 	resp := httptest.NewRecorder()
@@ -480,12 +595,18 @@ func TestHTTPDeletePlayerShouldDeletePlayerWhenExists(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	expectHistory(mock, models.OperationDelete)
+	mock.ExpectCommit()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("DELETE", "/v1/players/1", nil)
 	resp := httptest.NewRecorder()
@@ -502,12 +623,11 @@ func TestHTTPDeletePlayerShouldReturnErrorWhenNotExists(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	expectLock(mock, 1, false)
+	mock.ExpectRollback()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("DELETE", "/v1/players/1", nil)
 	resp := httptest.NewRecorder()
@@ -524,12 +644,17 @@ func TestHTTPDeletePlayerShouldReturnErrorWhenDatabaseReturnsError(t *testing.T)
 	assert.Nil(t, err)
 	defer db.Close()
 
-	mock.ExpectExec(`^DELETE FROM players
-		WHERE id=\$1$`).
-		WithArgs(1).
+	mock.ExpectBegin()
+	expectLock(mock, 1, true)
+	mock.ExpectQuery(`^UPDATE players
+		SET deleted_at=\$1, updated_at=\$2, version=version\+1
+		WHERE id=\$3 AND deleted_at IS NULL
+		RETURNING version$`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
 		WillReturnError(errors.New("database error"))
+	mock.ExpectRollback()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("DELETE", "/v1/players/1", nil)
 	resp := httptest.NewRecorder()
@@ -546,7 +671,7 @@ func TestHTTPDeletePlayerShouldReturnErrorWhenIDIsNonNumeric(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("DELETE", "/v1/players/a", nil)
 	resp := httptest.NewRecorder()
@@ -563,7 +688,7 @@ func TestHTTPDeletePlayerShouldReturnErrorWhenIDIsMissing(t *testing.T) {
 	assert.Nil(t, err)
 	defer db.Close()
 
-	es := NewEndpoints(NewService(db))
+	es := NewEndpoints(NewService(models.NewPostgresRepository(db)))
 
 	req := httptest.NewRequest("DELETE", "/v1/players", nil)
 	resp := httptest.NewRecorder()
@@ -574,3 +699,391 @@ func TestHTTPDeletePlayerShouldReturnErrorWhenIDIsMissing(t *testing.T) {
 	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
+
+func TestHTTPSavePlayerShouldReturnUnprocessableEntityWhenInvalid(t *testing.T) {
+	es := NewEndpoints(NewValidatingService(NewService(models.NewMemoryRepository())))
+
+	req := httptest.NewRequest("POST", "/v1/players", strings.NewReader(`{"name":"","number":"999","position":"QB"}`))
+	resp := httptest.NewRecorder()
+	NewHTTPTransport(es, log.NewNopLogger()).ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+
+	var body struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
+	}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, 2, len(body.Fields))
+	assert.Equal(t, "name", body.Fields[0].Field)
+	assert.Equal(t, "number", body.Fields[1].Field)
+}
+
+func TestHTTPPatchPlayerShouldApplyMergePatch(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB", College: "Florida State"})
+	assert.Nil(t, err)
+	handler := NewHTTPTransport(NewEndpoints(NewValidatingService(NewService(repo))), log.NewNopLogger())
+
+	req := httptest.NewRequest("PATCH", "/v1/players/1", strings.NewReader(`{"number":33,"college":null}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	player, err := repo.GetPlayer(context.Background(), 1, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "Jalen Ramsey", player.Name)
+	assert.Equal(t, models.Number(33), player.Number)
+	assert.Equal(t, "", player.College)
+}
+
+func TestHTTPPatchPlayerShouldApplyJSONPatch(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+	handler := NewHTTPTransport(NewEndpoints(NewService(repo)), log.NewNopLogger())
+
+	req := httptest.NewRequest("PATCH", "/v1/players/1", strings.NewReader(`[{"op":"replace","path":"/position","value":"S"}]`))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.True(t, strings.Contains(resp.Body.String(), `"position":"S"`))
+}
+
+func TestHTTPPatchPlayerShouldRejectInvalidPatches(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+	handler := NewHTTPTransport(NewEndpoints(NewValidatingService(NewService(repo))), log.NewNopLogger())
+
+	for _, tc := range []struct {
+		contentType string
+		body        string
+		code        int
+	}{
+		{"application/merge-patch+json", `{"salary":1}`, http.StatusBadRequest},
+		{"text/plain", `{"number":33}`, http.StatusUnsupportedMediaType},
+		{"application/merge-patch+json", `{"number":100}`, http.StatusUnprocessableEntity},
+	} {
+		req := httptest.NewRequest("PATCH", "/v1/players/1", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, tc.code, resp.Code, tc.body)
+	}
+
+	req := httptest.NewRequest("PATCH", "/v1/players/2", strings.NewReader(`{"number":33}`))
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestHTTPShouldEnforceIfMatch(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+	handler := NewHTTPTransport(NewEndpoints(NewValidatingService(NewService(repo))), log.NewNopLogger())
+
+	req := httptest.NewRequest("GET", "/v1/players/1", nil)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"1"`, resp.Header().Get("ETag"))
+
+	req = httptest.NewRequest("PUT", "/v1/players/1", strings.NewReader(`{"id":1,"name":"Jalen Ramsey","number":5,"position":"CB"}`))
+	req.Header.Set("If-Match", `"1"`)
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"2"`, resp.Header().Get("ETag"))
+
+	for _, tc := range []struct {
+		method  string
+		body    string
+		ifMatch string
+	}{
+		{"PUT", `{"id":1,"name":"Jalen Ramsey","number":20,"position":"CB"}`, `"1"`},
+		{"PATCH", `{"number":20}`, `"1"`},
+		{"PATCH", `{"number":20}`, `W/"2"`},
+		{"DELETE", "", `"1"`},
+	} {
+		req := httptest.NewRequest(tc.method, "/v1/players/1", strings.NewReader(tc.body))
+		req.Header.Set("If-Match", tc.ifMatch)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusPreconditionFailed, resp.Code, tc.method+" "+tc.ifMatch)
+	}
+
+	req = httptest.NewRequest("PATCH", "/v1/players/1", strings.NewReader(`{"number":20}`))
+	req.Header.Set("If-Match", `"2"`)
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"3"`, resp.Header().Get("ETag"))
+
+	req = httptest.NewRequest("DELETE", "/v1/players/1", nil)
+	req.Header.Set("If-Match", "*")
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+}
+
+func TestHTTPShouldAcceptAnyTagInIfMatch(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+	handler := NewHTTPTransport(NewEndpoints(NewValidatingService(NewService(repo))), log.NewNopLogger())
+
+	for _, tc := range []struct {
+		method  string
+		body    string
+		ifMatch string
+		code    int
+		etag    string
+	}{
+		{"PATCH", `{"number":5}`, `"3", "4"`, http.StatusPreconditionFailed, ""},
+		{"PATCH", `{"number":5}`, `"3", "1"`, http.StatusOK, `"2"`},
+		{"PUT", `{"id":1,"name":"Jalen Ramsey","number":20,"position":"CB"}`, `"1","2-yaml"`, http.StatusOK, `"3"`},
+		{"PATCH", `{"number":5}`, `W/"3", "x"`, http.StatusPreconditionFailed, ""},
+		{"PATCH", `{"number":5}`, `"1", *`, http.StatusOK, `"4"`},
+		{"DELETE", "", `"3", "4"`, http.StatusNoContent, ""},
+		{"POST", "", `"5", "6"`, http.StatusOK, `"6"`},
+	} {
+		path := "/v1/players/1"
+		if tc.method == "POST" {
+			path += ":restore"
+		}
+		req := httptest.NewRequest(tc.method, path, strings.NewReader(tc.body))
+		req.Header.Set("If-Match", tc.ifMatch)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, tc.code, resp.Code, tc.method+" "+tc.ifMatch)
+		assert.Equal(t, tc.etag, resp.Header().Get("ETag"), tc.method+" "+tc.ifMatch)
+	}
+}
+
+func TestHTTPUpdateShouldIgnoreBodyVersionWithoutIfMatch(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+	handler := NewHTTPTransport(NewEndpoints(NewValidatingService(NewService(repo))), log.NewNopLogger())
+
+	for i, ifMatch := range []string{"", "*"} {
+		req := httptest.NewRequest("PUT", "/v1/players/1", strings.NewReader(`{"id":1,"name":"Jalen Ramsey","number":20,"position":"CB","version":1}`))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code, ifMatch)
+		assert.Equal(t, fmt.Sprintf(`"%d"`, i+2), resp.Header().Get("ETag"), ifMatch)
+	}
+}
+
+func TestHTTPGetHistoryShouldReturnChangesByActor(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	handler := NewHTTPTransport(NewEndpoints(NewService(repo)), log.NewNopLogger())
+
+	req := httptest.NewRequest("POST", "/v1/players", strings.NewReader(`{"name":"Jalen Ramsey","number":20,"position":"CB"}`))
+	req.Header.Set(ActorHeader, "jaguars")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	req = httptest.NewRequest("PATCH", "/v1/players/1", strings.NewReader(`{"number":5}`))
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", "/v1/players/1/history", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body struct {
+		History []models.HistoryEntry `json:"history"`
+	}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, 2, len(body.History))
+	assert.Equal(t, "jaguars", body.History[0].Actor)
+	assert.Equal(t, models.OperationCreate, body.History[0].Operation)
+	assert.Equal(t, models.Anonymous, body.History[1].Actor)
+	assert.Equal(t, "number", body.History[1].Changes[0].Field)
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", "/v1/players/2/history", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestHTTPShouldHideDeletedPlayersUntilRestored(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	handler := NewHTTPTransport(NewEndpoints(NewService(repo)), log.NewNopLogger())
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Jalen Ramsey", Number: 20, Position: "CB"})
+	assert.Nil(t, err)
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("DELETE", "/v1/players/1", nil))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	for path, code := range map[string]int{
+		"/v1/players/1":                        http.StatusNotFound,
+		"/v1/players":                          http.StatusNotFound,
+		"/v1/players/1?include_deleted=true":   http.StatusOK,
+		"/v1/players?include_deleted=true":     http.StatusOK,
+		"/v1/players/1?include_deleted=maybe":  http.StatusBadRequest,
+		"/v1/players?include_deleted=sometime": http.StatusBadRequest,
+	} {
+		resp = httptest.NewRecorder()
+		handler.ServeHTTP(resp, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, code, resp.Code, path)
+	}
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", "/v1/players/1?include_deleted=true", nil))
+	var got struct {
+		Player map[string]interface{} `json:"player"`
+	}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.NotNil(t, got.Player["deleted_at"])
+
+	req := httptest.NewRequest("POST", "/v1/players/1:restore", nil)
+	req.Header.Set("If-Match", `"1"`)
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+
+	req = httptest.NewRequest("POST", "/v1/players/1:restore", nil)
+	req.Header.Set("If-Match", `"2"`)
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, `"3"`, resp.Header().Get("ETag"))
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("POST", "/v1/players/1:restore", nil))
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("POST", "/v1/players/2:restore", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest("GET", "/v1/players/1", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestHTTPImportPlayersShouldReportEachRow(t *testing.T) {
+	repo := models.NewMemoryRepository()
+	_, _, err := repo.SavePlayer(context.Background(), &models.Player{Name: "Blake Bortles", Number: 5, Position: "QB"})
+	assert.Nil(t, err)
+	handler := NewHTTPTransport(NewEndpoints(NewService(repo)), log.NewNopLogger())
+
+	csv := `name,number,position,height,weight,age,experience,college
+Blake Bortles,5,QB,6-5,236,26,5,Central Florida
+Jalen Ramsey,20,CB,6-1,209,23,3,Florida State
+Leonard Fournette,x,RB,6-0,240,23,R,LSU
+`
+	req := httptest.NewRequest("POST", "/v1/players:import", strings.NewReader(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set(ActorHeader, "jaguars")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var body importPlayersResponse
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, models.ImportSummary{Created: 1, Updated: 1, Rejected: 1}, body.Summary)
+	assert.Equal(t, models.ImportUpdated, body.Results[0].Status)
+	assert.Equal(t, 1, body.Results[0].ID)
+	assert.Equal(t, models.ImportCreated, body.Results[1].Status)
+	assert.Equal(t, 2, body.Results[1].ID)
+	assert.Equal(t, []string{`number: invalid number "x"`}, body.Results[2].Errors)
+
+	history, err := repo.GetPlayerHistory(context.Background(), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "jaguars", history[0].Actor)
+
+	for _, tc := range []struct {
+		contentType string
+		body        string
+		code        int
+	}{
+		{"text/csv", "nickname\nSacksonville\n", http.StatusBadRequest},
+		{"text/csv", "nickname", http.StatusBadRequest},
+		{"text/csv", "name\n" + strings.Repeat("x", MaxImportBytes), http.StatusRequestEntityTooLarge},
+		{"text/csv", strings.Repeat("x", MaxImportBytes+1), http.StatusRequestEntityTooLarge},
+		{"application/json", `{"name":"Jalen Ramsey"}`, http.StatusUnsupportedMediaType},
+	} {
+		req = httptest.NewRequest("POST", "/v1/players:import", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		resp = httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, tc.code, resp.Code, tc.contentType)
+	}
+}
+
+func TestHTTPExportPlayersShouldStreamFilteredPlayers(t *testing.T) {
+	db, mock, err := createDB()
+	assert.Nil(t, err)
+	defer db.Close()
+
+	for i := 0; i < 3; i++ {
+		mock.ExpectQuery(`^SELECT \* FROM players WHERE deleted_at IS NULL AND position IN \(\$1\) ORDER BY name ASC, id ASC$`).
+			WithArgs("QB").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "number", "position", "height", "experience"}).
+				AddRow(1, "Blake Bortles", 5, "QB", 77, 5).
+				AddRow(9, "Cody Kessler", 6, "QB", 73, 3))
+	}
+	handler := NewHTTPTransport(NewEndpoints(NewService(models.NewPostgresRepository(db))), log.NewNopLogger())
+
+	for format, expected := range map[string]string{
+		"": `id,name,number,position,height,weight,birth_date,experience,college
+1,Blake Bortles,5,QB,6-5,,,5,
+9,Cody Kessler,6,QB,6-1,,,3,
+`,
+		"tsv": "id\tname\tnumber\tposition\theight\tweight\tbirth_date\texperience\tcollege\n" +
+			"1\tBlake Bortles\t5\tQB\t6-5\t\t\t5\t\n" +
+			"9\tCody Kessler\t6\tQB\t6-1\t\t\t3\t\n",
+		"jsonl": "",
+	} {
+		req := httptest.NewRequest("GET", "/v1/players/export?position=QB&sort=name&format="+format, nil)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code, format)
+		if format == "jsonl" {
+			assert.Equal(t, "application/x-ndjson; charset=utf-8", resp.Header().Get("Content-Type"))
+			lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+			assert.Equal(t, 2, len(lines))
+			var p models.Player
+			assert.Nil(t, json.Unmarshal([]byte(lines[1]), &p))
+			assert.Equal(t, "Cody Kessler", p.Name)
+			continue
+		}
+		assert.Equal(t, expected, resp.Body.String(), format)
+	}
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestHTTPExportPlayersShouldReturnHeaderWhenNoPlayers(t *testing.T) {
+	handler := NewHTTPTransport(NewEndpoints(NewService(models.NewMemoryRepository())), log.NewNopLogger())
+
+	req := httptest.NewRequest("GET", "/v1/players/export", nil)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="players.csv"`, resp.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,name,number,position,height,weight,birth_date,experience,college\n", resp.Body.String())
+}
+
+func TestHTTPExportPlayersShouldReturnBadRequestWhenInvalid(t *testing.T) {
+	handler := NewHTTPTransport(NewEndpoints(NewService(models.NewMemoryRepository())), log.NewNopLogger())
+
+	for _, query := range []string{"format=xlsx", "sort=salary", "min_experience=x"} {
+		req := httptest.NewRequest("GET", "/v1/players/export?"+query, nil)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusBadRequest, resp.Code, query)
+		assert.Equal(t, "application/json; charset=utf-8", resp.Header().Get("Content-Type"), query)
+	}
+}
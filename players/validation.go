@@ -0,0 +1,206 @@
+package players
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hoop33/roster/models"
+)
+
+// FieldError describes why a single field failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a player fails validation
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error returns all the field errors as a single string
+func (e *ValidationError) Error() string {
+	return "invalid player: " + joinFieldErrors(e.Fields)
+}
+
+func joinFieldErrors(fields []FieldError) string {
+	msgs := make([]string, len(fields))
+	for i, f := range fields {
+		msgs[i] = fmt.Sprintf("%s %s", f.Field, f.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+var validPositions = map[string]bool{
+	"QB": true, "RB": true, "FB": true, "WR": true, "TE": true,
+	"OT": true, "T": true, "OG": true, "G": true, "C": true, "OL": true,
+	"DE": true, "DT": true, "NT": true, "DL": true,
+	"LB": true, "ILB": true, "OLB": true, "MLB": true,
+	"CB": true, "S": true, "FS": true, "SS": true, "DB": true,
+	"K": true, "P": true, "LS": true,
+}
+
+type validatingService struct {
+	next Service
+}
+
+// NewValidatingService returns a new service that validates players before saving them
+func NewValidatingService(next Service) Service {
+	return &validatingService{
+		next: next,
+	}
+}
+
+func (v *validatingService) ListPlayers(ctx context.Context, filter models.PlayerFilter, page models.PageRequest) ([]models.Player, string, error) {
+	if fields := validateFilter(filter); len(fields) > 0 {
+		return nil, "", newError(KindInvalidArgument, "invalid filter: "+joinFieldErrors(fields))
+	}
+	return v.next.ListPlayers(ctx, filter, page)
+}
+
+func (v *validatingService) ExportPlayers(ctx context.Context, filter models.PlayerFilter, fn func(*models.Player) error) error {
+	if fields := validateFilter(filter); len(fields) > 0 {
+		return newError(KindInvalidArgument, "invalid filter: "+joinFieldErrors(fields))
+	}
+	return v.next.ExportPlayers(ctx, filter, fn)
+}
+
+func (v *validatingService) SearchPlayers(ctx context.Context, query string, limit int) ([]models.SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, newError(KindInvalidArgument, "invalid search: q is required")
+	}
+	if limit < 0 {
+		return nil, newError(KindInvalidArgument, "invalid search: limit must not be negative")
+	}
+	return v.next.SearchPlayers(ctx, query, limit)
+}
+
+func (v *validatingService) GetPlayer(ctx context.Context, id int, includeDeleted bool) (*models.Player, error) {
+	return v.next.GetPlayer(ctx, id, includeDeleted)
+}
+
+func (v *validatingService) SavePlayer(ctx context.Context, player *models.Player) (*models.Player, bool, error) {
+	if fields := validatePlayer(player); len(fields) > 0 {
+		return nil, false, &ValidationError{
+			Fields: fields,
+		}
+	}
+	return v.next.SavePlayer(ctx, player)
+}
+
+// PatchPlayer validates only the patched fields, since the rest of the
+// player isn't changing
+func (v *validatingService) PatchPlayer(ctx context.Context, id int, patch *models.PlayerPatch) (*models.Player, error) {
+	if fields := validatePatch(patch); len(fields) > 0 {
+		return nil, &ValidationError{
+			Fields: fields,
+		}
+	}
+	return v.next.PatchPlayer(ctx, id, patch)
+}
+
+func (v *validatingService) DeletePlayer(ctx context.Context, id, version int) error {
+	return v.next.DeletePlayer(ctx, id, version)
+}
+
+func (v *validatingService) RestorePlayer(ctx context.Context, id, version int) (*models.Player, error) {
+	return v.next.RestorePlayer(ctx, id, version)
+}
+
+// ImportPlayers rejects the rows with invalid players, and imports the
+// rest. Only the fields a row sets are checked.
+func (v *validatingService) ImportPlayers(ctx context.Context, rows []models.ImportRow) ([]models.ImportResult, error) {
+	validated := make([]models.ImportRow, len(rows))
+	for i, row := range rows {
+		if len(row.Errors) == 0 {
+			set := map[string]bool{"name": true, "birth_date": true}
+			for _, f := range row.Fields {
+				set[f] = true
+			}
+			for _, f := range validatePlayer(&row.Player) {
+				if row.Fields == nil || set[f.Field] {
+					row.Errors = append(row.Errors, fmt.Sprintf("%s: %s", f.Field, f.Message))
+				}
+			}
+		}
+		validated[i] = row
+	}
+	return v.next.ImportPlayers(ctx, validated)
+}
+
+func (v *validatingService) GetPlayerHistory(ctx context.Context, id int) ([]models.HistoryEntry, error) {
+	return v.next.GetPlayerHistory(ctx, id)
+}
+
+func validatePlayer(p *models.Player) []FieldError {
+	if p == nil {
+		return []FieldError{{Field: "player", Message: "is required"}}
+	}
+
+	var fields []FieldError
+	if strings.TrimSpace(p.Name) == "" {
+		fields = append(fields, FieldError{Field: "name", Message: "is required"})
+	}
+	if p.Number < 0 || p.Number > 99 {
+		fields = append(fields, FieldError{Field: "number", Message: "must be between 0 and 99"})
+	}
+	if !validPositions[p.Position] {
+		fields = append(fields, FieldError{Field: "position", Message: fmt.Sprintf("%q is not a known position", p.Position)})
+	}
+	if p.Height < 0 {
+		fields = append(fields, FieldError{Field: "height", Message: "must not be negative"})
+	}
+	if p.Weight < 0 {
+		fields = append(fields, FieldError{Field: "weight", Message: "must not be negative"})
+	}
+	if p.BirthDate.After(time.Now()) {
+		fields = append(fields, FieldError{Field: "birth_date", Message: "must not be in the future"})
+	}
+	if p.Experience < 0 {
+		fields = append(fields, FieldError{Field: "experience", Message: "must not be negative"})
+	}
+	return fields
+}
+
+func validatePatch(patch *models.PlayerPatch) []FieldError {
+	if patch == nil {
+		return []FieldError{{Field: "patch", Message: "is required"}}
+	}
+
+	patched := map[string]bool{}
+	for _, f := range patch.Fields() {
+		patched[f] = true
+	}
+
+	var player models.Player
+	patch.Apply(&player)
+
+	var fields []FieldError
+	for _, f := range validatePlayer(&player) {
+		if patched[f.Field] {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func validateFilter(f models.PlayerFilter) []FieldError {
+	var fields []FieldError
+	if f.MinExperience != nil && *f.MinExperience < 0 {
+		fields = append(fields, FieldError{Field: "min_experience", Message: "must not be negative"})
+	}
+	if f.MaxExperience != nil && *f.MaxExperience < 0 {
+		fields = append(fields, FieldError{Field: "max_experience", Message: "must not be negative"})
+	}
+	if f.MinExperience != nil && f.MaxExperience != nil && *f.MinExperience > *f.MaxExperience {
+		fields = append(fields, FieldError{Field: "min_experience", Message: "must not be greater than max_experience"})
+	}
+	for _, sf := range f.Sort {
+		if !models.IsSortField(sf.Field) {
+			fields = append(fields, FieldError{Field: "sort", Message: fmt.Sprintf("%q is not a sortable field", sf.Field)})
+		}
+	}
+	return fields
+}
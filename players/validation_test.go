@@ -0,0 +1,147 @@
+package players
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hoop33/roster/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func validPlayer() *models.Player {
+	return &models.Player{
+		Name:       "Blake Bortles",
+		Number:     5,
+		Position:   "QB",
+		Height:     77,
+		Weight:     236,
+		BirthDate:  models.NewDate(1992, time.April, 29),
+		Experience: 5,
+		College:    "Central Florida",
+	}
+}
+
+func TestValidatingServiceShouldSaveValidPlayer(t *testing.T) {
+	m := &mockNextService{}
+	_, _, err := NewValidatingService(m).SavePlayer(context.Background(), validPlayer())
+	assert.Nil(t, err)
+	assert.True(t, m.called)
+}
+
+func TestValidatingServiceShouldRejectInvalidPlayer(t *testing.T) {
+	m := &mockNextService{}
+	p := validPlayer()
+	p.Name = " "
+	p.Number = 999
+	p.Position = "QUARTERBACK"
+
+	_, _, err := NewValidatingService(m).SavePlayer(context.Background(), p)
+	assert.False(t, m.called)
+	ve, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, 3, len(ve.Fields))
+	assert.Equal(t, "name", ve.Fields[0].Field)
+	assert.Equal(t, "number", ve.Fields[1].Field)
+	assert.Equal(t, "position", ve.Fields[2].Field)
+}
+
+func TestValidatingServiceShouldRejectMissingPlayer(t *testing.T) {
+	_, _, err := NewValidatingService(&mockNextService{}).SavePlayer(context.Background(), nil)
+	assert.EqualError(t, err, "invalid player: player is required")
+}
+
+func TestValidatePlayerShouldRejectNegativeAndFutureValues(t *testing.T) {
+	p := validPlayer()
+	p.Height = -1
+	p.Weight = -1
+	p.Experience = -1
+	p.BirthDate = models.NewDate(time.Now().Year()+1, time.January, 1)
+
+	fields := validatePlayer(p)
+	assert.Equal(t, []FieldError{
+		{Field: "height", Message: "must not be negative"},
+		{Field: "weight", Message: "must not be negative"},
+		{Field: "birth_date", Message: "must not be in the future"},
+		{Field: "experience", Message: "must not be negative"},
+	}, fields)
+}
+
+func TestValidatingServiceShouldPassThroughOtherCalls(t *testing.T) {
+	m := &mockNextService{}
+	s := NewValidatingService(m)
+	_, _, err := s.ListPlayers(context.Background(), models.PlayerFilter{}, models.PageRequest{})
+	assert.Nil(t, err)
+	assert.True(t, m.called)
+}
+
+func TestValidatingServiceShouldRejectInvalidFilter(t *testing.T) {
+	m := &mockNextService{}
+	three, one := 3, 1
+	_, _, err := NewValidatingService(m).ListPlayers(context.Background(), models.PlayerFilter{
+		MinExperience: &three,
+		MaxExperience: &one,
+		Sort:          models.ParseSort("-salary"),
+	}, models.PageRequest{})
+	assert.False(t, m.called)
+	assert.Equal(t, KindInvalidArgument, KindOf(err))
+	assert.EqualError(t, err, `invalid filter: min_experience must not be greater than max_experience; sort "salary" is not a sortable field`)
+}
+
+func TestValidatingServiceShouldRejectInvalidExportFilter(t *testing.T) {
+	m := &mockNextService{}
+	err := NewValidatingService(m).ExportPlayers(context.Background(), models.PlayerFilter{
+		Sort: models.ParseSort("-salary"),
+	}, nil)
+	assert.False(t, m.called)
+	assert.Equal(t, KindInvalidArgument, KindOf(err))
+}
+
+func TestValidatingServiceShouldRejectEmptySearch(t *testing.T) {
+	m := &mockNextService{}
+	_, err := NewValidatingService(m).SearchPlayers(context.Background(), "  ", 0)
+	assert.False(t, m.called)
+	assert.Equal(t, KindInvalidArgument, KindOf(err))
+}
+
+func TestValidatingServiceShouldValidateOnlyPatchedFields(t *testing.T) {
+	m := &mockNextService{}
+	s := NewValidatingService(m)
+
+	number := models.Number(33)
+	_, err := s.PatchPlayer(context.Background(), 1, &models.PlayerPatch{Number: &number})
+	assert.Nil(t, err)
+	assert.True(t, m.called)
+
+	m.called = false
+	number = 100
+	name := ""
+	_, err = s.PatchPlayer(context.Background(), 1, &models.PlayerPatch{Name: &name, Number: &number})
+	assert.False(t, m.called)
+	ve, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []FieldError{
+		{Field: "name", Message: "is required"},
+		{Field: "number", Message: "must be between 0 and 99"},
+	}, ve.Fields)
+}
+
+func TestValidatingServiceShouldRejectInvalidImportRows(t *testing.T) {
+	invalid := validPlayer()
+	invalid.Name = "Josh Allen"
+	invalid.Position = "EDGE"
+	rows := []models.ImportRow{
+		{Row: 1, Player: *validPlayer()},
+		{Row: 2, Player: *invalid},
+		{Row: 3, Errors: []string{"number: invalid number \"x\""}},
+	}
+
+	results, err := NewValidatingService(NewService(models.NewMemoryRepository())).ImportPlayers(context.Background(), rows)
+	assert.Nil(t, err)
+	assert.Equal(t, models.ImportCreated, results[0].Status)
+	assert.Equal(t, models.ImportRejected, results[1].Status)
+	assert.Equal(t, []string{`position: "EDGE" is not a known position`}, results[1].Errors)
+	assert.Equal(t, models.ImportRejected, results[2].Status)
+	assert.Equal(t, 1, len(results[2].Errors))
+	assert.Nil(t, rows[1].Errors)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/hoop33/roster/models"
+	"github.com/hoop33/roster/players"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPlayersTestServer() *httptest.Server {
+	return httptest.NewServer(players.NewHTTPTransport(players.NewEndpoints(newTestService()), log.NewNopLogger()))
+}
+
+// runPlayersAt runs a players command against a server, returning what it
+// wrote
+func runPlayersAt(addr string, args []string, stdin string) (string, error) {
+	args = append([]string{args[0], "-addr", addr}, args[1:]...)
+	var out bytes.Buffer
+	err := runPlayers(args, strings.NewReader(stdin), &out)
+	return out.String(), err
+}
+
+func TestRunPlayersShouldRejectBadUsage(t *testing.T) {
+	srv := newPlayersTestServer()
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"get", "1", "2"}, errPlayersUsage.Error()},
+		{[]string{"get"}, errPlayersUsage.Error()},
+		{[]string{"get", "-verbose", "1"}, errPlayersUsage.Error()},
+		{[]string{"get", "1", "-output"}, errPlayersUsage.Error()},
+		{[]string{"get", "1", "-o", "json"}, errPlayersUsage.Error()},
+		{[]string{"get", "--", "1", "-output", "json"}, errPlayersUsage.Error()},
+		{[]string{"get", "x"}, `invalid player id "x"`},
+		{[]string{"get", "0"}, `invalid player id "0"`},
+		{[]string{"get", "1", "-output", "xml"}, `unknown output format "xml"`},
+		{[]string{"get", "1", "-transport", "smtp"}, `unknown transport "smtp"`},
+		{[]string{"list", "QB"}, errPlayersUsage.Error()},
+		{[]string{"create"}, errPlayersUsage.Error()},
+		{[]string{"update", "1"}, errPlayersUsage.Error()},
+		{[]string{"delete"}, errPlayersUsage.Error()},
+	} {
+		_, err := runPlayersAt(srv.URL, tc.args, "")
+		assert.EqualError(t, err, tc.err, strings.Join(tc.args, " "))
+	}
+
+	for _, args := range [][]string{nil, {"restore", "1"}} {
+		var out bytes.Buffer
+		assert.Equal(t, errPlayersUsage, runPlayers(args, nil, &out), strings.Join(args, " "))
+	}
+}
+
+func TestRunPlayersShouldManagePlayers(t *testing.T) {
+	srv := newPlayersTestServer()
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		args  []string
+		stdin string
+		lines []string
+	}{
+		{[]string{"create", "-f", "-", "-output", "json"}, `{"name":"Blake Bortles","number":5,"position":"QB","college":"UCF"}`, nil},
+		{[]string{"create", "-f", "-"}, `{"name":"Jalen Ramsey","number":20,"position":"CB","height":"6-1"}`, []string{
+			"ID NUMBER NAME POSITION HEIGHT WEIGHT BIRTH DATE EXP COLLEGE VERSION",
+			"2 20 Jalen Ramsey CB 6-1 0 1",
+		}},
+		{[]string{"get", "1"}, "", []string{
+			"ID NUMBER NAME POSITION HEIGHT WEIGHT BIRTH DATE EXP COLLEGE VERSION",
+			"1 5 Blake Bortles QB 0 UCF 1",
+		}},
+		{[]string{"update", "1", "-f", "-", "-version", "1"}, `{"number":9}`, []string{
+			"ID NUMBER NAME POSITION HEIGHT WEIGHT BIRTH DATE EXP COLLEGE VERSION",
+			"1 9 Blake Bortles QB 0 UCF 2",
+		}},
+		{[]string{"list", "-position", "QB,CB", "-sort", "-number"}, "", []string{
+			"ID NUMBER NAME POSITION HEIGHT WEIGHT BIRTH DATE EXP COLLEGE VERSION",
+			"2 20 Jalen Ramsey CB 6-1 0 1",
+			"1 9 Blake Bortles QB 0 UCF 2",
+		}},
+		{[]string{"delete", "2", "-version", "1"}, "", []string{"deleted player 2"}},
+		{[]string{"list", "-position", "CB"}, "", []string{
+			"ID NUMBER NAME POSITION HEIGHT WEIGHT BIRTH DATE EXP COLLEGE VERSION",
+		}},
+	} {
+		out, err := runPlayersAt(srv.URL, tc.args, tc.stdin)
+		assert.Nil(t, err, strings.Join(tc.args, " "))
+		if tc.lines == nil {
+			continue
+		}
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			lines = append(lines, strings.Join(strings.Fields(line), " "))
+		}
+		assert.Equal(t, tc.lines, lines, strings.Join(tc.args, " "))
+	}
+
+	_, err := runPlayersAt(srv.URL, []string{"create", "-f", "-"}, `{"name":"Cody Kessler","number":6,"position":"QB"}`)
+	assert.Nil(t, err)
+	out, err := runPlayersAt(srv.URL, []string{"list", "-page-size", "1"}, "")
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(out, "\nnext page: -page-token "), out)
+}
+
+func TestRunPlayersShouldWriteEachOutput(t *testing.T) {
+	srv := newPlayersTestServer()
+	defer srv.Close()
+	_, err := runPlayersAt(srv.URL, []string{"create", "-f", "-"}, `{"name":"Blake Bortles","number":5,"position":"QB","height":77}`)
+	assert.Nil(t, err)
+
+	for _, args := range [][]string{{"get", "-output", "json", "1"}, {"get", "1", "-output", "json"}} {
+		out, err := runPlayersAt(srv.URL, args, "")
+		assert.Nil(t, err, strings.Join(args, " "))
+		var player models.Player
+		assert.Nil(t, json.Unmarshal([]byte(out), &player), out)
+		assert.Equal(t, "Blake Bortles", player.Name)
+		assert.Equal(t, models.Height(77), player.Height)
+	}
+
+	out, err := runPlayersAt(srv.URL, []string{"get", "1", "-output", "yaml"}, "")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(out, "id: 1\nname: Blake Bortles\nnumber: \"5\"\nposition: QB\nheight: 6-5\n"), out)
+
+	out, err = runPlayersAt(srv.URL, []string{"list", "-output", "json"}, "")
+	assert.Nil(t, err)
+	var list struct {
+		Players []models.Player `json:"players"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(out), &list))
+	assert.Equal(t, 1, len(list.Players))
+
+	out, err = runPlayersAt(srv.URL, []string{"list", "-output", "yaml", "-position", "CB"}, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "players: []\n", out)
+}
+
+func TestRunPlayersShouldReturnServerErrors(t *testing.T) {
+	srv := newPlayersTestServer()
+	defer srv.Close()
+	_, err := runPlayersAt(srv.URL, []string{"create", "-f", "-"}, `{"name":"Blake Bortles","number":5,"position":"QB"}`)
+	assert.Nil(t, err)
+
+	for _, tc := range []struct {
+		args  []string
+		stdin string
+		kind  players.Kind
+	}{
+		{[]string{"get", "9"}, "", players.KindNotFound},
+		{[]string{"delete", "9"}, "", players.KindNotFound},
+		{[]string{"update", "1", "-f", "-", "-version", "7"}, `{"number":9}`, players.KindPreconditionFailed},
+		{[]string{"update", "1", "-f", "-"}, `{"number":100}`, players.KindInvalidArgument},
+		{[]string{"create", "-f", "-"}, `{"number":5}`, players.KindInvalidArgument},
+	} {
+		out, err := runPlayersAt(srv.URL, tc.args, tc.stdin)
+		assert.Equal(t, tc.kind, players.KindOf(err), strings.Join(tc.args, " "))
+		assert.Equal(t, "", out, strings.Join(tc.args, " "))
+	}
+
+	for _, tc := range []struct {
+		args  []string
+		stdin string
+		err   string
+	}{
+		{[]string{"create", "-f", "-"}, `{"id":3,"name":"Jalen Ramsey"}`, "a new player can't have an id; use update to change a player"},
+		{[]string{"create", "-f", "-"}, `{`, "invalid player: unexpected end of JSON input"},
+	} {
+		_, err := runPlayersAt(srv.URL, tc.args, tc.stdin)
+		assert.EqualError(t, err, tc.err, strings.Join(tc.args, " "))
+	}
+}